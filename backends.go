@@ -0,0 +1,109 @@
+package searchkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BackendFailure reports a backend that failed or timed out during Search.
+type BackendFailure struct {
+	Backend Backend
+	// Language is empty when the failure affected every language (e.g. the
+	// query embedding call for BackendSemantic).
+	Language string
+	// TimedOut is true when the backend exceeded the backend timeout.
+	TimedOut bool
+	Err      error
+}
+
+func (f BackendFailure) Error() string {
+	what := string(f.Backend)
+	if f.Language != "" {
+		what += " (" + f.Language + ")"
+	}
+	if f.TimedOut {
+		return fmt.Sprintf("%s search timed out: %v", what, f.Err)
+	}
+	return fmt.Sprintf("%s search failed: %v", what, f.Err)
+}
+
+func (f BackendFailure) Unwrap() error { return f.Err }
+
+// backendTask is one retrieval call that produces ranked lists.
+type backendTask struct {
+	backend  Backend
+	language string
+	run      func(ctx context.Context) ([]rankedList, error)
+}
+
+type backendResult struct {
+	lists   []rankedList
+	failure *BackendFailure
+}
+
+// runBackendCall runs fn under an optional timeout and classifies its error.
+func runBackendCall(ctx context.Context, backend Backend, language string, timeout time.Duration, fn func(ctx context.Context) error) *BackendFailure {
+	callCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := fn(callCtx)
+	if err == nil {
+		return nil
+	}
+	timedOut := ctx.Err() == nil && errors.Is(callCtx.Err(), context.DeadlineExceeded)
+	return &BackendFailure{Backend: backend, Language: language, TimedOut: timedOut, Err: err}
+}
+
+// runBackendTasks runs tasks concurrently. Results are returned in task order
+// so fusion stays deterministic.
+func runBackendTasks(ctx context.Context, tasks []backendTask, timeout time.Duration) []backendResult {
+	out := make([]backendResult, len(tasks))
+	var wg sync.WaitGroup
+	for i, t := range tasks {
+		i, t := i, t
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var lists []rankedList
+			out[i].failure = runBackendCall(ctx, t.backend, t.language, timeout, func(ctx context.Context) error {
+				var err error
+				lists, err = t.run(ctx)
+				return err
+			})
+			if out[i].failure == nil {
+				out[i].lists = lists
+			}
+		}()
+	}
+	wg.Wait()
+	return out
+}
+
+// collectBackendResults flattens results into ranked lists and failures.
+//
+// Without allowPartial, the first failure (in task order) is returned as an
+// error. With allowPartial, an error is returned only if every backend failed.
+func collectBackendResults(results []backendResult, allowPartial bool) ([]rankedList, []BackendFailure, error) {
+	var lists []rankedList
+	var failures []BackendFailure
+	for _, r := range results {
+		if r.failure != nil {
+			failures = append(failures, *r.failure)
+			continue
+		}
+		lists = append(lists, r.lists...)
+	}
+	if len(failures) == 0 {
+		return lists, nil, nil
+	}
+	if !allowPartial || len(failures) == len(results) {
+		return nil, failures, failures[0]
+	}
+	return lists, failures, nil
+}
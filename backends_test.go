@@ -0,0 +1,115 @@
+package searchkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunBackendTasks_TimeoutAndOrder(t *testing.T) {
+	t.Parallel()
+
+	tasks := []backendTask{
+		{
+			backend:  BackendSemantic,
+			language: "en",
+			run: func(ctx context.Context) ([]rankedList, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+		{
+			backend:  BackendFTS,
+			language: "en",
+			run: func(ctx context.Context) ([]rankedList, error) {
+				return []rankedList{{backend: BackendFTS, language: "en"}}, nil
+			},
+		},
+	}
+
+	results := runBackendTasks(context.Background(), tasks, 20*time.Millisecond)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if f := results[0].failure; f == nil || !f.TimedOut || f.Backend != BackendSemantic {
+		t.Fatalf("expected semantic timeout failure, got %+v", results[0].failure)
+	}
+	if results[1].failure != nil || len(results[1].lists) != 1 {
+		t.Fatalf("expected fts success, got %+v", results[1])
+	}
+
+	lists, failures, err := collectBackendResults(results, true)
+	if err != nil {
+		t.Fatalf("partial collect: %v", err)
+	}
+	if len(lists) != 1 || len(failures) != 1 {
+		t.Fatalf("expected 1 list + 1 failure, got %d + %d", len(lists), len(failures))
+	}
+
+	if _, _, err := collectBackendResults(results, false); err == nil {
+		t.Fatalf("expected error without partial results")
+	}
+}
+
+func TestCollectBackendResults_AllFailed(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("boom")
+	results := []backendResult{{failure: &BackendFailure{Backend: BackendFTS, Language: "en", Err: boom}}}
+	if _, _, err := collectBackendResults(results, true); !errors.Is(err, boom) {
+		t.Fatalf("expected wrapped boom error, got %v", err)
+	}
+}
+
+func TestClientSearch_EmbedderFailureReported(t *testing.T) {
+	t.Parallel()
+
+	boom := errors.New("provider down")
+	client, err := NewClient(ClientConfig{
+		Pool:                newTestPool(t),
+		Schema:              "test",
+		Embedder:            &recordingEmbedder{err: boom},
+		DefaultModel:        "model",
+		AllowPartialResults: true,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	_, err = client.Search(context.Background(), "two factor", SearchOptions{
+		Mode:                SearchModeSemantic,
+		SemanticEntityTypes: []string{"gallery"},
+	})
+	var failure BackendFailure
+	if !errors.As(err, &failure) || failure.Backend != BackendSemantic || !errors.Is(err, boom) {
+		t.Fatalf("expected semantic BackendFailure wrapping provider error, got %v", err)
+	}
+}
+
+func TestClientSearch_EmptyEmbeddingReturnsNoHits(t *testing.T) {
+	t.Parallel()
+
+	emb := &recordingEmbedder{vec: []float32{}}
+	client, err := NewClient(ClientConfig{
+		Pool:         newTestPool(t),
+		Schema:       "test",
+		Embedder:     emb,
+		DefaultModel: "model",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// Fallback languages would query more tiers; the empty embedding stops
+	// the search before any semantic query reaches the (unreachable) pool.
+	hits, err := client.Search(context.Background(), "two factor", SearchOptions{
+		Mode:                SearchModeSemantic,
+		SemanticEntityTypes: []string{"gallery"},
+		LanguageMode:        LanguageModeFallbackOnShortfall,
+		FallbackLanguages:   []string{"es"},
+	})
+	if err != nil || len(hits) != 0 || !emb.called {
+		t.Fatalf("expected no hits without error, got %v, %v", hits, err)
+	}
+}
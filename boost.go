@@ -0,0 +1,181 @@
+package searchkit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/open-rails/searchkit/pg"
+)
+
+// BoostOptions combines host ranking signals (runtime.BuildSignals /
+// pg.UpsertSignals) with the fused score (RRF or SearchOptions.Fusion):
+//
+//	score = fused × (1 + Recency.Weight·decay + Popularity.Weight·ln(1 + static_rank))
+//	decay = 2^(-age / Recency.HalfLife)
+//
+// Hits without signals keep their fused score. Boosting is applied to the fused
+// (and language-collapsed) ranking before grouping and pagination.
+type BoostOptions struct {
+	Recency    *RecencyBoost
+	Popularity *PopularityBoost
+
+	// OverFetch multiplies backend retrieval depth so that boosted hits from
+	// below the page can move up (default 2).
+	OverFetch int
+}
+
+// RecencyBoost is an exponential time decay on the entity timestamp.
+type RecencyBoost struct {
+	// Weight is the boost of a brand-new entity.
+	Weight float32
+	// HalfLife is the age at which the boost has halved.
+	HalfLife time.Duration
+	// Origin is the reference time ages are measured from (zero = now; page
+	// tokens pin "now" so every page is ranked against the same clock).
+	// Timestamps after Origin get the full boost.
+	Origin time.Time
+}
+
+// PopularityBoost is a logarithmic boost on the entity static rank.
+type PopularityBoost struct {
+	Weight float32
+}
+
+// BoostExplanation shows how a hit's score was boosted (HitExplanation.Boost).
+type BoostExplanation struct {
+	// FusedScore is the fused score before boosting.
+	FusedScore float32
+
+	StaticRank float64
+	// Timestamp is zero when the entity has no timestamp.
+	Timestamp time.Time
+
+	// Recency is Recency.Weight·decay; Popularity is
+	// Popularity.Weight·ln(1 + StaticRank).
+	Recency    float32
+	Popularity float32
+	// Multiplier is 1 + Recency + Popularity (Score = FusedScore × Multiplier).
+	Multiplier float32
+}
+
+func validateBoosts(b *BoostOptions) error {
+	if b == nil {
+		return nil
+	}
+	if b.OverFetch < 0 {
+		return fmt.Errorf("invalid SearchOptions.Boosts: OverFetch must be >= 0")
+	}
+	if r := b.Recency; r != nil {
+		if r.Weight <= 0 {
+			return fmt.Errorf("invalid SearchOptions.Boosts: Recency.Weight must be > 0")
+		}
+		if r.HalfLife <= 0 {
+			return fmt.Errorf("invalid SearchOptions.Boosts: Recency.HalfLife must be > 0")
+		}
+	}
+	if p := b.Popularity; p != nil && p.Weight <= 0 {
+		return fmt.Errorf("invalid SearchOptions.Boosts: Popularity.Weight must be > 0")
+	}
+	return nil
+}
+
+// boostsFingerprint renders the parts of b that affect result ordering. A
+// zero Recency.Origin ("now") is not part of it, so page tokens stay valid as
+// time passes; the page token pins the clock instead (see boostsUseClock).
+func boostsFingerprint(b *BoostOptions) string {
+	if b == nil {
+		return ""
+	}
+	s := ""
+	if r := b.Recency; r != nil {
+		s += fmt.Sprintf("recency=%g/%s", r.Weight, r.HalfLife)
+		if !r.Origin.IsZero() {
+			s += "@" + fmt.Sprint(r.Origin.Unix())
+		}
+	}
+	if p := b.Popularity; p != nil {
+		s += fmt.Sprintf("|popularity=%g", p.Weight)
+	}
+	return s
+}
+
+// boostsUseClock reports whether b ranks against the current time, which
+// page tokens then pin so all pages of a search share one clock.
+func boostsUseClock(b *BoostOptions) bool {
+	return b != nil && b.Recency != nil && b.Recency.Origin.IsZero()
+}
+
+// boostOverFetch returns the retrieval depth multiplier requested by b.
+func boostOverFetch(b *BoostOptions) int {
+	if b == nil || (b.Recency == nil && b.Popularity == nil) {
+		return 1
+	}
+	if b.OverFetch > 0 {
+		return b.OverFetch
+	}
+	return 2
+}
+
+// applyBoosts loads signals for hits and multiplies their scores, measuring
+// ages from now unless Recency.Origin is set. The result is not re-sorted;
+// see sortSearchHits.
+func (c *Client) applyBoosts(ctx context.Context, hits []SearchHit, b *BoostOptions, now time.Time) ([]SearchHit, error) {
+	if b == nil || (b.Recency == nil && b.Popularity == nil) || len(hits) == 0 {
+		return hits, nil
+	}
+	keys := make([]pg.SignalKey, 0, len(hits))
+	seen := map[pg.SignalKey]struct{}{}
+	for _, h := range hits {
+		k := pg.SignalKey{EntityType: h.EntityType, EntityID: h.EntityID}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		keys = append(keys, k)
+	}
+	signals, err := pg.LoadSignals(ctx, c.pool, c.schema, keys)
+	if err != nil {
+		return nil, fmt.Errorf("load signals: %w", err)
+	}
+	boostHits(hits, signals, b, now)
+	return hits, nil
+}
+
+// boostHits multiplies hit scores in place by their boost multiplier.
+func boostHits(hits []SearchHit, signals map[pg.SignalKey]pg.Signals, b *BoostOptions, now time.Time) {
+	for i := range hits {
+		h := &hits[i]
+		s, ok := signals[pg.SignalKey{EntityType: h.EntityType, EntityID: h.EntityID}]
+		if !ok {
+			continue
+		}
+		e := BoostExplanation{FusedScore: h.Score, StaticRank: s.StaticRank, Timestamp: s.Timestamp}
+		if r := b.Recency; r != nil && !s.Timestamp.IsZero() {
+			origin := r.Origin
+			if origin.IsZero() {
+				origin = now
+			}
+			e.Recency = r.Weight * float32(recencyDecay(origin.Sub(s.Timestamp), r.HalfLife))
+		}
+		if p := b.Popularity; p != nil && s.StaticRank > 0 {
+			e.Popularity = p.Weight * float32(math.Log1p(s.StaticRank))
+		}
+		e.Multiplier = 1 + e.Recency + e.Popularity
+		h.Score *= e.Multiplier
+		if h.Explain != nil {
+			x := *h.Explain
+			x.Boost = &e
+			h.Explain = &x
+		}
+	}
+}
+
+// recencyDecay is 2^(-age/halfLife), clamped to 1 for future timestamps.
+func recencyDecay(age time.Duration, halfLife time.Duration) float64 {
+	if age <= 0 {
+		return 1
+	}
+	return math.Exp2(-float64(age) / float64(halfLife))
+}
@@ -0,0 +1,92 @@
+package searchkit
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-rails/searchkit/pg"
+)
+
+func TestBoostHits(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	hits := []SearchHit{
+		{EntityType: "gallery", EntityID: "old", Score: 0.03, Explain: &HitExplanation{RRFK: 60}},
+		{EntityType: "gallery", EntityID: "new", Score: 0.02, Explain: &HitExplanation{RRFK: 60}},
+		{EntityType: "gallery", EntityID: "none", Score: 0.025},
+	}
+	signals := map[pg.SignalKey]pg.Signals{
+		{EntityType: "gallery", EntityID: "old"}: {StaticRank: 0, Timestamp: now.AddDate(-1, 0, 0)},
+		{EntityType: "gallery", EntityID: "new"}: {StaticRank: math.E - 1, Timestamp: now.Add(-7 * 24 * time.Hour)},
+	}
+	boostHits(hits, signals, &BoostOptions{
+		Recency:    &RecencyBoost{Weight: 1, HalfLife: 7 * 24 * time.Hour},
+		Popularity: &PopularityBoost{Weight: 0.5},
+	}, now)
+
+	// new: 1 + 1·0.5 + 0.5·ln(e) = 2.
+	if e := hits[1].Explain.Boost; e == nil || !near(e.Recency, 0.5) || !near(e.Popularity, 0.5) || !near(e.Multiplier, 2) || e.FusedScore != 0.02 {
+		t.Fatalf("unexpected boost explanation %+v", hits[1].Explain.Boost)
+	}
+	if !near(hits[1].Score, 0.04) {
+		t.Fatalf("boosted score = %v; want 0.04", hits[1].Score)
+	}
+	// A year old at a one-week half-life decays to ~0.
+	if hits[0].Score < 0.03 || hits[0].Score > 0.0301 {
+		t.Fatalf("old score = %v; want ~0.03", hits[0].Score)
+	}
+	if hits[2].Score != 0.025 || hits[2].Explain != nil {
+		t.Fatalf("hit without signals changed: %+v", hits[2])
+	}
+}
+
+func TestRecencyDecay(t *testing.T) {
+	t.Parallel()
+
+	day := 24 * time.Hour
+	for _, tc := range []struct {
+		age  time.Duration
+		want float64
+	}{
+		{age: -day, want: 1},
+		{age: 0, want: 1},
+		{age: day, want: 0.5},
+		{age: 2 * day, want: 0.25},
+	} {
+		if got := recencyDecay(tc.age, day); math.Abs(got-tc.want) > 1e-9 {
+			t.Fatalf("recencyDecay(%s) = %v; want %v", tc.age, got, tc.want)
+		}
+	}
+}
+
+func TestClientSearch_InvalidBoosts(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{Pool: newTestPool(t), Schema: "test"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	for _, b := range []*BoostOptions{
+		{Recency: &RecencyBoost{Weight: 1}},
+		{Recency: &RecencyBoost{Weight: -1, HalfLife: time.Hour}},
+		{Popularity: &PopularityBoost{}},
+		{OverFetch: -1},
+	} {
+		_, err := client.Search(context.Background(), "two factor", SearchOptions{
+			Mode:               SearchModeLexical,
+			LexicalEntityTypes: []string{"gallery"},
+			Boosts:             b,
+		})
+		if err == nil || !strings.Contains(err.Error(), "SearchOptions.Boosts") {
+			t.Fatalf("expected invalid Boosts error for %+v, got: %v", b, err)
+		}
+	}
+}
+
+func near(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-6
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 	querynorm "github.com/open-rails/searchkit/internal/normalize"
@@ -23,6 +25,23 @@ const (
 	SearchModeDual     SearchMode = "dual"
 )
 
+// Backend names a retrieval backend that contributes a ranked list to Search.
+type Backend string
+
+const (
+	// BackendFTS is Postgres full-text search (ts_rank_cd over search_documents.tsv).
+	BackendFTS Backend = "fts"
+	// BackendTrigram is pg_trgm similarity over search_documents.document.
+	BackendTrigram Backend = "trigram"
+	// BackendPGroonga is PGroonga full-text search over search_documents.raw_document.
+	BackendPGroonga Backend = "pgroonga"
+	// BackendSemantic is pgvector cosine KNN over embedding_vectors.
+	BackendSemantic Backend = "semantic"
+	// BackendRerank is the second-stage reranker (SearchOptions.Rerank). It
+	// contributes no ranked list; it only appears in BackendFailure.
+	BackendRerank Backend = "rerank"
+)
+
 type LanguageMode string
 
 const (
@@ -30,6 +49,14 @@ const (
 	LanguageModeExact LanguageMode = "exact"
 	// LanguageModeFallbackEnglish uses requested language first, then English.
 	LanguageModeFallbackEnglish LanguageMode = "fallback_en"
+	// LanguageModeFallback queries the requested language and every language in
+	// FallbackLanguages (in order) in one call. Hits are ranked by language
+	// first: all requested-language hits, then the first fallback's, and so on.
+	LanguageModeFallback LanguageMode = "fallback"
+	// LanguageModeFallbackOnShortfall is LanguageModeFallback, but each fallback
+	// language is only queried while the languages before it returned fewer
+	// than FallbackMinHits hits.
+	LanguageModeFallbackOnShortfall LanguageMode = "fallback_on_shortfall"
 )
 
 type ClientConfig struct {
@@ -38,6 +65,12 @@ type ClientConfig struct {
 
 	Embedder Embedder
 
+	// Reranker enables SearchOptions.Rerank (e.g. reranker.NewHTTP).
+	Reranker Reranker
+	// RerankDocuments returns the text reranked for each hit. nil uses the
+	// stored raw documents (search_documents.raw_document).
+	RerankDocuments RerankDocumentsFunc
+
 	// Defaults.
 	DefaultLanguage  string
 	DefaultModel     string
@@ -45,6 +78,41 @@ type ClientConfig struct {
 	DefaultRRFK      int
 	TwoStage         bool
 	OversampleFactor int
+
+	// DefaultBackendTimeout bounds each backend call in Search (see
+	// SearchOptions.BackendTimeout). 0 means no timeout beyond ctx.
+	DefaultBackendTimeout time.Duration
+	// AllowPartialResults is the default for SearchOptions.AllowPartialResults.
+	AllowPartialResults bool
+
+	// ResultCache enables caching of Search and Typeahead responses (e.g.
+	// NewLRUResultCache). Entries are invalidated by the index generation the
+	// worker bumps on every write (see pg.BumpSearchGeneration).
+	ResultCache ResultCache
+	// SearchCacheTTL and TypeaheadCacheTTL bound how long responses are served
+	// from ResultCache. Defaults: 60s and 5m. Negative disables caching for
+	// that call type.
+	SearchCacheTTL    time.Duration
+	TypeaheadCacheTTL time.Duration
+	// GenerationRefreshInterval is how often the index generation is re-read
+	// from Postgres (default 1s). This bounds how stale cached results can be
+	// after a worker write, and how long synonym dictionary changes take to
+	// apply.
+	GenerationRefreshInterval time.Duration
+
+	// DefaultWeights are RRF weights per backend used by Search unless
+	// overridden per request (SearchOptions.Weights). Missing backends weigh 1.0.
+	DefaultWeights map[Backend]float32
+	// DefaultFallbackLanguageWeight multiplies the weight of lists retrieved for
+	// fallback languages (e.g. English under LanguageModeFallbackEnglish).
+	// 0 means 1.0.
+	DefaultFallbackLanguageWeight float32
+
+	// TitleBoost boosts trigram and PGroonga matches in the title (A-weight
+	// text) of field-weighted documents (runtime.BuildLexicalDocument), e.g.
+	// 0.5. 0 (the default) disables it, so ranking only changes on opt-in. FTS
+	// ranks by field weight instead.
+	TitleBoost float32
 }
 
 type Client struct {
@@ -52,12 +120,29 @@ type Client struct {
 	schema   string
 	embedder Embedder
 
+	reranker   Reranker
+	rerankDocs RerankDocumentsFunc
+
 	defaultLanguage   string
 	defaultModel      string
 	defaultLimit      int
 	defaultRRFK       int
 	defaultTwoStage   bool
 	defaultOversample int
+
+	defaultBackendTimeout time.Duration
+	defaultAllowPartial   bool
+
+	defaultWeights        map[Backend]float32
+	defaultFallbackWeight float32
+	titleBoost            float32
+
+	resultCache       ResultCache
+	searchCacheTTL    time.Duration
+	typeaheadCacheTTL time.Duration
+	generation        generationTracker
+	synonyms          generationValue[map[string]querynorm.Synonyms]
+	rules             generationValue[[]compiledRule]
 }
 
 func NewClient(cfg ClientConfig) (*Client, error) {
@@ -67,16 +152,32 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 	if strings.TrimSpace(cfg.Schema) == "" {
 		return nil, fmt.Errorf("Schema is required")
 	}
+	if err := validateBackendWeights("ClientConfig.DefaultWeights", cfg.DefaultWeights); err != nil {
+		return nil, err
+	}
+	if cfg.DefaultFallbackLanguageWeight < 0 {
+		return nil, fmt.Errorf("invalid ClientConfig.DefaultFallbackLanguageWeight: must be >= 0")
+	}
+	if cfg.TitleBoost < 0 {
+		return nil, fmt.Errorf("invalid ClientConfig.TitleBoost: must be >= 0")
+	}
 	c := &Client{
-		pool:              cfg.Pool,
-		schema:            strings.TrimSpace(cfg.Schema),
-		embedder:          cfg.Embedder,
-		defaultLanguage:   strings.TrimSpace(cfg.DefaultLanguage),
-		defaultModel:      strings.TrimSpace(cfg.DefaultModel),
-		defaultLimit:      cfg.DefaultLimit,
-		defaultRRFK:       cfg.DefaultRRFK,
-		defaultTwoStage:   cfg.TwoStage,
-		defaultOversample: cfg.OversampleFactor,
+		pool:                  cfg.Pool,
+		schema:                strings.TrimSpace(cfg.Schema),
+		embedder:              cfg.Embedder,
+		reranker:              cfg.Reranker,
+		rerankDocs:            cfg.RerankDocuments,
+		defaultLanguage:       strings.TrimSpace(cfg.DefaultLanguage),
+		defaultModel:          strings.TrimSpace(cfg.DefaultModel),
+		defaultLimit:          cfg.DefaultLimit,
+		defaultRRFK:           cfg.DefaultRRFK,
+		defaultTwoStage:       cfg.TwoStage,
+		defaultOversample:     cfg.OversampleFactor,
+		defaultBackendTimeout: cfg.DefaultBackendTimeout,
+		defaultAllowPartial:   cfg.AllowPartialResults,
+		defaultWeights:        mergeBackendWeights(cfg.DefaultWeights, nil),
+		defaultFallbackWeight: cfg.DefaultFallbackLanguageWeight,
+		titleBoost:            cfg.TitleBoost,
 	}
 	if c.defaultLanguage == "" {
 		c.defaultLanguage = "en"
@@ -90,6 +191,21 @@ func NewClient(cfg ClientConfig) (*Client, error) {
 	if c.defaultOversample < 0 {
 		c.defaultOversample = 0
 	}
+	if cfg.ResultCache != nil {
+		c.resultCache = cfg.ResultCache
+		c.searchCacheTTL = cfg.SearchCacheTTL
+		if c.searchCacheTTL == 0 {
+			c.searchCacheTTL = 60 * time.Second
+		}
+		c.typeaheadCacheTTL = cfg.TypeaheadCacheTTL
+		if c.typeaheadCacheTTL == 0 {
+			c.typeaheadCacheTTL = 5 * time.Minute
+		}
+	}
+	c.generation.every = cfg.GenerationRefreshInterval
+	if c.generation.every <= 0 {
+		c.generation.every = time.Second
+	}
 	return c, nil
 }
 
@@ -97,7 +213,39 @@ type SearchOptions struct {
 	Language string
 	// Defaults to LanguageModeExact when omitted.
 	LanguageMode LanguageMode
-	Mode         SearchMode
+	// FallbackLanguages is the ordered fallback chain used by
+	// LanguageModeFallback and LanguageModeFallbackOnShortfall
+	// (e.g. {"es", "en"} for a pt-BR user).
+	FallbackLanguages []string
+	// FallbackMinHits is the shortfall threshold for
+	// LanguageModeFallbackOnShortfall. 0 means "fill the page": fallbacks are
+	// queried when the earlier languages cannot fill the requested page.
+	FallbackMinHits int
+	// CollapseLanguages merges hits for the same entity found in several
+	// languages into one hit (see SearchHit.LanguageVariants). The merged hit
+	// sums the variants' RRF scores and is represented by the variant earliest
+	// in the language chain (the requested language when it matched).
+	CollapseLanguages bool
+
+	// Grouping applies per-entity-type quotas and collapse-by-key to the fused
+	// ranking (see GroupingOptions and SearchResult.Groups).
+	Grouping *GroupingOptions
+
+	// Boosts combines recency and popularity signals with the fused RRF
+	// score (see BoostOptions and runtime.BuildSignals).
+	Boosts *BoostOptions
+
+	// Rerank reorders the top of the fused ranking with ClientConfig.Reranker
+	// (see RerankOptions).
+	Rerank *RerankOptions
+
+	// Diversity is the MMR lambda used to diversify the ranking: near-duplicate
+	// hits (by cosine similarity of their stored vectors for Model) are
+	// demoted. Higher means more relevance, less diversity; 0 and 1 disable
+	// it. Applied after reranking, before grouping, pins and pagination.
+	Diversity float32
+
+	Mode SearchMode
 
 	// If set, applied to both lexical + semantic entity types unless explicitly overridden.
 	EntityTypes []string
@@ -105,7 +253,11 @@ type SearchOptions struct {
 	LexicalEntityTypes  []string
 	SemanticEntityTypes []string
 
+	// Limit is the page size.
 	Limit int
+	// PageToken continues a previous search (SearchResult.NextPageToken).
+	// Tokens are only valid for the same query and options (except Limit).
+	PageToken string
 
 	// Semantic model override (defaults to client).
 	Model string
@@ -114,21 +266,129 @@ type SearchOptions struct {
 	OversampleFactor int
 	RRFK             int
 
+	// Fusion selects how backend lists are fused: search.FusionRRF (default,
+	// ranks only) or a score-based method using each backend's raw scores
+	// (search.FusionMinMax, search.FusionZScore, search.FusionDBSF). Weights
+	// apply to every method. Score-based methods normalize each list over its
+	// top scoreFusionWindow hits, which every page retrieves, so hit scores
+	// are the same on every page.
+	Fusion search.FusionMethod
+
+	// Weights are RRF weights per backend (e.g. {semantic: 1.5, trigram: 0.5}),
+	// overlaid on ClientConfig.DefaultWeights. Missing backends weigh 1.0.
+	Weights map[Backend]float32
+	// FallbackLanguageWeight multiplies the weight of lists retrieved for
+	// fallback languages. 0 uses ClientConfig.DefaultFallbackLanguageWeight.
+	FallbackLanguageWeight float32
+
 	FilterSQL  string
 	FilterArgs map[string]any
+	// Filter is a builder alternative to FilterSQL/FilterArgs (search.Eq,
+	// search.Exists, ...); both are ANDed when set. search.AttrEq/AttrIn/
+	// AttrRange/AttrExists filter on stored document attributes inside
+	// retrieval, without joining host tables.
+	Filter search.Filter
+
+	// BackendTimeout bounds each backend call (the query embedding and every
+	// retrieval query separately). 0 uses ClientConfig.DefaultBackendTimeout;
+	// both 0 means no timeout beyond ctx.
+	BackendTimeout time.Duration
+	// AllowPartialResults returns fused results from the backends that
+	// succeeded when others fail or time out (reported in
+	// SearchResult.Failures). Search still fails if every backend failed.
+	// nil uses ClientConfig.AllowPartialResults.
+	AllowPartialResults *bool
+
+	// Explain attaches a per-backend score breakdown to every hit
+	// (SearchHit.Explain). Intended for relevance tuning; it adds no queries.
+	Explain bool
+
+	// Highlight attaches matched spans / snippets to the hits of the page
+	// (SearchHit.Highlight). nil disables highlighting.
+	Highlight *HighlightOptions
+
+	// WithFacets also computes per-entity-type/language counts for the query
+	// (SearchResult.Facets), as Client.Facets would with the same options.
+	WithFacets bool
+	// FacetMinSimilarity and FacetMaxCandidates configure the semantic facet
+	// estimate (see FacetOptions.SemanticMinSimilarity/SemanticMaxCandidates).
+	FacetMinSimilarity float32
+	FacetMaxCandidates int
+
+	// RetryWithSuggestion reruns a first-page search that returned no hits
+	// with the best Client.Suggest correction (SearchResult.CorrectedQuery).
+	// Later pages should be requested with the corrected query.
+	RetryWithSuggestion bool
 }
 
 type SearchHit struct {
 	EntityType string
 	EntityID   string
 	Language   string
-	Score      float32
+	// Score is the fused (and boosted) relevance score. With
+	// SearchOptions.Rerank, reranked hits carry the reranker's score instead,
+	// on the reranker's own scale: compare scores only among reranked hits,
+	// or among the others, and rely on the order of Hits across them.
+	Score float32
+
+	// LanguageVariants lists the languages the entity matched in, requested
+	// language first. Set only when SearchOptions.CollapseLanguages is true.
+	LanguageVariants []string
+
+	// Explain is set only when SearchOptions.Explain is true.
+	Explain *HitExplanation
+	// Highlight is set only when SearchOptions.Highlight is set and the hit
+	// has a stored lexical document.
+	Highlight *Highlight
+}
+
+// SearchResult is the response of Client.SearchWithMeta.
+type SearchResult struct {
+	Hits []SearchHit
+	// NextPageToken fetches the next page when passed as SearchOptions.PageToken.
+	// Empty when there are no further results.
+	NextPageToken string
+	// Failures lists backends that failed or timed out. Only non-empty when
+	// partial results are allowed (SearchOptions.AllowPartialResults).
+	Failures []BackendFailure
+	// Facets is set only when SearchOptions.WithFacets is true.
+	Facets *FacetResult
+	// Groups splits Hits by entity type (quota types first, in quota order).
+	// Set only when SearchOptions.Grouping is set.
+	Groups []HitGroup
+	// CorrectedQuery is the query the hits were found with when
+	// SearchOptions.RetryWithSuggestion replaced the user's query.
+	CorrectedQuery string
+	// MatchedRules lists the IDs of the search rules (pg.SearchRule) that
+	// matched the query and were applied: the rewrite/redirect rule first,
+	// then the others by priority.
+	MatchedRules []string
+
+	// OriginalQuery is the user's query; RewrittenQuery is the query searched
+	// instead when a rewrite rule applied (empty otherwise).
+	OriginalQuery  string
+	RewrittenQuery string
+	// Redirect is the target of the redirect rule that matched the query.
+	// When set, no search ran and Hits is empty.
+	Redirect string
 }
 
 type SimilarOptions struct {
 	Language string
-	Model    string
-	Limit    int
+	// Defaults to LanguageModeExact when omitted. Fallback languages look up
+	// the source entity's vector in that language.
+	LanguageMode LanguageMode
+	// FallbackLanguages is the ordered fallback chain used by
+	// LanguageModeFallback and LanguageModeFallbackOnShortfall
+	// (e.g. {"es", "en"} for a pt-BR user).
+	FallbackLanguages []string
+	// FallbackMinHits is the shortfall threshold for
+	// LanguageModeFallbackOnShortfall. 0 means "fill the page": fallbacks are
+	// queried when the earlier languages cannot fill the requested page.
+	FallbackMinHits int
+
+	Model string
+	Limit int
 
 	EntityTypes []string
 	ExcludeIDs  []string
@@ -137,6 +397,8 @@ type SimilarOptions struct {
 
 	FilterSQL  string
 	FilterArgs map[string]any
+	// Filter behaves as in SearchOptions.
+	Filter search.Filter
 }
 
 type SimilarHit struct {
@@ -147,20 +409,65 @@ type SimilarHit struct {
 	Score      float32
 }
 
+// Search runs lexical and/or semantic retrieval and returns fused hits.
+//
+// It is shorthand for SearchWithMeta when only the hits are needed.
 func (c *Client) Search(ctx context.Context, userText string, opts SearchOptions) ([]SearchHit, error) {
-	qEmbed := querynorm.QueryForEmbedding(userText)
-	if qEmbed == "" || !hasAnyLetterOrNumber(qEmbed) {
-		return []SearchHit{}, nil
+	res, err := c.SearchWithMeta(ctx, userText, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res.Hits, nil
+}
+
+// scoreFusionWindow is the search.FusionOptions.Window of score-based fusion.
+const scoreFusionWindow = 100
+
+// SearchWithMeta is like Search but also returns response metadata such as the
+// token for the next page.
+func (c *Client) SearchWithMeta(ctx context.Context, userText string, opts SearchOptions) (*SearchResult, error) {
+	if opts.RetryWithSuggestion && opts.PageToken == "" {
+		return c.searchWithSuggestion(ctx, userText, opts)
+	}
+
+	filterSQL, filterArgs, err := search.CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Filter: %w", err)
+	}
+	opts.FilterSQL, opts.FilterArgs, opts.Filter = filterSQL, filterArgs, nil
+	if err := validateHighlight("SearchOptions.Highlight", opts.Highlight); err != nil {
+		return nil, err
 	}
 
 	language := strings.TrimSpace(opts.Language)
 	if language == "" {
 		language = c.defaultLanguage
 	}
-	languages, err := resolveLanguageModes(language, opts.LanguageMode)
+
+	// Rewrite rules run on the user's query before any normalization.
+	originalQuery := userText
+	rewrite := c.rewriteQuery(ctx, language, userText)
+	if rewrite.redirect != "" {
+		return &SearchResult{Hits: []SearchHit{}, OriginalQuery: originalQuery, Redirect: rewrite.redirect, MatchedRules: []string{rewrite.ruleID}}, nil
+	}
+	if rewrite.query != "" {
+		userText = rewrite.query
+	}
+
+	qEmbed := querynorm.QueryForEmbedding(userText)
+	// The embedder and reranker take the positive terms only: operators,
+	// field names and excluded words would otherwise pull results toward
+	// what the query rejects. A query without positive terms matches nothing.
+	positiveText := querynorm.ParseQuery(qEmbed).PositiveText()
+	if qEmbed == "" || !hasAnyLetterOrNumber(positiveText) {
+		return &SearchResult{Hits: []SearchHit{}, OriginalQuery: originalQuery, RewrittenQuery: rewrite.query}, nil
+	}
+
+	chain, err := resolveLanguageChain(language, opts.LanguageMode, opts.FallbackLanguages, opts.FallbackMinHits)
 	if err != nil {
-		return nil, fmt.Errorf("invalid SearchOptions.LanguageMode %q", opts.LanguageMode)
+		return nil, fmt.Errorf("invalid SearchOptions.LanguageMode %q: %w", opts.LanguageMode, err)
 	}
+	languages := chain.languages
 	mode := opts.Mode
 	if mode == "" {
 		mode = SearchModeDual
@@ -171,27 +478,44 @@ func (c *Client) Search(ctx context.Context, userText string, opts SearchOptions
 		return nil, fmt.Errorf("invalid SearchOptions.Mode %q", mode)
 	}
 
-	limit := opts.Limit
-	if limit <= 0 {
-		limit = c.defaultLimit
+	if err := validateGrouping(opts.Grouping); err != nil {
+		return nil, err
+	}
+	if err := validateBoosts(opts.Boosts); err != nil {
+		return nil, err
+	}
+	if err := c.validateRerank(opts.Rerank); err != nil {
+		return nil, err
+	}
+	if err := validateDiversity(opts.Diversity); err != nil {
+		return nil, err
 	}
 
 	rrfk := opts.RRFK
 	if rrfk <= 0 {
 		rrfk = c.defaultRRFK
 	}
+	if !search.ValidFusionMethod(opts.Fusion) {
+		return nil, fmt.Errorf("invalid SearchOptions.Fusion %q", opts.Fusion)
+	}
+	fusionMethod := opts.Fusion
+	if fusionMethod == "" {
+		fusionMethod = search.FusionRRF
+	}
 
-	lexTypes := cloneAndTrim(opts.LexicalEntityTypes)
-	semTypes := cloneAndTrim(opts.SemanticEntityTypes)
-	if len(opts.EntityTypes) > 0 {
-		all := cloneAndTrim(opts.EntityTypes)
-		if len(lexTypes) == 0 {
-			lexTypes = all
-		}
-		if len(semTypes) == 0 {
-			semTypes = all
-		}
+	if err := validateBackendWeights("SearchOptions.Weights", opts.Weights); err != nil {
+		return nil, err
+	}
+	if opts.FallbackLanguageWeight < 0 {
+		return nil, fmt.Errorf("invalid SearchOptions.FallbackLanguageWeight: must be >= 0")
 	}
+	backendWeights := mergeBackendWeights(c.defaultWeights, opts.Weights)
+	fallbackWeight := opts.FallbackLanguageWeight
+	if fallbackWeight == 0 {
+		fallbackWeight = c.defaultFallbackWeight
+	}
+
+	lexTypes, semTypes := resolveEntityTypes(opts.EntityTypes, opts.LexicalEntityTypes, opts.SemanticEntityTypes)
 
 	if mode != SearchModeSemantic && len(lexTypes) == 0 {
 		return nil, fmt.Errorf("LexicalEntityTypes is required for lexical/dual search")
@@ -200,27 +524,112 @@ func (c *Client) Search(ctx context.Context, userText string, opts SearchOptions
 		return nil, fmt.Errorf("SemanticEntityTypes is required for semantic/dual search")
 	}
 
-	lists := make([][]search.RRFKey, 0, 3)
+	var searched []string
+	if mode != SearchModeSemantic {
+		searched = append(searched, lexTypes...)
+	}
+	if mode != SearchModeLexical {
+		searched = append(searched, semTypes...)
+	}
+	searched = cloneAndTrim(searched)
 
-	if mode == SearchModeLexical || mode == SearchModeDual {
-		for _, lang := range languages {
-			lexLists, err := c.searchLexical(ctx, qEmbed, lang, limit, lexTypes, opts.FilterSQL, opts.FilterArgs)
-			if err != nil {
-				return nil, err
-			}
-			lists = append(lists, lexLists...)
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = groupedLimit(opts.Grouping, searched)
+	}
+	if limit <= 0 {
+		limit = c.defaultLimit
+	}
+
+	model := strings.TrimSpace(opts.Model)
+	if model == "" {
+		model = c.defaultModel
+	}
+	diversify := opts.Diversity > 0 && opts.Diversity < 1
+	if diversify && model == "" {
+		return nil, fmt.Errorf("Model is required for SearchOptions.Diversity")
+	}
+
+	fingerprint := queryFingerprint(
+		"search",
+		qEmbed,
+		chain.fingerprint(),
+		string(mode),
+		strings.Join(lexTypes, ","),
+		strings.Join(semTypes, ","),
+		model,
+		fmt.Sprint(rrfk),
+		string(fusionMethod),
+		weightsFingerprint(backendWeights, fallbackWeight),
+		filterFingerprint(opts.FilterSQL, opts.FilterArgs),
+		fmt.Sprint(opts.CollapseLanguages),
+		groupingFingerprint(opts.Grouping),
+		boostsFingerprint(opts.Boosts),
+		rerankFingerprint(opts.Rerank),
+		fmt.Sprint(opts.Diversity),
+	)
+	cursor, err := decodePageToken(opts.PageToken, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().Truncate(time.Second)
+	if cursor.Clock != 0 {
+		now = time.Unix(cursor.Clock, 0)
+	} else if boostsUseClock(opts.Boosts) {
+		cursor.Clock = now.Unix()
+	}
+	// Each backend only needs to rank deep enough to cover this page. Grouping
+	// drops hits after fusion and boosting reorders them, so backends
+	// over-fetch to still fill it, as does diversification, which demotes
+	// near-duplicates. Reranking needs at least its top N.
+	depth := cursor.Offset + limit
+	overFetch := boostOverFetch(opts.Boosts)
+	if diversify {
+		overFetch = max(overFetch, 2)
+	}
+	if opts.Grouping != nil {
+		g := opts.Grouping.OverFetch
+		if g <= 0 {
+			g = 3
+		}
+		if g > overFetch {
+			overFetch = g
 		}
 	}
+	fetch := max(depth*overFetch, rerankTopN(opts.Rerank))
+	// Score-based fusion normalizes each list over a fixed window, which
+	// every page retrieves, so scores do not shift with the page depth.
+	fusionWindow := 0
+	if fusionMethod != search.FusionRRF {
+		fusionWindow = scoreFusionWindow
+		fetch = max(fetch, fusionWindow)
+	}
+
+	// The original query is part of the key (not of the fingerprint): queries
+	// rewritten to the same text share page tokens, but not the response
+	// metadata (OriginalQuery, MatchedRules).
+	cacheKey := c.resultCacheKey(ctx, "search", c.searchCacheTTL, fingerprint, originalQuery, opts.PageToken, fmt.Sprint(limit), fmt.Sprint(opts.Explain),
+		fmt.Sprint(opts.WithFacets, opts.FacetMinSimilarity, opts.FacetMaxCandidates), highlightFingerprint(opts.Highlight))
+	if res, ok := c.cachedSearch(cacheKey); ok {
+		return res, nil
+	}
 
+	timeout := opts.BackendTimeout
+	if timeout <= 0 {
+		timeout = c.defaultBackendTimeout
+	}
+	allowPartial := c.defaultAllowPartial
+	if opts.AllowPartialResults != nil {
+		allowPartial = *opts.AllowPartialResults
+	}
+
+	var semantic *semanticPlan
+	var embedding *queryEmbedding
 	if mode == SearchModeSemantic || mode == SearchModeDual {
 		if c.embedder == nil {
 			return nil, fmt.Errorf("Embedder is required for semantic search")
 		}
-		model := strings.TrimSpace(opts.Model)
 		if model == "" {
-			model = c.defaultModel
-		}
-		if strings.TrimSpace(model) == "" {
 			return nil, fmt.Errorf("Model is required for semantic search")
 		}
 
@@ -232,49 +641,241 @@ func (c *Client) Search(ctx context.Context, userText string, opts SearchOptions
 		if oversample <= 0 {
 			oversample = c.defaultOversample
 		}
+		embedding = &queryEmbedding{embedder: c.embedder, model: model, query: positiveText}
+		semantic = &semanticPlan{
+			embedding:   embedding,
+			model:       model,
+			limit:       fetch,
+			entityTypes: semTypes,
+			twoStage:    twoStage,
+			oversample:  oversample,
+			filterSQL:   opts.FilterSQL,
+			filterArgs:  opts.FilterArgs,
+			constraints: qEmbed,
+		}
+	}
 
-		vec, err := c.embedder.EmbedQueryText(ctx, model, qEmbed)
-		if err != nil {
-			return nil, err
+	// Facet counting runs concurrently with retrieval and always covers the
+	// whole language chain.
+	var facets *FacetResult
+	var facetErr error
+	var facetWG sync.WaitGroup
+	if opts.WithFacets {
+		fp := facetPlan{
+			query:         qEmbed,
+			languages:     languages,
+			minSimilarity: opts.FacetMinSimilarity,
+			maxCandidates: opts.FacetMaxCandidates,
+			filterSQL:     opts.FilterSQL,
+			filterArgs:    opts.FilterArgs,
+		}
+		if mode != SearchModeSemantic {
+			fp.lexTypes = lexTypes
 		}
-		if len(vec) == 0 {
-			return []SearchHit{}, nil
+		if semantic != nil {
+			fp.semTypes = semTypes
+			fp.model = model
+			fp.embedding = embedding
 		}
+		facetWG.Add(1)
+		go func() {
+			defer facetWG.Done()
+			facets, facetErr = c.runFacets(ctx, fp, timeout, allowPartial)
+		}()
+	}
 
-		for _, lang := range languages {
-			semKeys, err := c.searchSemantic(ctx, lang, model, vec, limit, semTypes, twoStage, oversample, opts.FilterSQL, opts.FilterArgs)
-			if err != nil {
-				return nil, err
+	// Languages are queried in tiers: all at once, or one after another under
+	// LanguageModeFallbackOnShortfall, stopping once enough hits were found.
+	var results []backendResult
+	found := map[search.RRFKey]struct{}{}
+	embedFailed := false
+	tierLexTypes := lexTypes
+	if mode == SearchModeSemantic {
+		tierLexTypes = nil
+	}
+	for i, tier := range chain.tiers() {
+		if i > 0 && len(found) >= chain.shortfallThreshold(depth) {
+			break
+		}
+		for _, r := range c.runSearchTier(ctx, tier, qEmbed, fetch, tierLexTypes, semantic, opts.FilterSQL, opts.FilterArgs, timeout) {
+			if r.failure != nil && r.failure.Backend == BackendSemantic && r.failure.Language == "" {
+				// The query embedding is shared by all tiers; report its failure once.
+				if embedFailed {
+					continue
+				}
+				embedFailed = true
 			}
-			lists = append(lists, semKeys)
+			for _, l := range r.lists {
+				for _, h := range l.hits {
+					found[h.key] = struct{}{}
+				}
+			}
+			results = append(results, r)
+		}
+		if embedding.empty() {
+			break
 		}
 	}
+	facetWG.Wait()
 
-	if len(lists) == 0 {
-		return []SearchHit{}, nil
+	// An empty query embedding means the provider found nothing to embed:
+	// the search returns no hits, as it did before backends ran concurrently.
+	if embedding.empty() {
+		return &SearchResult{Hits: []SearchHit{}, OriginalQuery: originalQuery, RewrittenQuery: rewrite.query}, nil
 	}
 
-	fused := search.FuseRRF(lists, search.RRFOptions{K: rrfk})
-	out := make([]SearchHit, 0, minInt(limit, len(fused)))
+	lists, failures, err := collectBackendResults(results, allowPartial)
+	if err != nil {
+		return nil, err
+	}
+	if facetErr != nil {
+		return nil, facetErr
+	}
+
+	fx := newRuleEffects(c.matchingRules(ctx, languages[0], userText))
+	matched := matchedRuleIDs(rewrite, fx)
+
+	if len(lists) == 0 && len(fx.pins) == 0 {
+		return &SearchResult{Hits: []SearchHit{}, Failures: failures, Facets: facets, MatchedRules: matched,
+			OriginalQuery: originalQuery, RewrittenQuery: rewrite.query}, nil
+	}
+
+	more := false
+	for _, l := range lists {
+		if l.full {
+			more = true
+		}
+	}
+	fusion := search.FusionOptions{
+		Method:  fusionMethod,
+		K:       rrfk,
+		Weights: listWeights(lists, languages[0], backendWeights, fallbackWeight),
+		Window:  fusionWindow,
+	}
+	fused, err := search.Fuse(scoredLists(lists), fusion)
+	if err != nil {
+		return nil, err
+	}
+
+	var explanations map[search.RRFKey]*HitExplanation
+	if opts.Explain {
+		explanations, err = explainLists(lists, fusion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ranked := make([]SearchHit, 0, len(fused))
 	for _, h := range fused {
-		out = append(out, SearchHit{
+		ranked = append(ranked, SearchHit{
 			EntityType: h.EntityType,
 			EntityID:   h.EntityID,
 			Language:   h.Language,
 			Score:      h.Score,
+			Explain:    explanations[h.RRFKey],
 		})
-		if len(out) >= limit {
-			break
+	}
+	if opts.CollapseLanguages {
+		ranked = collapseSearchHits(ranked, chain)
+	}
+	ranked, err = c.applyBoosts(ctx, ranked, opts.Boosts, now)
+	if err != nil {
+		return nil, err
+	}
+	// Search rules apply to the fused ranking: hide and boost/bury before
+	// sorting; pins last, after grouping.
+	ranked, rescored := hideAndScale(ranked, fx, searchHitKey, searchHitScore)
+	if opts.CollapseLanguages || chain.tiered || opts.Boosts != nil || rescored {
+		sortSearchHits(ranked, chain)
+	}
+	if opts.Rerank != nil {
+		var failure *BackendFailure
+		ranked, failure = c.applyRerank(ctx, positiveText, ranked, opts.Rerank, timeout)
+		if failure != nil {
+			if !allowPartial {
+				return nil, *failure
+			}
+			failures = append(failures, *failure)
 		}
 	}
-	return out, nil
+	if diversify {
+		ranked, err = c.diversifyHits(ctx, ranked, chain, model, opts.Diversity)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ranked, err = c.applyGrouping(ctx, ranked, opts.Grouping)
+	if err != nil {
+		return nil, err
+	}
+	pinned, err := c.resolvePins(ctx, missingPins(ranked, fx, searchHitKey), pinScope{
+		languages:   languages,
+		entityTypes: searched,
+		filterSQL:   opts.FilterSQL,
+		filterArgs:  opts.FilterArgs,
+		constraints: qEmbed,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ranked = pinHits(ranked, fx, searchHitKey, func(k entityKey) (SearchHit, bool) {
+		lang, ok := pinned[k]
+		h := SearchHit{EntityType: k.entityType, EntityID: k.entityID, Language: lang}
+		if opts.CollapseLanguages {
+			h.LanguageVariants = []string{lang}
+		}
+		return h, ok
+	})
+
+	start, end := paginate(len(ranked), limit, cursor, func(i int) hitKey {
+		return hitKey{EntityType: ranked[i].EntityType, EntityID: ranked[i].EntityID, Language: ranked[i].Language}
+	})
+	out := ranked[start:end:end]
+
+	if opts.Highlight != nil && len(out) > 0 {
+		keys := make([]search.DocKey, len(out))
+		for i, h := range out {
+			keys[i] = search.DocKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language}
+		}
+		highlights, err := c.highlightDocs(ctx, qEmbed, keys, false, opts.Highlight)
+		if err != nil {
+			return nil, err
+		}
+		for i := range out {
+			out[i].Highlight = highlights[keys[i]]
+		}
+	}
+
+	res := &SearchResult{Hits: out, Failures: failures, Facets: facets, MatchedRules: matched,
+		OriginalQuery: originalQuery, RewrittenQuery: rewrite.query}
+	if opts.Grouping != nil {
+		res.Groups = groupHits(out, opts.Grouping)
+	}
+	if len(out) > 0 {
+		lastHit := out[len(out)-1]
+		res.NextPageToken = nextPageToken(cursor, end, limit,
+			hitKey{EntityType: lastHit.EntityType, EntityID: lastHit.EntityID, Language: lastHit.Language},
+			more || end < len(ranked))
+	}
+	c.storeSearch(cacheKey, res)
+	return res, nil
 }
 
 func (c *Client) SimilarTo(ctx context.Context, entityType string, entityID string, opts SimilarOptions) ([]SimilarHit, error) {
+	filterSQL, filterArgs, err := search.CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Filter: %w", err)
+	}
+	opts.FilterSQL, opts.FilterArgs, opts.Filter = filterSQL, filterArgs, nil
+
 	lang := strings.TrimSpace(opts.Language)
 	if lang == "" {
 		lang = c.defaultLanguage
 	}
+	chain, err := resolveLanguageChain(lang, opts.LanguageMode, opts.FallbackLanguages, opts.FallbackMinHits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SimilarOptions.LanguageMode %q: %w", opts.LanguageMode, err)
+	}
 	model := strings.TrimSpace(opts.Model)
 	if model == "" {
 		model = c.defaultModel
@@ -290,35 +891,171 @@ func (c *Client) SimilarTo(ctx context.Context, entityType string, entityID stri
 		return nil, fmt.Errorf("entityType and entityID are required")
 	}
 
-	rows, err := search.SimilarTo(ctx, c.pool, c.schema, entityType, entityID, model, lang, limit, search.Options{
-		EntityTypes:   cloneAndTrim(opts.EntityTypes),
-		ExcludeIDs:    cloneAndTrim(opts.ExcludeIDs),
-		MinSimilarity: opts.MinSimilarity,
-		FilterSQL:     opts.FilterSQL,
-		FilterArgs:    opts.FilterArgs,
+	// Each language is a tier: its neighbors come before those of the next
+	// language. Under LanguageModeFallbackEnglish both languages are merged by
+	// similarity instead.
+	var out []SimilarHit
+	threshold := chain.shortfallThreshold(limit)
+	for i, l := range chain.languages {
+		if chain.onShortfall && i > 0 && len(out) >= threshold {
+			break
+		}
+		rows, err := search.SimilarTo(ctx, c.pool, c.schema, entityType, entityID, model, l, limit, search.Options{
+			EntityTypes:   cloneAndTrim(opts.EntityTypes),
+			ExcludeIDs:    cloneAndTrim(opts.ExcludeIDs),
+			MinSimilarity: opts.MinSimilarity,
+			FilterSQL:     opts.FilterSQL,
+			FilterArgs:    opts.FilterArgs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			out = append(out, SimilarHit{
+				EntityType: row.EntityType,
+				EntityID:   row.EntityID,
+				Model:      row.Model,
+				Language:   row.Language,
+				Score:      row.Similarity,
+			})
+		}
+	}
+	if !chain.tiered {
+		sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	}
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	if out == nil {
+		out = []SimilarHit{}
+	}
+	return out, nil
+}
+
+// rankedList is one backend's ranked candidate list (best-first).
+type rankedList struct {
+	backend  Backend
+	language string
+	model    string
+	hits     []rankedHit
+	// full reports whether the backend returned as many rows as requested, i.e.
+	// deeper pages may still find more candidates.
+	full bool
+}
+
+type rankedHit struct {
+	key search.RRFKey
+	// rawScore is the backend's own score (see ListContribution.RawScore).
+	rawScore float32
+}
+
+func (l *rankedList) add(entityType, entityID, language string, rawScore float32) {
+	l.hits = append(l.hits, rankedHit{
+		key:      search.RRFKey{EntityType: entityType, EntityID: entityID, Language: language},
+		rawScore: rawScore,
 	})
-	if err != nil {
-		return nil, err
+}
+
+func (l rankedList) keys() []search.RRFKey {
+	out := make([]search.RRFKey, len(l.hits))
+	for i, h := range l.hits {
+		out[i] = h.key
 	}
+	return out
+}
 
-	out := make([]SimilarHit, 0, len(rows))
-	for _, row := range rows {
-		out = append(out, SimilarHit{
-			EntityType: row.EntityType,
-			EntityID:   row.EntityID,
-			Model:      row.Model,
-			Language:   row.Language,
-			Score:      row.Similarity,
-		})
+// scoredLists returns the lists' keys with their raw scores, for search.Fuse.
+func scoredLists(lists []rankedList) [][]search.ScoredKey {
+	out := make([][]search.ScoredKey, len(lists))
+	for i, l := range lists {
+		out[i] = make([]search.ScoredKey, len(l.hits))
+		for j, h := range l.hits {
+			out[i][j] = search.ScoredKey{RRFKey: h.key, Score: h.rawScore}
+		}
 	}
-	return out, nil
+	return out
 }
 
-func (c *Client) searchLexical(ctx context.Context, q string, language string, limit int, entityTypes []string, filterSQL string, filterArgs map[string]any) ([][]search.RRFKey, error) {
-	route := lexicalRouting(language, q, false)
-	out := make([][]search.RRFKey, 0, 2)
+// runSearchTier runs the lexical backends (when entityTypes is set) and the
+// semantic plan (when set) for languages concurrently.
+func (c *Client) runSearchTier(
+	ctx context.Context,
+	languages []string,
+	q string,
+	limit int,
+	entityTypes []string,
+	semantic *semanticPlan,
+	filterSQL string,
+	filterArgs map[string]any,
+	timeout time.Duration,
+) []backendResult {
+	var lexTasks []backendTask
+	if len(entityTypes) > 0 {
+		for _, lang := range languages {
+			lexTasks = append(lexTasks, c.lexicalTasks(q, lang, limit, entityTypes, filterSQL, filterArgs)...)
+		}
+	}
 
+	var lexResults, semResults []backendResult
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lexResults = runBackendTasks(ctx, lexTasks, timeout)
+	}()
+	if semantic != nil {
+		plan := *semantic
+		plan.languages = languages
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semResults = c.runSemantic(ctx, plan, timeout)
+		}()
+	}
+	wg.Wait()
+	return append(lexResults, semResults...)
+}
+
+// lexicalTasks returns one retrieval task per lexical backend routed for
+// language.
+func (c *Client) lexicalTasks(q string, language string, limit int, entityTypes []string, filterSQL string, filterArgs map[string]any) []backendTask {
+	route := lexicalRouting(language, q, false)
+	var backends []Backend
 	if route.useFTS {
+		backends = append(backends, BackendFTS)
+	}
+	if route.useTrigram {
+		backends = append(backends, BackendTrigram)
+	}
+	if route.usePGroonga {
+		backends = append(backends, BackendPGroonga)
+	}
+
+	out := make([]backendTask, 0, len(backends))
+	for _, b := range backends {
+		b := b
+		out = append(out, backendTask{
+			backend:  b,
+			language: language,
+			run: func(ctx context.Context) ([]rankedList, error) {
+				list, err := c.searchLexical(ctx, b, q, language, limit, entityTypes, filterSQL, filterArgs)
+				if err != nil {
+					return nil, err
+				}
+				return []rankedList{list}, nil
+			},
+		})
+	}
+	return out
+}
+
+func (c *Client) searchLexical(ctx context.Context, backend Backend, q string, language string, limit int, entityTypes []string, filterSQL string, filterArgs map[string]any) (rankedList, error) {
+	list := rankedList{backend: backend, language: language}
+
+	synonyms := c.synonymsFor(ctx, language)
+
+	switch backend {
+	case BackendFTS:
 		lex, err := search.FTSSearch(ctx, c.pool, q, search.FTSOptions{
 			Schema:      c.schema,
 			Language:    language,
@@ -326,62 +1063,130 @@ func (c *Client) searchLexical(ctx context.Context, q string, language string, l
 			Limit:       limit,
 			FilterSQL:   filterSQL,
 			FilterArgs:  filterArgs,
+			Synonyms:    synonyms,
 		})
 		if err != nil {
-			return nil, err
+			return rankedList{}, err
 		}
-		keys := make([]search.RRFKey, 0, len(lex))
 		for _, h := range lex {
-			keys = append(keys, search.RRFKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language})
+			list.add(h.EntityType, h.EntityID, h.Language, h.Score)
 		}
-		out = append(out, keys)
-	}
 
-	if route.useTrigram {
-		lex, err := search.LexicalSearch(ctx, c.pool, q, search.LexicalOptions{
-			Schema:        c.schema,
-			Language:      language,
-			EntityTypes:   entityTypes,
-			Limit:         limit,
-			MinSimilarity: 0.1,
-			FilterSQL:     filterSQL,
-			FilterArgs:    filterArgs,
-		})
-		if err != nil {
-			return nil, err
-		}
-		keys := make([]search.RRFKey, 0, len(lex))
-		for _, h := range lex {
-			keys = append(keys, search.RRFKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language})
+	case BackendTrigram:
+		// Trigram has no operators, so OR groups (including synonyms) run as
+		// extra probes and each document keeps its best score across probes.
+		// Field scopes and exclusions of the whole query constrain every probe.
+		probes := querynorm.ParseQuery(q).ExpandSynonyms(synonyms).TrigramProbes(1 + maxSynonymProbes)
+		var merged []rankedHit
+		for _, probe := range probes {
+			hits, err := c.lexicalProbe(ctx, probe, q, language, limit, entityTypes, filterSQL, filterArgs)
+			if err != nil {
+				return rankedList{}, err
+			}
+			merged = append(merged, hits...)
 		}
-		out = append(out, keys)
-	}
+		list.hits = bestRankedHits(merged, limit)
 
-	if route.usePGroonga {
+	case BackendPGroonga:
 		lex, err := search.PGroongaSearch(ctx, c.pool, q, search.PGroongaOptions{
 			Schema:      c.schema,
 			Language:    language,
 			EntityTypes: entityTypes,
 			Limit:       limit,
-			Prefix:      false,
+			Structured:  true,
+			Synonyms:    synonyms,
 			ScoreK:      1,
+			TitleBoost:  c.titleBoost,
 			FilterSQL:   filterSQL,
 			FilterArgs:  filterArgs,
 		})
 		if err != nil {
-			return nil, err
+			return rankedList{}, err
 		}
-		keys := make([]search.RRFKey, 0, len(lex))
 		for _, h := range lex {
-			keys = append(keys, search.RRFKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language})
+			list.add(h.EntityType, h.EntityID, h.Language, h.RawScore)
 		}
-		out = append(out, keys)
+
+	default:
+		return rankedList{}, fmt.Errorf("unsupported lexical backend %q", backend)
 	}
 
-	if len(out) == 0 {
-		return nil, nil
+	list.full = len(list.hits) >= limit
+	return list, nil
+}
+
+// lexicalProbe runs one trigram query for probe, constrained by the field
+// scopes and exclusions of the user query q.
+func (c *Client) lexicalProbe(ctx context.Context, probe string, q string, language string, limit int, entityTypes []string, filterSQL string, filterArgs map[string]any) ([]rankedHit, error) {
+	lex, err := search.LexicalSearch(ctx, c.pool, probe, search.LexicalOptions{
+		Schema:        c.schema,
+		Language:      language,
+		EntityTypes:   entityTypes,
+		Limit:         limit,
+		MinSimilarity: 0.1,
+		TitleBoost:    c.titleBoost,
+		FilterSQL:     filterSQL,
+		FilterArgs:    filterArgs,
+		Constraints:   q,
+	})
+	if err != nil {
+		return nil, err
 	}
-	return out, nil
+	list := rankedList{}
+	for _, h := range lex {
+		list.add(h.EntityType, h.EntityID, h.Language, h.Score)
+	}
+	return list.hits, nil
+}
+
+// semanticPlan holds the resolved semantic side of a Search call.
+type semanticPlan struct {
+	embedding   *queryEmbedding
+	model       string
+	languages   []string
+	limit       int
+	entityTypes []string
+	twoStage    bool
+	oversample  int
+	filterSQL   string
+	filterArgs  map[string]any
+	// constraints is the user query whose exclusions apply to the KNN (see
+	// search.Options.Constraints).
+	constraints string
+}
+
+// runSemantic embeds the query once, then runs one KNN query per language
+// concurrently.
+func (c *Client) runSemantic(ctx context.Context, p semanticPlan, timeout time.Duration) []backendResult {
+	var vec []float32
+	failure := runBackendCall(ctx, BackendSemantic, "", timeout, func(ctx context.Context) error {
+		var err error
+		vec, err = p.embedding.get(ctx)
+		return err
+	})
+	if failure != nil {
+		return []backendResult{{failure: failure}}
+	}
+	if len(vec) == 0 {
+		return nil
+	}
+
+	tasks := make([]backendTask, 0, len(p.languages))
+	for _, lang := range p.languages {
+		lang := lang
+		tasks = append(tasks, backendTask{
+			backend:  BackendSemantic,
+			language: lang,
+			run: func(ctx context.Context) ([]rankedList, error) {
+				list, err := c.searchSemantic(ctx, lang, p.model, vec, p.limit, p.entityTypes, p.twoStage, p.oversample, p.filterSQL, p.filterArgs, p.constraints)
+				if err != nil {
+					return nil, err
+				}
+				return []rankedList{list}, nil
+			},
+		})
+	}
+	return runBackendTasks(ctx, tasks, timeout)
 }
 
 func (c *Client) searchSemantic(
@@ -395,7 +1200,8 @@ func (c *Client) searchSemantic(
 	oversampleFactor int,
 	filterSQL string,
 	filterArgs map[string]any,
-) ([]search.RRFKey, error) {
+	constraints string,
+) (rankedList, error) {
 	sem, err := search.SemanticSearch(ctx, c.pool, search.Query{
 		Schema:     c.schema,
 		Model:      model,
@@ -409,27 +1215,51 @@ func (c *Client) searchSemantic(
 			OversampleFactor: oversampleFactor,
 			FilterSQL:        filterSQL,
 			FilterArgs:       filterArgs,
+			Constraints:      constraints,
 		},
 	})
 	if err != nil {
-		return nil, err
+		return rankedList{}, err
 	}
-	keys := make([]search.RRFKey, 0, len(sem))
+	list := rankedList{backend: BackendSemantic, language: language, model: model, full: len(sem) >= limit}
 	for _, h := range sem {
-		keys = append(keys, search.RRFKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language})
+		list.add(h.EntityType, h.EntityID, h.Language, h.Similarity)
 	}
-	return keys, nil
+	return list, nil
 }
 
 type TypeaheadOptions struct {
 	Language string
 	// Defaults to LanguageModeExact when omitted.
-	LanguageMode  LanguageMode
-	EntityTypes   []string
-	Limit         int
+	LanguageMode LanguageMode
+	// FallbackLanguages is the ordered fallback chain used by
+	// LanguageModeFallback and LanguageModeFallbackOnShortfall
+	// (e.g. {"es", "en"} for a pt-BR user).
+	FallbackLanguages []string
+	// FallbackMinHits is the shortfall threshold for
+	// LanguageModeFallbackOnShortfall. 0 means "fill the page": fallbacks are
+	// queried when the earlier languages cannot fill the requested page.
+	FallbackMinHits int
+	// CollapseLanguages merges hits for the same entity found in several
+	// languages (see SearchOptions.CollapseLanguages). The merged hit keeps the
+	// best variant's score.
+	CollapseLanguages bool
+
+	EntityTypes []string
+	// Limit is the page size.
+	Limit int
+	// PageToken continues a previous typeahead call
+	// (TypeaheadResult.NextPageToken).
+	PageToken     string
 	MinSimilarity float32
 	FilterSQL     string
 	FilterArgs    map[string]any
+	// Filter behaves as in SearchOptions.
+	Filter search.Filter
+
+	// Highlight attaches matched spans / snippets to the hits of the page
+	// (TypeaheadHit.Highlight). nil disables highlighting.
+	Highlight *HighlightOptions
 }
 
 type TypeaheadHit struct {
@@ -437,22 +1267,72 @@ type TypeaheadHit struct {
 	EntityID   string
 	Language   string
 	Score      float32
+
+	// LanguageVariants lists the languages the entity matched in, requested
+	// language first. Set only when TypeaheadOptions.CollapseLanguages is true.
+	LanguageVariants []string
+	// Highlight is set only when TypeaheadOptions.Highlight is set.
+	Highlight *Highlight
+}
+
+// TypeaheadResult is the response of Client.TypeaheadWithMeta.
+type TypeaheadResult struct {
+	Hits []TypeaheadHit
+	// NextPageToken fetches the next page when passed as
+	// TypeaheadOptions.PageToken. Empty when there are no further results.
+	NextPageToken string
+	// MatchedRules lists the IDs of the search rules that matched the query
+	// (see SearchResult.MatchedRules).
+	MatchedRules []string
+
+	// OriginalQuery, RewrittenQuery and Redirect behave as in SearchResult.
+	OriginalQuery  string
+	RewrittenQuery string
+	Redirect       string
 }
 
 // Typeahead returns suggestions while a user is typing (typos/substring matching).
 func (c *Client) Typeahead(ctx context.Context, userText string, opts TypeaheadOptions) ([]TypeaheadHit, error) {
-	q := querynorm.QueryForEmbedding(userText)
-	if q == "" || !hasAnyLetterOrNumber(q) {
-		return []TypeaheadHit{}, nil
+	res, err := c.TypeaheadWithMeta(ctx, userText, opts)
+	if err != nil {
+		return nil, err
+	}
+	return res.Hits, nil
+}
+
+// TypeaheadWithMeta is like Typeahead but also returns response metadata such
+// as the token for the next page.
+func (c *Client) TypeaheadWithMeta(ctx context.Context, userText string, opts TypeaheadOptions) (*TypeaheadResult, error) {
+	filterSQL, filterArgs, err := search.CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Filter: %w", err)
+	}
+	opts.FilterSQL, opts.FilterArgs, opts.Filter = filterSQL, filterArgs, nil
+	if err := validateHighlight("TypeaheadOptions.Highlight", opts.Highlight); err != nil {
+		return nil, err
 	}
 
 	language := strings.TrimSpace(opts.Language)
 	if language == "" {
 		language = c.defaultLanguage
 	}
-	languages, err := resolveLanguageModes(language, opts.LanguageMode)
+
+	originalQuery := userText
+	rewrite := c.rewriteQuery(ctx, language, userText)
+	if rewrite.redirect != "" {
+		return &TypeaheadResult{Hits: []TypeaheadHit{}, OriginalQuery: originalQuery, Redirect: rewrite.redirect, MatchedRules: []string{rewrite.ruleID}}, nil
+	}
+	if rewrite.query != "" {
+		userText = rewrite.query
+	}
+
+	q := querynorm.QueryForEmbedding(userText)
+	if q == "" || !hasAnyLetterOrNumber(q) {
+		return &TypeaheadResult{Hits: []TypeaheadHit{}, OriginalQuery: originalQuery, RewrittenQuery: rewrite.query}, nil
+	}
+	chain, err := resolveLanguageChain(language, opts.LanguageMode, opts.FallbackLanguages, opts.FallbackMinHits)
 	if err != nil {
-		return nil, fmt.Errorf("invalid TypeaheadOptions.LanguageMode %q", opts.LanguageMode)
+		return nil, fmt.Errorf("invalid TypeaheadOptions.LanguageMode %q: %w", opts.LanguageMode, err)
 	}
 	entityTypes := cloneAndTrim(opts.EntityTypes)
 	if len(entityTypes) == 0 {
@@ -464,6 +1344,27 @@ func (c *Client) Typeahead(ctx context.Context, userText string, opts TypeaheadO
 	}
 	minSim := opts.MinSimilarity
 
+	fingerprint := queryFingerprint(
+		"typeahead",
+		q,
+		chain.fingerprint(),
+		strings.Join(entityTypes, ","),
+		fmt.Sprint(minSim),
+		filterFingerprint(opts.FilterSQL, opts.FilterArgs),
+		fmt.Sprint(opts.CollapseLanguages),
+	)
+	cursor, err := decodePageToken(opts.PageToken, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	depth := cursor.Offset + limit
+	more := false
+
+	cacheKey := c.resultCacheKey(ctx, "typeahead", c.typeaheadCacheTTL, fingerprint, originalQuery, opts.PageToken, fmt.Sprint(limit), highlightFingerprint(opts.Highlight))
+	if res, ok := c.cachedTypeahead(cacheKey); ok {
+		return res, nil
+	}
+
 	type key struct {
 		t string
 		i string
@@ -477,7 +1378,11 @@ func (c *Client) Typeahead(ctx context.Context, userText string, opts TypeaheadO
 		}
 	}
 
-	for _, lang := range languages {
+	threshold := chain.shortfallThreshold(depth)
+	for i, lang := range chain.languages {
+		if chain.onShortfall && i > 0 && len(merged) >= threshold {
+			break
+		}
 		route := lexicalRouting(lang, q, true)
 
 		if route.useTrigram {
@@ -485,14 +1390,18 @@ func (c *Client) Typeahead(ctx context.Context, userText string, opts TypeaheadO
 				Schema:        c.schema,
 				Language:      lang,
 				EntityTypes:   entityTypes,
-				Limit:         limit,
+				Limit:         depth,
 				MinSimilarity: minSim,
+				TitleBoost:    c.titleBoost,
 				FilterSQL:     opts.FilterSQL,
 				FilterArgs:    opts.FilterArgs,
 			})
 			if err != nil {
 				return nil, err
 			}
+			if len(hits) >= depth {
+				more = true
+			}
 			for _, h := range hits {
 				add(TypeaheadHit{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language, Score: h.Score})
 			}
@@ -503,15 +1412,19 @@ func (c *Client) Typeahead(ctx context.Context, userText string, opts TypeaheadO
 				Schema:      c.schema,
 				Language:    lang,
 				EntityTypes: entityTypes,
-				Limit:       limit,
+				Limit:       depth,
 				Prefix:      true,
 				ScoreK:      1,
+				TitleBoost:  c.titleBoost,
 				FilterSQL:   opts.FilterSQL,
 				FilterArgs:  opts.FilterArgs,
 			})
 			if err != nil {
 				return nil, err
 			}
+			if len(hits) >= depth {
+				more = true
+			}
 			for _, h := range hits {
 				if minSim > 0 && h.Score < minSim {
 					continue
@@ -521,12 +1434,23 @@ func (c *Client) Typeahead(ctx context.Context, userText string, opts TypeaheadO
 		}
 	}
 
+	fx := newRuleEffects(c.matchingRules(ctx, chain.languages[0], userText))
+
 	out := make([]TypeaheadHit, 0, len(merged))
 	for _, h := range merged {
 		out = append(out, h)
 	}
+	if opts.CollapseLanguages {
+		out = collapseTypeaheadHits(out, chain)
+	}
+	out, _ = hideAndScale(out, fx, typeaheadHitKey, typeaheadHitScore)
 	sort.Slice(out, func(i, j int) bool {
 		a, b := out[i], out[j]
+		if chain.tiered && a.Language != b.Language {
+			if ra, rb := chain.rank(a.Language), chain.rank(b.Language); ra != rb {
+				return ra < rb
+			}
+		}
 		if a.Score != b.Score {
 			return a.Score > b.Score
 		}
@@ -538,10 +1462,51 @@ func (c *Client) Typeahead(ctx context.Context, userText string, opts TypeaheadO
 		}
 		return a.Language < b.Language
 	})
-	if len(out) > limit {
-		out = out[:limit]
+	pinned, err := c.resolvePins(ctx, missingPins(out, fx, typeaheadHitKey), pinScope{
+		languages:   chain.languages,
+		entityTypes: entityTypes,
+		filterSQL:   opts.FilterSQL,
+		filterArgs:  opts.FilterArgs,
+	})
+	if err != nil {
+		return nil, err
 	}
-	return out, nil
+	out = pinHits(out, fx, typeaheadHitKey, func(k entityKey) (TypeaheadHit, bool) {
+		lang, ok := pinned[k]
+		h := TypeaheadHit{EntityType: k.entityType, EntityID: k.entityID, Language: lang}
+		if opts.CollapseLanguages {
+			h.LanguageVariants = []string{lang}
+		}
+		return h, ok
+	})
+
+	start, end := paginate(len(out), limit, cursor, func(i int) hitKey {
+		return hitKey{EntityType: out[i].EntityType, EntityID: out[i].EntityID, Language: out[i].Language}
+	})
+	page := out[start:end]
+	if opts.Highlight != nil && len(page) > 0 {
+		keys := make([]search.DocKey, len(page))
+		for i, h := range page {
+			keys[i] = search.DocKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language}
+		}
+		highlights, err := c.highlightDocs(ctx, q, keys, true, opts.Highlight)
+		if err != nil {
+			return nil, err
+		}
+		for i := range page {
+			page[i].Highlight = highlights[keys[i]]
+		}
+	}
+	res := &TypeaheadResult{Hits: page, MatchedRules: matchedRuleIDs(rewrite, fx),
+		OriginalQuery: originalQuery, RewrittenQuery: rewrite.query}
+	if end > start {
+		lastHit := out[end-1]
+		res.NextPageToken = nextPageToken(cursor, end, limit,
+			hitKey{EntityType: lastHit.EntityType, EntityID: lastHit.EntityID, Language: lastHit.Language},
+			more || end < len(out))
+	}
+	c.storeTypeahead(cacheKey, res)
+	return res, nil
 }
 
 func isCJKLanguage(lang string) bool {
@@ -592,6 +1557,23 @@ func resolveLanguageModes(language string, mode LanguageMode) ([]string, error)
 	}
 }
 
+// resolveEntityTypes applies the shared EntityTypes to whichever of the
+// lexical/semantic lists is not set explicitly.
+func resolveEntityTypes(all []string, lexical []string, semantic []string) (lexTypes []string, semTypes []string) {
+	lexTypes = cloneAndTrim(lexical)
+	semTypes = cloneAndTrim(semantic)
+	if len(all) > 0 {
+		shared := cloneAndTrim(all)
+		if len(lexTypes) == 0 {
+			lexTypes = shared
+		}
+		if len(semTypes) == 0 {
+			semTypes = shared
+		}
+	}
+	return lexTypes, semTypes
+}
+
 func cloneAndTrim(in []string) []string {
 	seen := map[string]struct{}{}
 	out := make([]string, 0, len(in))
@@ -611,10 +1593,3 @@ func cloneAndTrim(in []string) []string {
 	}
 	return out
 }
-
-func minInt(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
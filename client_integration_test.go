@@ -2,10 +2,19 @@ package searchkit
 
 import (
 	"context"
+	"io/fs"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/open-rails/searchkit/embedder"
+	"github.com/open-rails/searchkit/migrations"
+	"github.com/open-rails/searchkit/pg"
+	"github.com/open-rails/searchkit/runtime"
+	"github.com/open-rails/searchkit/tasks"
+	"github.com/open-rails/searchkit/worker"
 	"github.com/pgvector/pgvector-go"
 )
 
@@ -120,6 +129,44 @@ func TestClientSearch_Integration_LexicalAndSemantic(t *testing.T) {
 		t.Fatalf("expected lexical hit entity_id=1, got %+v", lexHits)
 	}
 
+	page1, err := client.SearchWithMeta(ctx, "two factor", SearchOptions{
+		Mode:               SearchModeLexical,
+		Language:           "en",
+		LexicalEntityTypes: []string{"gallery"},
+		Limit:              1,
+	})
+	if err != nil {
+		t.Fatalf("paged lexical Search: %v", err)
+	}
+	if len(page1.Hits) != 1 || page1.NextPageToken == "" {
+		t.Fatalf("expected one hit and a next page token, got %+v", page1)
+	}
+	page2, err := client.SearchWithMeta(ctx, "two factor", SearchOptions{
+		Mode:               SearchModeLexical,
+		Language:           "en",
+		LexicalEntityTypes: []string{"gallery"},
+		Limit:              1,
+		PageToken:          page1.NextPageToken,
+	})
+	if err != nil {
+		t.Fatalf("paged lexical Search page 2: %v", err)
+	}
+	if len(page2.Hits) != 1 || page2.Hits[0].EntityID == page1.Hits[0].EntityID {
+		t.Fatalf("expected a distinct second page hit, got %+v then %+v", page1.Hits, page2.Hits)
+	}
+
+	facets, err := client.Facets(ctx, "two factor", FacetOptions{
+		Mode:               SearchModeLexical,
+		Language:           "en",
+		LexicalEntityTypes: []string{"gallery"},
+	})
+	if err != nil {
+		t.Fatalf("Facets: %v", err)
+	}
+	if len(facets.Counts) != 1 || facets.Counts[0].EntityType != "gallery" || facets.Counts[0].Count != 2 {
+		t.Fatalf("expected gallery/en count 2, got %+v", facets.Counts)
+	}
+
 	semHits, err := client.Search(ctx, "two-factor", SearchOptions{
 		Mode:                SearchModeSemantic,
 		Language:            "en",
@@ -252,3 +299,325 @@ func TestClientSearch_Integration_LexicalAndSemantic(t *testing.T) {
 		}
 	}
 }
+
+// Synonyms are optional: the test above runs without the synonym and
+// generation tables. This one adds them and checks that Search and Facets
+// expand the query the same way.
+func TestClientSearch_Integration_Synonyms(t *testing.T) {
+	dsn := os.Getenv("SEARCHKIT_TEST_URL")
+	if dsn == "" {
+		t.Skip("SEARCHKIT_TEST_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("pgxpool: %v", err)
+	}
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE SCHEMA IF NOT EXISTS s_synonyms;
+		SET search_path = s_synonyms, public;
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+		CREATE OR REPLACE FUNCTION searchkit_regconfig_for_language(lang text)
+		RETURNS regconfig
+		LANGUAGE sql
+		IMMUTABLE
+		AS $$
+			SELECT 'simple'::regconfig
+		$$;
+
+		CREATE TABLE IF NOT EXISTS search_documents (
+			entity_type text NOT NULL,
+			entity_id text NOT NULL,
+			language text NOT NULL,
+			raw_document text,
+			tsv tsvector,
+			created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (entity_type, entity_id, language)
+		);
+
+		CREATE TABLE IF NOT EXISTS search_generation (
+			id boolean PRIMARY KEY DEFAULT true CHECK (id),
+			generation bigint NOT NULL DEFAULT 0,
+			updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE IF NOT EXISTS search_synonyms (
+			language text NOT NULL,
+			term text NOT NULL,
+			synonyms text[] NOT NULL,
+			mode text NOT NULL DEFAULT 'two_way',
+			created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (language, term)
+		);
+
+		TRUNCATE TABLE search_documents;
+		TRUNCATE TABLE search_synonyms;
+
+		INSERT INTO search_documents(entity_type, entity_id, language, raw_document, tsv) VALUES
+			('gallery', '1', 'en', 'Two factor authentication', to_tsvector('simple', 'Two factor authentication')),
+			('gallery', '2', 'en', 'Two factor backup codes', to_tsvector('simple', 'Two factor backup codes'));
+	`)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	client, err := NewClient(ClientConfig{
+		Pool:   pool,
+		Schema: "s_synonyms",
+		// Pick up the synonym change below immediately.
+		GenerationRefreshInterval: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	if err := pg.UpsertSynonym(ctx, pool, "s_synonyms", pg.Synonym{
+		Language: "en",
+		Term:     "2FA",
+		Synonyms: []string{"two factor"},
+		Mode:     pg.SynonymModeOneWay,
+	}); err != nil {
+		t.Fatalf("UpsertSynonym: %v", err)
+	}
+	synHits, err := client.Search(ctx, "2fa backup", SearchOptions{
+		Mode:               SearchModeLexical,
+		Language:           "en",
+		LexicalEntityTypes: []string{"gallery"},
+		Limit:              10,
+	})
+	if err != nil {
+		t.Fatalf("synonym lexical Search: %v", err)
+	}
+	if len(synHits) != 1 || synHits[0].EntityID != "2" {
+		t.Fatalf("expected synonym-expanded hit entity_id=2, got %+v", synHits)
+	}
+
+	facets, err := client.Facets(ctx, "2fa", FacetOptions{
+		Mode:               SearchModeLexical,
+		Language:           "en",
+		LexicalEntityTypes: []string{"gallery"},
+	})
+	if err != nil {
+		t.Fatalf("synonym Facets: %v", err)
+	}
+	if len(facets.Counts) != 1 || facets.Counts[0].Count != 2 {
+		t.Fatalf("expected synonym-expanded gallery/en count 2, got %+v", facets.Counts)
+	}
+}
+
+// A pinned entity is only inserted when retrieval could have returned it:
+// filtered-out and missing entities are never pinned.
+func TestClientSearch_Integration_PinsRespectFilters(t *testing.T) {
+	dsn := os.Getenv("SEARCHKIT_TEST_URL")
+	if dsn == "" {
+		t.Skip("SEARCHKIT_TEST_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("pgxpool: %v", err)
+	}
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		DROP SCHEMA IF EXISTS s_pins CASCADE;
+		CREATE SCHEMA s_pins;
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+		CREATE FUNCTION s_pins.searchkit_regconfig_for_language(lang text)
+		RETURNS regconfig
+		LANGUAGE sql
+		IMMUTABLE
+		AS $$
+			SELECT 'simple'::regconfig
+		$$;
+
+		CREATE TABLE s_pins.search_documents (
+			entity_type text NOT NULL,
+			entity_id text NOT NULL,
+			language text NOT NULL,
+			raw_document text,
+			tsv tsvector,
+			PRIMARY KEY (entity_type, entity_id, language)
+		);
+
+		CREATE TABLE s_pins.search_generation (
+			id boolean PRIMARY KEY DEFAULT true CHECK (id),
+			generation bigint NOT NULL DEFAULT 0,
+			updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE TABLE s_pins.search_rules (
+			id text PRIMARY KEY,
+			language text NOT NULL DEFAULT '',
+			match_type text NOT NULL,
+			pattern text NOT NULL,
+			actions jsonb NOT NULL DEFAULT '[]',
+			priority integer NOT NULL DEFAULT 0,
+			enabled boolean NOT NULL DEFAULT true,
+			created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- 1 and 2 match the query; 3 does not; 2 is filtered out.
+		INSERT INTO s_pins.search_documents(entity_type, entity_id, language, raw_document, tsv) VALUES
+			('gallery', '1', 'en', 'Two factor authentication', to_tsvector('simple', 'Two factor authentication')),
+			('gallery', '2', 'en', 'Two factor backup codes', to_tsvector('simple', 'Two factor backup codes')),
+			('gallery', '3', 'en', 'Password managers', to_tsvector('simple', 'Password managers'));
+	`)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := pg.UpsertSearchRule(ctx, pool, "s_pins", pg.SearchRule{
+		ID:      "promo",
+		Match:   pg.RuleMatchExact,
+		Pattern: "factor",
+		Actions: []pg.RuleAction{
+			{Type: pg.RuleActionPin, EntityType: "gallery", EntityID: "2", Position: 1},
+			{Type: pg.RuleActionPin, EntityType: "gallery", EntityID: "3", Position: 2},
+			{Type: pg.RuleActionPin, EntityType: "gallery", EntityID: "404", Position: 3},
+		},
+	}); err != nil {
+		t.Fatalf("UpsertSearchRule: %v", err)
+	}
+
+	client, err := NewClient(ClientConfig{Pool: pool, Schema: "s_pins"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	hits, err := client.Search(ctx, "factor", SearchOptions{
+		Mode:               SearchModeLexical,
+		Language:           "en",
+		LexicalEntityTypes: []string{"gallery"},
+		Limit:              10,
+		FilterSQL:          "sd.entity_id <> @hidden_id",
+		FilterArgs:         map[string]any{"hidden_id": "2"},
+	})
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	var got []string
+	for _, h := range hits {
+		got = append(got, h.EntityID)
+	}
+	if want := []string{"1", "3"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("hits = %v; want %v (2 is filtered out, 404 does not exist)", got, want)
+	}
+}
+
+// fixedTextEmbedder embeds every document as vec.
+type fixedTextEmbedder struct{ vec []float32 }
+
+func (e fixedTextEmbedder) Model() string   { return "m" }
+func (e fixedTextEmbedder) Dimensions() int { return len(e.vec) }
+func (e fixedTextEmbedder) EmbedText(context.Context, string) ([]float32, error) {
+	return e.vec, nil
+}
+func (e fixedTextEmbedder) EmbedTexts(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i := range texts {
+		out[i] = e.vec
+	}
+	return out, nil
+}
+
+func TestClientSearch_Integration_CacheMissesAfterDrain(t *testing.T) {
+	dsn := os.Getenv("SEARCHKIT_TEST_URL")
+	if dsn == "" {
+		t.Skip("SEARCHKIT_TEST_URL not set")
+	}
+
+	ctx := context.Background()
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("parse dsn: %v", err)
+	}
+	// The migrations use unqualified names.
+	cfg.ConnConfig.RuntimeParams["search_path"] = "s_drain, public"
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("pgxpool: %v", err)
+	}
+	defer pool.Close()
+
+	if _, err := pool.Exec(ctx, `DROP SCHEMA IF EXISTS s_drain CASCADE; CREATE SCHEMA s_drain;`); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	for _, name := range []string{"001_embedding_tasks.up.sql", "005_search_generation.up.sql"} {
+		sql, err := fs.ReadFile(migrations.Postgres, name)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if _, err := pool.Exec(ctx, string(sql)); err != nil {
+			t.Fatalf("apply %s: %v", name, err)
+		}
+	}
+
+	vec := []float32{1, 0, 0}
+	rt, err := runtime.New(runtime.Options{
+		Pool:          pool,
+		Schema:        "s_drain",
+		TextEmbedders: []embedder.Embedder{fixedTextEmbedder{vec: vec}},
+		BuildSemanticDocument: func(ctx context.Context, entityType string, language string, entityIDs []string) (map[string]string, error) {
+			out := make(map[string]string, len(entityIDs))
+			for _, id := range entityIDs {
+				out[id] = "document " + id
+			}
+			return out, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("runtime.New: %v", err)
+	}
+
+	client, err := NewClient(ClientConfig{
+		Pool:                      pool,
+		Schema:                    "s_drain",
+		Embedder:                  &recordingEmbedder{vec: vec},
+		DefaultModel:              "m",
+		ResultCache:               NewLRUResultCache(16),
+		GenerationRefreshInterval: time.Nanosecond,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	search := func() []SearchHit {
+		t.Helper()
+		hits, err := client.Search(ctx, "document", SearchOptions{
+			Mode:                SearchModeSemantic,
+			Language:            "en",
+			SemanticEntityTypes: []string{"gallery"},
+			Limit:               10,
+		})
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		return hits
+	}
+
+	if hits := search(); len(hits) != 0 {
+		t.Fatalf("expected no hits before embedding, got %+v", hits)
+	}
+
+	repo := tasks.NewRepo(pool, "s_drain")
+	if err := repo.Enqueue(ctx, "gallery", "1", "m", "en", "test"); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := worker.DrainOnce(ctx, rt, repo, worker.Options{}); err != nil {
+		t.Fatalf("DrainOnce: %v", err)
+	}
+
+	// The stored vector bumps the generation, so the cached empty response
+	// is not served.
+	hits := search()
+	if len(hits) != 1 || hits[0].EntityID != "1" {
+		t.Fatalf("expected the drained entity after DrainOnce, got %+v", hits)
+	}
+}
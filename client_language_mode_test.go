@@ -116,3 +116,47 @@ func TestClientTypeahead_InvalidLanguageMode(t *testing.T) {
 		t.Fatalf("expected invalid TypeaheadOptions.LanguageMode error, got: %v", err)
 	}
 }
+
+func TestResolveLanguageChain(t *testing.T) {
+	t.Parallel()
+
+	chain, err := resolveLanguageChain("PT", LanguageModeFallback, []string{"es", " pt ", "EN", ""}, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(chain.languages, ","); got != "pt,es,en" {
+		t.Fatalf("languages = %q; want pt,es,en", got)
+	}
+	if !chain.tiered || chain.onShortfall {
+		t.Fatalf("expected tiered, non-shortfall chain: %+v", chain)
+	}
+	if len(chain.tiers()) != 1 {
+		t.Fatalf("expected a single tier, got %v", chain.tiers())
+	}
+	if chain.rank("es") != 1 || chain.rank("fr") != 3 {
+		t.Fatalf("unexpected ranks: es=%d fr=%d", chain.rank("es"), chain.rank("fr"))
+	}
+
+	chain, err = resolveLanguageChain("pt", LanguageModeFallbackOnShortfall, []string{"es", "en"}, 5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chain.tiers()) != 3 {
+		t.Fatalf("expected one tier per language, got %v", chain.tiers())
+	}
+	if got := chain.shortfallThreshold(20); got != 5 {
+		t.Fatalf("shortfallThreshold(20) = %d; want 5", got)
+	}
+	if got := chain.shortfallThreshold(3); got != 3 {
+		t.Fatalf("shortfallThreshold(3) = %d; want 3 (capped at depth)", got)
+	}
+
+	if _, err := resolveLanguageChain("pt", LanguageModeFallback, nil, 0); err == nil {
+		t.Fatalf("expected error without fallback languages")
+	}
+
+	chain, err = resolveLanguageChain("es", LanguageModeFallbackEnglish, []string{"fr"}, 0)
+	if err != nil || chain.tiered || strings.Join(chain.languages, ",") != "es,en" {
+		t.Fatalf("fallback_en should keep its fused behavior, got %+v, %v", chain, err)
+	}
+}
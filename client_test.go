@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/open-rails/searchkit/search"
 )
 
 type recordingEmbedder struct {
@@ -86,6 +87,51 @@ func TestClientSearch_SemanticEmbedsNormalizedText(t *testing.T) {
 	}
 }
 
+func TestClientSearch_SemanticEmbedsPositiveTerms(t *testing.T) {
+	t.Parallel()
+
+	emb := &recordingEmbedder{vec: []float32{1, 0, 0}}
+	client, err := NewClient(ClientConfig{
+		Pool:         newTestPool(t),
+		Schema:       "test",
+		Embedder:     emb,
+		DefaultModel: "model",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	_, _ = client.Search(context.Background(), `title:"red cat" OR dog not mouse`, SearchOptions{
+		Mode:                SearchModeSemantic,
+		SemanticEntityTypes: []string{"gallery"},
+	})
+	if emb.text != "red cat dog" {
+		t.Fatalf("expected the positive terms %q to be embedded, got %q", "red cat dog", emb.text)
+	}
+
+	// Terms keep their punctuation.
+	_, _ = client.Search(context.Background(), `what's new in c++ 2.0? not r&b`, SearchOptions{
+		Mode:                SearchModeSemantic,
+		SemanticEntityTypes: []string{"gallery"},
+	})
+	if want := "what's new in c++ 2.0?"; emb.text != want {
+		t.Fatalf("expected the positive terms %q to be embedded, got %q", want, emb.text)
+	}
+
+	// Only exclusions: nothing can match, so nothing is embedded.
+	emb.called = false
+	res, err := client.Search(context.Background(), "not mouse", SearchOptions{
+		Mode:                SearchModeSemantic,
+		SemanticEntityTypes: []string{"gallery"},
+	})
+	if err != nil || len(res) != 0 {
+		t.Fatalf("expected no hits, got %+v (%v)", res, err)
+	}
+	if emb.called {
+		t.Fatalf("expected the embedder not to be called without positive terms")
+	}
+}
+
 func TestClientSearch_LexicalDoesNotCallEmbedder(t *testing.T) {
 	t.Parallel()
 
@@ -108,3 +154,28 @@ func TestClientSearch_LexicalDoesNotCallEmbedder(t *testing.T) {
 		t.Fatalf("expected embedder not to be called in lexical mode")
 	}
 }
+
+func TestClientSearch_InvalidFilter(t *testing.T) {
+	t.Parallel()
+
+	emb := &recordingEmbedder{vec: []float32{1, 0, 0}}
+	client, err := NewClient(ClientConfig{
+		Pool:         newTestPool(t),
+		Schema:       "test",
+		Embedder:     emb,
+		DefaultModel: "model",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	_, err = client.Search(context.Background(), "two factor", SearchOptions{
+		Filter: search.Eq("sd.entity_id; --", "1"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "invalid Filter") {
+		t.Fatalf("expected invalid filter error, got: %v", err)
+	}
+	if emb.called {
+		t.Fatalf("expected the filter to be rejected before retrieval")
+	}
+}
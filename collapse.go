@@ -0,0 +1,122 @@
+package searchkit
+
+import "sort"
+
+type entityKey struct {
+	entityType string
+	entityID   string
+}
+
+// collapseSearchHits merges hits for the same (entity_type, entity_id) found in
+// several languages into one hit.
+//
+// The merged Score is the sum of the variants' RRF scores (i.e. all of their
+// list contributions), and the representative Language is the variant earliest
+// in the language chain (the requested language when it matched), then by
+// name for languages outside the chain. The result is not re-sorted; see
+// sortSearchHits.
+func collapseSearchHits(hits []SearchHit, chain languageChain) []SearchHit {
+	index := make(map[entityKey]int, len(hits))
+	out := make([]SearchHit, 0, len(hits))
+	for _, h := range hits {
+		k := entityKey{h.EntityType, h.EntityID}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(out)
+			h.LanguageVariants = []string{h.Language}
+			if h.Explain != nil {
+				e := *h.Explain
+				e.Lists = append([]ListContribution(nil), e.Lists...)
+				h.Explain = &e
+			}
+			out = append(out, h)
+			continue
+		}
+		m := &out[i]
+		m.Score += h.Score
+		m.LanguageVariants = append(m.LanguageVariants, h.Language)
+		if h.Explain != nil {
+			if m.Explain == nil {
+				m.Explain = &HitExplanation{Fusion: h.Explain.Fusion, RRFK: h.Explain.RRFK}
+			}
+			m.Explain.Lists = append(m.Explain.Lists, h.Explain.Lists...)
+		}
+		if languageBefore(h.Language, m.Language, chain) {
+			m.Language = h.Language
+		}
+	}
+	for i := range out {
+		sortLanguages(out[i].LanguageVariants, chain)
+	}
+	return out
+}
+
+// collapseTypeaheadHits is collapseSearchHits for typeahead hits. Typeahead
+// scores are similarities rather than additive RRF scores, so the merged Score
+// is the best variant's.
+func collapseTypeaheadHits(hits []TypeaheadHit, chain languageChain) []TypeaheadHit {
+	index := make(map[entityKey]int, len(hits))
+	out := make([]TypeaheadHit, 0, len(hits))
+	for _, h := range hits {
+		k := entityKey{h.EntityType, h.EntityID}
+		i, ok := index[k]
+		if !ok {
+			index[k] = len(out)
+			h.LanguageVariants = []string{h.Language}
+			out = append(out, h)
+			continue
+		}
+		m := &out[i]
+		if h.Score > m.Score {
+			m.Score = h.Score
+		}
+		m.LanguageVariants = append(m.LanguageVariants, h.Language)
+		if languageBefore(h.Language, m.Language, chain) {
+			m.Language = h.Language
+		}
+	}
+	for i := range out {
+		sortLanguages(out[i].LanguageVariants, chain)
+	}
+	return out
+}
+
+// sortLanguages orders languages by chain position, then name.
+func sortLanguages(languages []string, chain languageChain) {
+	sort.Slice(languages, func(i, j int) bool {
+		return languageBefore(languages[i], languages[j], chain)
+	})
+}
+
+// languageBefore reports whether a precedes b: by chain position, then name.
+// The collapsed representative is the first variant in this order, so it does
+// not depend on the order variants were found in.
+func languageBefore(a, b string, chain languageChain) bool {
+	if ra, rb := chain.rank(a), chain.rank(b); ra != rb {
+		return ra < rb
+	}
+	return a < b
+}
+
+// sortSearchHits orders hits by language tier (tiered chains only), then score,
+// then key, matching FuseRRF's deterministic tie-break.
+func sortSearchHits(hits []SearchHit, chain languageChain) {
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if chain.tiered {
+			if ra, rb := chain.rank(a.Language), chain.rank(b.Language); ra != rb {
+				return ra < rb
+			}
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.EntityType != b.EntityType {
+			return a.EntityType < b.EntityType
+		}
+		if a.EntityID != b.EntityID {
+			return a.EntityID < b.EntityID
+		}
+		return a.Language < b.Language
+	})
+}
@@ -0,0 +1,81 @@
+package searchkit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseSearchHits(t *testing.T) {
+	t.Parallel()
+
+	chain, err := resolveLanguageChain("ja", LanguageModeFallbackEnglish, nil, 0)
+	if err != nil {
+		t.Fatalf("resolveLanguageChain: %v", err)
+	}
+	hits := []SearchHit{
+		{EntityType: "gallery", EntityID: "1", Language: "en", Score: 0.03,
+			Explain: &HitExplanation{RRFK: 60, Lists: []ListContribution{{Backend: BackendFTS, Language: "en", Contribution: 0.03}}}},
+		{EntityType: "gallery", EntityID: "2", Language: "ja", Score: 0.025},
+		{EntityType: "gallery", EntityID: "1", Language: "ja", Score: 0.01,
+			Explain: &HitExplanation{RRFK: 60, Lists: []ListContribution{{Backend: BackendPGroonga, Language: "ja", Contribution: 0.01}}}},
+	}
+
+	got := collapseSearchHits(hits, chain)
+	sortSearchHits(got, chain)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 collapsed hits, got %+v", got)
+	}
+	first := got[0]
+	if first.EntityID != "1" || first.Language != "ja" {
+		t.Fatalf("expected gallery 1 represented by requested language ja, got %+v", first)
+	}
+	if first.Score != 0.04 {
+		t.Fatalf("expected summed score 0.04, got %v", first.Score)
+	}
+	if !reflect.DeepEqual(first.LanguageVariants, []string{"ja", "en"}) {
+		t.Fatalf("LanguageVariants = %v; want [ja en]", first.LanguageVariants)
+	}
+	if first.Explain == nil || len(first.Explain.Lists) != 2 {
+		t.Fatalf("expected merged explanation with 2 lists, got %+v", first.Explain)
+	}
+	if len(hits[0].Explain.Lists) != 1 {
+		t.Fatalf("input explanation was mutated")
+	}
+}
+
+func TestCollapseTypeaheadHits(t *testing.T) {
+	t.Parallel()
+
+	chain, err := resolveLanguageChain("pt", LanguageModeFallback, []string{"es", "en"}, 0)
+	if err != nil {
+		t.Fatalf("resolveLanguageChain: %v", err)
+	}
+	got := collapseTypeaheadHits([]TypeaheadHit{
+		{EntityType: "tag", EntityID: "7", Language: "en", Score: 0.9},
+		{EntityType: "tag", EntityID: "7", Language: "es", Score: 0.5},
+	}, chain)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 hit, got %+v", got)
+	}
+	if got[0].Language != "es" || got[0].Score != 0.9 {
+		t.Fatalf("expected es representative with best score 0.9, got %+v", got[0])
+	}
+	if !reflect.DeepEqual(got[0].LanguageVariants, []string{"es", "en"}) {
+		t.Fatalf("LanguageVariants = %v; want [es en]", got[0].LanguageVariants)
+	}
+}
+
+func TestCollapseSearchHits_RepresentativeIndependentOfOrder(t *testing.T) {
+	t.Parallel()
+
+	// Neither variant is in the chain, so their ranks tie.
+	chain := languageChain{languages: []string{"ja"}}
+	a := SearchHit{EntityType: "gallery", EntityID: "1", Language: "fr", Score: 0.02}
+	b := SearchHit{EntityType: "gallery", EntityID: "1", Language: "de", Score: 0.03}
+	for _, hits := range [][]SearchHit{{a, b}, {b, a}} {
+		got := collapseSearchHits(hits, chain)
+		if len(got) != 1 || got[0].Language != "de" || !reflect.DeepEqual(got[0].LanguageVariants, []string{"de", "fr"}) {
+			t.Fatalf("collapse(%v) = %+v; want representative de", hits, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package searchkit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+	"github.com/open-rails/searchkit/search"
+)
+
+// TermCompletionOptions configures Client.CompleteTerms.
+type TermCompletionOptions struct {
+	// Language selects the vocabulary. Defaults to ClientConfig.DefaultLanguage.
+	Language string
+	// Limit is the maximum number of completions (default 10).
+	Limit int
+	// MinPrefixLength is the number of characters the word being typed needs
+	// before it is completed (default 2; 1 for CJK-script prefixes).
+	MinPrefixLength int
+}
+
+// TermCompletion is a completion of the word being typed.
+type TermCompletion struct {
+	Term string
+	// DocCount is the number of documents containing Term.
+	DocCount int64
+	// Query is the (normalized, lowercased) input with its last word replaced
+	// by Term.
+	Query string
+}
+
+const defaultTermCompletionLimit = 10
+
+// CompleteTerms completes the last word of userText from the indexed corpus
+// (`search_vocabulary`), most frequent terms first.
+//
+// It complements Typeahead, which suggests entities: "naruto tentac" completes
+// to "naruto tentacle". Input ending in whitespace has no word to complete and
+// returns an empty slice. Native-script CJK prefixes in ja/zh/ko use PGroonga
+// prefix search (no completions when the extension is not installed);
+// everything else is an index-backed LIKE prefix lookup.
+func (c *Client) CompleteTerms(ctx context.Context, userText string, opts TermCompletionOptions) ([]TermCompletion, error) {
+	if r, _ := utf8.DecodeLastRuneInString(userText); userText == "" || unicode.IsSpace(r) {
+		return []TermCompletion{}, nil
+	}
+	q := strings.ToLower(querynorm.QueryForEmbedding(userText))
+	spans := wordSpans(q)
+	if len(spans) == 0 || spans[len(spans)-1].End != len(q) {
+		return []TermCompletion{}, nil
+	}
+	last := spans[len(spans)-1]
+	prefix := q[last.Start:last.End]
+
+	language := strings.TrimSpace(opts.Language)
+	if language == "" {
+		language = c.defaultLanguage
+	}
+	if language == "" {
+		return nil, fmt.Errorf("Language is required")
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultTermCompletionLimit
+	}
+
+	pgroonga := isCJKLanguage(language) && containsCJKScript(prefix)
+	minLen := opts.MinPrefixLength
+	if minLen <= 0 {
+		minLen = 2
+		if containsCJKScript(prefix) {
+			minLen = 1
+		}
+	}
+	if utf8.RuneCountInString(prefix) < minLen {
+		return []TermCompletion{}, nil
+	}
+
+	terms, err := search.CompleteTerms(ctx, c.pool, prefix, search.CompleteTermsOptions{
+		Schema:   c.schema,
+		Language: language,
+		Limit:    limit,
+		PGroonga: pgroonga,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]TermCompletion, 0, len(terms))
+	for _, t := range terms {
+		out = append(out, TermCompletion{
+			Term:     t.Term,
+			DocCount: t.DocCount,
+			Query:    q[:last.Start] + t.Term,
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,30 @@
+package searchkit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCompleteTerms_NothingToCompleteSkipsDB(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{
+		Pool:   newTestPool(t),
+		Schema: "test",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	// The test pool cannot connect, so any of these reaching the database
+	// would fail.
+	for _, in := range []string{"", "naruto ", "naruto t", "!!"} {
+		got, err := client.CompleteTerms(context.Background(), in, TermCompletionOptions{Language: "en"})
+		if err != nil {
+			t.Fatalf("CompleteTerms(%q): %v", in, err)
+		}
+		if len(got) != 0 {
+			t.Fatalf("CompleteTerms(%q) = %+v; want none", in, got)
+		}
+	}
+}
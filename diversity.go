@@ -0,0 +1,122 @@
+package searchkit
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/open-rails/searchkit/search"
+)
+
+// maxDiversityCandidates bounds the hits SearchOptions.Diversity reorders;
+// hits below it keep their order.
+const maxDiversityCandidates = 500
+
+func validateDiversity(lambda float32) error {
+	if lambda < 0 || lambda > 1 {
+		return fmt.Errorf("invalid SearchOptions.Diversity: must be in [0..1]")
+	}
+	return nil
+}
+
+// diversifyHits reorders hits with MMR (search.MMRReRank) using the cosine
+// similarity of their stored model vectors. Relevance is the hit score,
+// min-max normalized over the candidates so it is on the same scale as
+// cosine similarity. Hits without a vector are never redundant.
+//
+// With a tiered language chain, each language's hits are diversified among
+// themselves, so the tiers keep their order.
+func (c *Client) diversifyHits(ctx context.Context, hits []SearchHit, chain languageChain, model string, lambda float32) ([]SearchHit, error) {
+	if lambda <= 0 || lambda >= 1 || len(hits) < 2 {
+		return hits, nil
+	}
+	n := min(len(hits), maxDiversityCandidates)
+	keys := make([]search.DocKey, n)
+	for i, h := range hits[:n] {
+		keys[i] = search.DocKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language}
+	}
+	vectors, err := search.StoredVectors(ctx, c.pool, c.schema, model, keys)
+	if err != nil {
+		return nil, fmt.Errorf("load vectors: %w", err)
+	}
+	diversifyTiers(hits[:n], vectors, chain, lambda)
+	return hits, nil
+}
+
+// diversifyTiers reorders hits in place with MMR over vectors, within each
+// language tier when chain is tiered.
+func diversifyTiers(hits []SearchHit, vectors map[search.DocKey][]float32, chain languageChain, lambda float32) {
+	unit := make(map[search.DocKey][]float32, len(vectors))
+	for k, v := range vectors {
+		if u := unitVector(v); u != nil {
+			unit[k] = u
+		}
+	}
+	if !chain.tiered {
+		mmrHits(hits, unit, lambda)
+		return
+	}
+	// Hits are sorted by tier first.
+	for start := 0; start < len(hits); {
+		end := start + 1
+		for end < len(hits) && chain.rank(hits[end].Language) == chain.rank(hits[start].Language) {
+			end++
+		}
+		mmrHits(hits[start:end], unit, lambda)
+		start = end
+	}
+}
+
+// unitVector returns v scaled to length 1, or nil for a zero vector.
+func unitVector(v []float32) []float32 {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return nil
+	}
+	scale := 1 / math.Sqrt(norm)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) * scale)
+	}
+	return out
+}
+
+// mmrHits reorders hits in place with MMR over unit vectors (see
+// unitVector), whose dot product is their cosine similarity.
+func mmrHits(hits []SearchHit, vectors map[search.DocKey][]float32, lambda float32) {
+	if len(hits) < 2 {
+		return
+	}
+	scores := make([]float32, len(hits))
+	for i, h := range hits {
+		scores[i] = h.Score
+	}
+	relevance := search.NormalizeScores(scores, search.FusionMinMax)
+
+	byKey := make(map[search.DocKey]SearchHit, len(hits))
+	candidates := make([]search.Hit, len(hits))
+	for i, h := range hits {
+		byKey[search.DocKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language}] = h
+		candidates[i] = search.Hit{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language, Similarity: relevance[i]}
+	}
+	docKey := func(h search.Hit) search.DocKey {
+		return search.DocKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language}
+	}
+	order := search.MMRReRank(candidates, len(candidates), lambda, func(a, b search.Hit) float32 {
+		va, vb := vectors[docKey(a)], vectors[docKey(b)]
+		if len(va) == 0 || len(va) != len(vb) {
+			return 0
+		}
+		var dot float32
+		for i := range va {
+			dot += va[i] * vb[i]
+		}
+		return dot
+	})
+	for i, cand := range order {
+		hits[i] = byKey[docKey(cand)]
+	}
+}
@@ -0,0 +1,97 @@
+package searchkit
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-rails/searchkit/search"
+)
+
+func TestMMRHits(t *testing.T) {
+	t.Parallel()
+
+	key := func(id string) search.DocKey {
+		return search.DocKey{EntityType: "gallery", EntityID: id, Language: "en"}
+	}
+	hits := []SearchHit{
+		{EntityType: "gallery", EntityID: "a", Language: "en", Score: 0.033},
+		{EntityType: "gallery", EntityID: "a2", Language: "en", Score: 0.032},
+		{EntityType: "gallery", EntityID: "b", Language: "en", Score: 0.030},
+		{EntityType: "gallery", EntityID: "novec", Language: "en", Score: 0.020},
+	}
+	vectors := map[search.DocKey][]float32{
+		key("a"):  {1, 0},
+		key("a2"): {0.99, 0.05}, // near-duplicate of a
+		key("b"):  {0, 1},
+	}
+	diversifyTiers(hits, vectors, languageChain{}, 0.5)
+
+	var got []string
+	for _, h := range hits {
+		got = append(got, h.EntityID)
+	}
+	if want := []string{"a", "b", "novec", "a2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order = %v; want %v", got, want)
+	}
+	if hits[3].Score != 0.032 {
+		t.Fatalf("diversification changed scores: %+v", hits)
+	}
+}
+
+func TestDiversifyTiers(t *testing.T) {
+	t.Parallel()
+
+	chain, err := resolveLanguageChain("en", LanguageModeFallback, []string{"ja"}, 0)
+	if err != nil {
+		t.Fatalf("resolveLanguageChain: %v", err)
+	}
+	hits := []SearchHit{
+		{EntityType: "gallery", EntityID: "a", Language: "en", Score: 0.033},
+		{EntityType: "gallery", EntityID: "a2", Language: "en", Score: 0.032},
+		{EntityType: "gallery", EntityID: "b", Language: "ja", Score: 0.030},
+		{EntityType: "gallery", EntityID: "c", Language: "ja", Score: 0.020},
+	}
+	vectors := map[search.DocKey][]float32{
+		{EntityType: "gallery", EntityID: "a", Language: "en"}:  {2, 0},
+		{EntityType: "gallery", EntityID: "a2", Language: "en"}: {1.98, 0.1}, // near-duplicate of a
+		{EntityType: "gallery", EntityID: "b", Language: "ja"}:  {0, 3},
+		{EntityType: "gallery", EntityID: "c", Language: "ja"}:  {1, 0},
+	}
+	diversifyTiers(hits, vectors, chain, 0.5)
+
+	// The duplicate stays in the en tier: MMR never moves a ja hit above it.
+	var got []string
+	for _, h := range hits {
+		got = append(got, h.EntityID+"/"+h.Language)
+	}
+	if want := []string{"a/en", "a2/en", "b/ja", "c/ja"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("order = %v; want %v", got, want)
+	}
+}
+
+func TestClientSearch_InvalidDiversity(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{Pool: newTestPool(t), Schema: "test"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	for _, tc := range []struct {
+		diversity float32
+		want      string
+	}{
+		{diversity: 1.5, want: "invalid SearchOptions.Diversity"},
+		{diversity: 0.7, want: "Model is required for SearchOptions.Diversity"},
+	} {
+		_, err := client.Search(context.Background(), "naruto", SearchOptions{
+			Mode:        SearchModeLexical,
+			EntityTypes: []string{"gallery"},
+			Diversity:   tc.diversity,
+		})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("Diversity %v: err = %v; want %q", tc.diversity, err, tc.want)
+		}
+	}
+}
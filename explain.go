@@ -0,0 +1,108 @@
+package searchkit
+
+import "github.com/open-rails/searchkit/search"
+
+// HitExplanation breaks a fused SearchHit score down per ranked list.
+//
+// Score = Σ Lists[i].Contribution, times Boost.Multiplier when the hit was
+// boosted (SearchOptions.Boosts). Reranked hits (SearchOptions.Rerank) are
+// scored by the reranker instead (see Rerank).
+type HitExplanation struct {
+	// Fusion is the fusion method (SearchOptions.Fusion).
+	Fusion search.FusionMethod
+	// RRFK is the RRF stabilizer constant used for fusion.
+	RRFK int
+	// Lists holds one entry per ranked list the hit appeared in, in the order
+	// the lists were fused.
+	Lists []ListContribution
+	// Boost is set when SearchOptions.Boosts applied signals to the hit.
+	Boost *BoostExplanation
+	// Rerank is set when SearchOptions.Rerank scored the hit.
+	Rerank *RerankExplanation
+}
+
+// ListContribution describes a hit's position in one backend's ranked list.
+type ListContribution struct {
+	Backend  Backend
+	Language string
+	// Model is the embedding model (semantic lists only).
+	Model string
+
+	// Rank is the 1-based position in the backend's list.
+	Rank int
+	// RawScore is the backend's own score:
+	//   - fts: ts_rank_cd
+	//   - trigram: pg_trgm similarity
+	//   - pgroonga: pgroonga_score (unnormalized)
+	//   - semantic: cosine similarity
+	RawScore float32
+	// NormalizedScore is RawScore normalized within the list, over the same
+	// window as fusion (score-based fusion only; see
+	// search.NormalizeScoresWindow).
+	NormalizedScore float32
+
+	// Weight is the fusion weight applied to the list.
+	Weight float32
+	// Contribution is Weight / (RRFK + Rank) for RRF, and the weighted
+	// NormalizedScore for score-based fusion (divided by the sum of list
+	// weights for the convex combinations).
+	Contribution float32
+}
+
+// explainLists builds per-hit explanations for lists fused with search.Fuse
+// using fusion.
+func explainLists(lists []rankedList, fusion search.FusionOptions) (map[search.RRFKey]*HitExplanation, error) {
+	scored := scoredLists(lists)
+	contribs, err := search.FusionContributions(scored, fusion)
+	if err != nil {
+		return nil, err
+	}
+	method := fusion.Method
+	if method == "" {
+		method = search.FusionRRF
+	}
+	k := fusion.K
+	if k <= 0 {
+		k = 60
+	}
+	out := make(map[search.RRFKey]*HitExplanation)
+	for li, l := range lists {
+		w := float32(1.0)
+		if li < len(fusion.Weights) && fusion.Weights[li] > 0 {
+			w = fusion.Weights[li]
+		}
+		var norm []float32
+		if method != search.FusionRRF {
+			norm = search.NormalizeScoresWindow(scoresOfList(l), method, fusion.Window)
+		}
+		for i, h := range l.hits {
+			e := out[h.key]
+			if e == nil {
+				e = &HitExplanation{Fusion: method, RRFK: k}
+				out[h.key] = e
+			}
+			lc := ListContribution{
+				Backend:      l.backend,
+				Language:     l.language,
+				Model:        l.model,
+				Rank:         i + 1,
+				RawScore:     h.rawScore,
+				Weight:       w,
+				Contribution: contribs[li][i],
+			}
+			if norm != nil {
+				lc.NormalizedScore = norm[i]
+			}
+			e.Lists = append(e.Lists, lc)
+		}
+	}
+	return out, nil
+}
+
+func scoresOfList(l rankedList) []float32 {
+	out := make([]float32, len(l.hits))
+	for i, h := range l.hits {
+		out[i] = h.rawScore
+	}
+	return out
+}
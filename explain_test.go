@@ -0,0 +1,127 @@
+package searchkit
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/open-rails/searchkit/search"
+)
+
+func TestExplainLists_MatchesFusedScore(t *testing.T) {
+	t.Parallel()
+
+	fts := rankedList{backend: BackendFTS, language: "en"}
+	fts.add("gallery", "1", "en", 0.4)
+	fts.add("gallery", "2", "en", 0.1)
+	sem := rankedList{backend: BackendSemantic, language: "en", model: "m"}
+	sem.add("gallery", "2", "en", 0.9)
+
+	lists := []rankedList{fts, sem}
+	fused := search.FuseRRF([][]search.RRFKey{fts.keys(), sem.keys()}, search.RRFOptions{K: 60})
+	expl, err := explainLists(lists, search.FusionOptions{K: 60})
+	if err != nil {
+		t.Fatalf("explainLists: %v", err)
+	}
+
+	for _, h := range fused {
+		e := expl[h.RRFKey]
+		if e == nil {
+			t.Fatalf("missing explanation for %+v", h.RRFKey)
+		}
+		var sum float32
+		for _, lc := range e.Lists {
+			sum += lc.Contribution
+		}
+		if math.Abs(float64(sum-h.Score)) > 1e-6 {
+			t.Fatalf("contributions sum to %v; fused score %v", sum, h.Score)
+		}
+	}
+
+	e := expl[search.RRFKey{EntityType: "gallery", EntityID: "2", Language: "en"}]
+	if len(e.Lists) != 2 {
+		t.Fatalf("expected 2 list contributions, got %+v", e.Lists)
+	}
+	if e.Lists[0].Backend != BackendFTS || e.Lists[0].Rank != 2 || e.Lists[0].RawScore != 0.1 {
+		t.Fatalf("unexpected fts contribution %+v", e.Lists[0])
+	}
+	if e.Lists[1].Backend != BackendSemantic || e.Lists[1].Rank != 1 || e.Lists[1].Model != "m" {
+		t.Fatalf("unexpected semantic contribution %+v", e.Lists[1])
+	}
+}
+
+func TestExplainLists_ScoreFusion(t *testing.T) {
+	t.Parallel()
+
+	fts := rankedList{backend: BackendFTS, language: "en"}
+	fts.add("gallery", "1", "en", 0.4)
+	fts.add("gallery", "2", "en", 0.1)
+	sem := rankedList{backend: BackendSemantic, language: "en", model: "m"}
+	sem.add("gallery", "2", "en", 0.9)
+	sem.add("gallery", "3", "en", 0.5)
+	lists := []rankedList{fts, sem}
+
+	for _, m := range []search.FusionMethod{search.FusionMinMax, search.FusionZScore, search.FusionDBSF} {
+		fusion := search.FusionOptions{Method: m, Weights: []float32{2, 1}}
+		fused, err := search.Fuse(scoredLists(lists), fusion)
+		if err != nil {
+			t.Fatalf("Fuse: %v", err)
+		}
+		expl, err := explainLists(lists, fusion)
+		if err != nil {
+			t.Fatalf("explainLists: %v", err)
+		}
+		for _, h := range fused {
+			e := expl[h.RRFKey]
+			if e == nil || e.Fusion != m {
+				t.Fatalf("unexpected explanation %+v for %+v", e, h.RRFKey)
+			}
+			var sum float32
+			for _, lc := range e.Lists {
+				sum += lc.Contribution
+			}
+			if math.Abs(float64(sum-h.Score)) > 1e-6 {
+				t.Fatalf("%s: contributions sum to %v; fused score %v", m, sum, h.Score)
+			}
+		}
+		if top := expl[search.RRFKey{EntityType: "gallery", EntityID: "1", Language: "en"}].Lists[0]; top.NormalizedScore <= 0 || top.Weight != 2 {
+			t.Fatalf("%s: unexpected fts contribution %+v", m, top)
+		}
+	}
+}
+
+func TestExplainLists_NormalizesOverFusionWindow(t *testing.T) {
+	t.Parallel()
+
+	// Deeper than the window: scores below it are clamped by fusion, and the
+	// explanation must report the same normalized values.
+	fts := rankedList{backend: BackendFTS, language: "en"}
+	n := scoreFusionWindow + 20
+	for i := 0; i < n; i++ {
+		fts.add("gallery", strconv.Itoa(i), "en", float32(n-i))
+	}
+	lists := []rankedList{fts}
+
+	for _, m := range []search.FusionMethod{search.FusionMinMax, search.FusionZScore, search.FusionDBSF} {
+		fusion := search.FusionOptions{Method: m, Window: scoreFusionWindow}
+		fused, err := search.Fuse(scoredLists(lists), fusion)
+		if err != nil {
+			t.Fatalf("Fuse: %v", err)
+		}
+		expl, err := explainLists(lists, fusion)
+		if err != nil {
+			t.Fatalf("explainLists: %v", err)
+		}
+		for _, h := range fused {
+			// A single list of weight 1 contributes its normalized score.
+			lc := expl[h.RRFKey].Lists[0]
+			if math.Abs(float64(lc.NormalizedScore-h.Score)) > 1e-6 {
+				t.Fatalf("%s: %+v normalized to %v; fused score %v", m, h.RRFKey, lc.NormalizedScore, h.Score)
+			}
+		}
+		last := expl[search.RRFKey{EntityType: "gallery", EntityID: strconv.Itoa(n - 1), Language: "en"}].Lists[0]
+		if m == search.FusionMinMax && last.NormalizedScore != 0 {
+			t.Fatalf("%s: expected a below-window score to be clamped, got %+v", m, last)
+		}
+	}
+}
@@ -0,0 +1,418 @@
+package searchkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+	"github.com/open-rails/searchkit/search"
+)
+
+// FacetOptions configures Client.Facets. Fields mean the same as in
+// SearchOptions, so tabs built from facets agree with Search results.
+type FacetOptions struct {
+	Language string
+	// Defaults to LanguageModeExact when omitted. Facets always cover every
+	// language of the chain (LanguageModeFallbackOnShortfall counts like
+	// LanguageModeFallback).
+	LanguageMode      LanguageMode
+	FallbackLanguages []string
+	// Mode selects which sides are counted (default SearchModeDual).
+	Mode SearchMode
+
+	EntityTypes         []string
+	LexicalEntityTypes  []string
+	SemanticEntityTypes []string
+
+	// Semantic model override (defaults to client).
+	Model string
+
+	// SemanticMinSimilarity is the cosine similarity a vector needs to be
+	// counted (default 0.5).
+	SemanticMinSimilarity float32
+	// SemanticMaxCandidates bounds how many nearest neighbors are inspected per
+	// language (default 1000). See FacetResult.SemanticCapped.
+	SemanticMaxCandidates int
+
+	FilterSQL  string
+	FilterArgs map[string]any
+	// Filter behaves as in SearchOptions.
+	Filter search.Filter
+
+	// BackendTimeout and AllowPartialResults behave as in SearchOptions.
+	BackendTimeout      time.Duration
+	AllowPartialResults *bool
+}
+
+// FacetCount holds match counts for one (entity_type, language) pair.
+type FacetCount struct {
+	EntityType string
+	Language   string
+	// Count is the exact number of entities matched by the lexical backends
+	// routed for the language (a document matched by several backends counts
+	// once).
+	Count int64
+	// SemanticCount is the number of entities among the nearest neighbors whose
+	// similarity is at least the semantic threshold. It is an estimate; see
+	// FacetResult.SemanticCapped.
+	SemanticCount int64
+}
+
+// FacetResult is the response of Client.Facets (and SearchResult.Facets).
+type FacetResult struct {
+	// Counts is ordered by language (requested language first), then entity
+	// type.
+	Counts []FacetCount
+	// SemanticCapped reports that, for at least one language, every inspected
+	// neighbor passed the threshold: SemanticCount values are then lower bounds.
+	SemanticCapped bool
+	// Failures lists backends that failed or timed out (partial results only).
+	Failures []BackendFailure
+}
+
+// Facets returns per-entity-type and per-language match counts for a query,
+// using the same rewrite rules, normalization, language resolution, routing,
+// synonym expansion and FilterSQL as Search. A query redirected by a rule has
+// no counts.
+func (c *Client) Facets(ctx context.Context, userText string, opts FacetOptions) (*FacetResult, error) {
+	filterSQL, filterArgs, err := search.CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Filter: %w", err)
+	}
+	opts.FilterSQL, opts.FilterArgs, opts.Filter = filterSQL, filterArgs, nil
+
+	language := strings.TrimSpace(opts.Language)
+	if language == "" {
+		language = c.defaultLanguage
+	}
+	rewrite := c.rewriteQuery(ctx, language, userText)
+	if rewrite.redirect != "" {
+		return &FacetResult{Counts: []FacetCount{}}, nil
+	}
+	if rewrite.query != "" {
+		userText = rewrite.query
+	}
+
+	q := querynorm.QueryForEmbedding(userText)
+	positiveText := querynorm.ParseQuery(q).PositiveText()
+	if q == "" || !hasAnyLetterOrNumber(positiveText) {
+		return &FacetResult{Counts: []FacetCount{}}, nil
+	}
+	chain, err := resolveLanguageChain(language, opts.LanguageMode, opts.FallbackLanguages, 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FacetOptions.LanguageMode %q: %w", opts.LanguageMode, err)
+	}
+	languages := chain.languages
+	mode := opts.Mode
+	if mode == "" {
+		mode = SearchModeDual
+	}
+	switch mode {
+	case SearchModeLexical, SearchModeSemantic, SearchModeDual:
+	default:
+		return nil, fmt.Errorf("invalid FacetOptions.Mode %q", mode)
+	}
+
+	lexTypes, semTypes := resolveEntityTypes(opts.EntityTypes, opts.LexicalEntityTypes, opts.SemanticEntityTypes)
+	if mode != SearchModeSemantic && len(lexTypes) == 0 {
+		return nil, fmt.Errorf("LexicalEntityTypes is required for lexical/dual facets")
+	}
+	if mode != SearchModeLexical && len(semTypes) == 0 {
+		return nil, fmt.Errorf("SemanticEntityTypes is required for semantic/dual facets")
+	}
+
+	p := facetPlan{
+		query:         q,
+		languages:     languages,
+		minSimilarity: opts.SemanticMinSimilarity,
+		maxCandidates: opts.SemanticMaxCandidates,
+		filterSQL:     opts.FilterSQL,
+		filterArgs:    opts.FilterArgs,
+	}
+	if mode != SearchModeSemantic {
+		p.lexTypes = lexTypes
+	}
+	if mode != SearchModeLexical {
+		model := strings.TrimSpace(opts.Model)
+		if model == "" {
+			model = c.defaultModel
+		}
+		if c.embedder == nil {
+			return nil, fmt.Errorf("Embedder is required for semantic facets")
+		}
+		if model == "" {
+			return nil, fmt.Errorf("Model is required for semantic facets")
+		}
+		p.semTypes = semTypes
+		p.model = model
+		// As in Search, only the positive terms are embedded.
+		p.embedding = &queryEmbedding{embedder: c.embedder, model: model, query: positiveText}
+	}
+
+	timeout := opts.BackendTimeout
+	if timeout <= 0 {
+		timeout = c.defaultBackendTimeout
+	}
+	allowPartial := c.defaultAllowPartial
+	if opts.AllowPartialResults != nil {
+		allowPartial = *opts.AllowPartialResults
+	}
+	return c.runFacets(ctx, p, timeout, allowPartial)
+}
+
+// facetPlan holds the resolved parameters of a facet computation. Empty
+// lexTypes/semTypes skip that side.
+type facetPlan struct {
+	query     string
+	languages []string
+
+	lexTypes []string
+
+	semTypes      []string
+	model         string
+	embedding     *queryEmbedding
+	minSimilarity float32
+	maxCandidates int
+
+	filterSQL  string
+	filterArgs map[string]any
+}
+
+func (c *Client) runFacets(ctx context.Context, p facetPlan, timeout time.Duration, allowPartial bool) (*FacetResult, error) {
+	minSim := p.minSimilarity
+	if minSim <= 0 {
+		minSim = 0.5
+	}
+	maxCandidates := p.maxCandidates
+	if maxCandidates <= 0 {
+		maxCandidates = 1000
+	}
+
+	type facetPart struct {
+		semantic bool
+		counts   []search.FacetCount
+		capped   bool
+		failure  *BackendFailure
+	}
+	var (
+		mu    sync.Mutex
+		parts []facetPart
+		wg    sync.WaitGroup
+	)
+	record := func(part facetPart) {
+		mu.Lock()
+		parts = append(parts, part)
+		mu.Unlock()
+	}
+
+	if len(p.lexTypes) > 0 {
+		for _, lang := range p.languages {
+			lang := lang
+			route := lexicalRouting(lang, p.query, false)
+			// Failures are attributed to the language's primary lexical backend.
+			backend := BackendFTS
+			if !route.useFTS {
+				backend = BackendTrigram
+				if route.usePGroonga {
+					backend = BackendPGroonga
+				}
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var part facetPart
+				part.failure = runBackendCall(ctx, backend, lang, timeout, func(ctx context.Context) error {
+					var err error
+					part.counts, err = search.LexicalFacets(ctx, c.pool, p.query, search.LexicalFacetOptions{
+						Schema:      c.schema,
+						Language:    lang,
+						EntityTypes: p.lexTypes,
+						FTS:         route.useFTS,
+						Trigram:     route.useTrigram,
+						PGroonga:    route.usePGroonga,
+						FilterSQL:   p.filterSQL,
+						FilterArgs:  p.filterArgs,
+					})
+					return err
+				})
+				record(part)
+			}()
+		}
+	}
+
+	if len(p.semTypes) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var vec []float32
+			failure := runBackendCall(ctx, BackendSemantic, "", timeout, func(ctx context.Context) error {
+				var err error
+				vec, err = p.embedding.get(ctx)
+				return err
+			})
+			if failure != nil {
+				record(facetPart{semantic: true, failure: failure})
+				return
+			}
+			if len(vec) == 0 {
+				return
+			}
+			var inner sync.WaitGroup
+			for _, lang := range p.languages {
+				lang := lang
+				inner.Add(1)
+				go func() {
+					defer inner.Done()
+					part := facetPart{semantic: true}
+					part.failure = runBackendCall(ctx, BackendSemantic, lang, timeout, func(ctx context.Context) error {
+						var err error
+						part.counts, part.capped, err = search.SemanticFacets(ctx, c.pool, search.Query{
+							Schema:     c.schema,
+							Model:      p.model,
+							Language:   lang,
+							QueryVec:   vec,
+							Limit:      maxCandidates,
+							Dimensions: len(vec),
+							Options: search.Options{
+								EntityTypes: p.semTypes,
+								FilterSQL:   p.filterSQL,
+								FilterArgs:  p.filterArgs,
+								Constraints: p.query,
+							},
+						}, minSim)
+						return err
+					})
+					record(part)
+				}()
+			}
+			inner.Wait()
+		}()
+	}
+	wg.Wait()
+
+	res := &FacetResult{}
+	type facetKey struct{ entityType, language string }
+	merged := map[facetKey]*FacetCount{}
+	for _, part := range parts {
+		if part.failure != nil {
+			res.Failures = append(res.Failures, *part.failure)
+			continue
+		}
+		if part.capped {
+			res.SemanticCapped = true
+		}
+		for _, fc := range part.counts {
+			k := facetKey{fc.EntityType, fc.Language}
+			m := merged[k]
+			if m == nil {
+				m = &FacetCount{EntityType: fc.EntityType, Language: fc.Language}
+				merged[k] = m
+			}
+			if part.semantic {
+				m.SemanticCount += fc.Count
+			} else {
+				m.Count += fc.Count
+			}
+		}
+	}
+	if len(res.Failures) > 0 {
+		sortBackendFailures(res.Failures)
+		if !allowPartial || len(res.Failures) == len(parts) {
+			return nil, res.Failures[0]
+		}
+	}
+
+	langRank := make(map[string]int, len(p.languages))
+	for i, l := range p.languages {
+		langRank[l] = i
+	}
+	res.Counts = make([]FacetCount, 0, len(merged))
+	for _, m := range merged {
+		res.Counts = append(res.Counts, *m)
+	}
+	sort.Slice(res.Counts, func(i, j int) bool {
+		a, b := res.Counts[i], res.Counts[j]
+		if a.Language != b.Language {
+			return langRank[a.Language] < langRank[b.Language]
+		}
+		return a.EntityType < b.EntityType
+	})
+	return res, nil
+}
+
+// sortBackendFailures orders failures deterministically (facet parts complete
+// in arbitrary order).
+func sortBackendFailures(failures []BackendFailure) {
+	sort.SliceStable(failures, func(i, j int) bool {
+		if failures[i].Backend != failures[j].Backend {
+			return failures[i].Backend < failures[j].Backend
+		}
+		return failures[i].Language < failures[j].Language
+	})
+}
+
+// queryEmbedding memoizes the query embedding so that Search and its facet
+// computation make a single provider call.
+//
+// Each caller embeds under its own context. A call that fails because its
+// caller's context ended is not memoized: the next waiting caller retries
+// with its own context instead of inheriting the cancellation.
+type queryEmbedding struct {
+	embedder Embedder
+	model    string
+	query    string
+
+	mu      sync.Mutex
+	running chan struct{} // closed when the in-flight call returns
+	done    bool
+	vec     []float32
+	err     error
+}
+
+func (e *queryEmbedding) get(ctx context.Context) ([]float32, error) {
+	for {
+		e.mu.Lock()
+		if e.done {
+			e.mu.Unlock()
+			return e.vec, e.err
+		}
+		if wait := e.running; wait != nil {
+			e.mu.Unlock()
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		running := make(chan struct{})
+		e.running = running
+		e.mu.Unlock()
+
+		vec, err := e.embedder.EmbedQueryText(ctx, e.model, e.query)
+
+		e.mu.Lock()
+		if err == nil || ctx.Err() == nil {
+			e.vec, e.err, e.done = vec, err, true
+		}
+		e.running = nil
+		close(running)
+		e.mu.Unlock()
+		return vec, err
+	}
+}
+
+// empty reports whether the embedding was computed and came back empty. It is
+// safe to call on a nil *queryEmbedding (no semantic side).
+func (e *queryEmbedding) empty() bool {
+	if e == nil {
+		return false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.done && e.err == nil && len(e.vec) == 0
+}
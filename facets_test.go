@@ -0,0 +1,113 @@
+package searchkit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientFacets_Validation(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{
+		Pool:         newTestPool(t),
+		Schema:       "test",
+		DefaultModel: "model",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := client.Facets(ctx, "two factor", FacetOptions{Mode: SearchModeLexical}); err == nil || !strings.Contains(err.Error(), "LexicalEntityTypes is required") {
+		t.Fatalf("expected lexical-types error, got %v", err)
+	}
+	if _, err := client.Facets(ctx, "two factor", FacetOptions{Mode: SearchModeSemantic, EntityTypes: []string{"gallery"}}); err == nil || !strings.Contains(err.Error(), "Embedder is required") {
+		t.Fatalf("expected embedder-required error, got %v", err)
+	}
+
+	res, err := client.Facets(ctx, "  ", FacetOptions{EntityTypes: []string{"gallery"}})
+	if err != nil || len(res.Counts) != 0 {
+		t.Fatalf("expected empty facets for empty query, got %+v, %v", res, err)
+	}
+}
+
+func TestClientFacets_SemanticFailure(t *testing.T) {
+	t.Parallel()
+
+	emb := &recordingEmbedder{err: errors.New("provider down")}
+	client, err := NewClient(ClientConfig{
+		Pool:         newTestPool(t),
+		Schema:       "test",
+		Embedder:     emb,
+		DefaultModel: "model",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	_, err = client.Facets(context.Background(), "two factor", FacetOptions{
+		Mode:        SearchModeSemantic,
+		EntityTypes: []string{"gallery"},
+	})
+	var failure BackendFailure
+	if !errors.As(err, &failure) || failure.Backend != BackendSemantic {
+		t.Fatalf("expected semantic BackendFailure, got %v", err)
+	}
+	if emb.text != "two factor" {
+		t.Fatalf("expected normalized query to be embedded, got %q", emb.text)
+	}
+}
+
+// ctxEmbedder fails calls whose context ends before release is closed.
+type ctxEmbedder struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (e *ctxEmbedder) EmbedQueryText(ctx context.Context, _ string, _ string) ([]float32, error) {
+	e.calls.Add(1)
+	select {
+	case <-e.release:
+		return []float32{1}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestQueryEmbedding_CallerCancellationNotShared(t *testing.T) {
+	t.Parallel()
+
+	emb := &ctxEmbedder{release: make(chan struct{})}
+	e := &queryEmbedding{embedder: emb, model: "m", query: "q"}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := e.get(first)
+		firstErr <- err
+	}()
+	for emb.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	second := make(chan []float32, 1)
+	go func() {
+		vec, _ := e.get(context.Background())
+		second <- vec
+	}()
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller err = %v; want context.Canceled", err)
+	}
+	close(emb.release)
+	if vec := <-second; len(vec) != 1 {
+		t.Fatalf("second caller got %v; want the embedding", vec)
+	}
+	if vec, err := e.get(context.Background()); err != nil || len(vec) != 1 || emb.calls.Load() != 2 {
+		t.Fatalf("expected memoized embedding after 2 calls, got %v, %v (%d calls)", vec, err, emb.calls.Load())
+	}
+}
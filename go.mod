@@ -9,6 +9,7 @@ require (
 	github.com/mozillazg/go-unidecode v0.2.0
 	github.com/pgvector/pgvector-go v0.2.2
 	github.com/sashabaranov/go-openai v1.40.3
+	golang.org/x/sync v0.17.0
 	golang.org/x/text v0.29.0
 )
 
@@ -18,5 +19,4 @@ require (
 	github.com/jackc/puddle/v2 v2.2.2 // indirect
 	github.com/stretchr/testify v1.11.1 // indirect
 	golang.org/x/crypto v0.42.0 // indirect
-	golang.org/x/sync v0.17.0 // indirect
 )
@@ -0,0 +1,286 @@
+package searchkit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GroupingOptions shapes Search results for federated result lists, e.g. "the
+// top 3 artists, top 5 tags and top 12 galleries, at most 2 galleries per
+// artist".
+//
+// Grouping is applied to the fused (and language-collapsed) ranking before the
+// page is cut, so Limit and pagination operate on the grouped list.
+type GroupingOptions struct {
+	// Quotas caps the number of hits per entity type, in display order (see
+	// SearchResult.Groups). Entity types without a quota are not capped. When
+	// SearchOptions.Limit is 0 and every searched type has a quota, the page
+	// size defaults to the sum of the quotas.
+	Quotas []GroupQuota
+
+	// Collapse keeps at most MaxPerKey hits per collapse key value.
+	Collapse *CollapseKey
+
+	// OverFetch multiplies backend retrieval depth so that quotas and
+	// collapsing can still fill the page (default 3).
+	OverFetch int
+}
+
+type GroupQuota struct {
+	EntityType string
+	Limit      int
+}
+
+// CollapseKey derives a key per candidate hit; hits sharing a key beyond
+// MaxPerKey are dropped (the best-ranked ones are kept). Exactly one of Func
+// or SQL must be set. Hits without a key are never collapsed.
+type CollapseKey struct {
+	// Func looks up keys from host attributes: it receives the candidate IDs of
+	// one entity type and returns key by entity ID.
+	Func func(ctx context.Context, entityType string, entityIDs []string) (map[string]string, error)
+
+	// SQL is a trusted host SQL expression evaluated once per candidate, which
+	// is available as k.entity_type and k.entity_id (text), e.g.
+	//   (SELECT g.artist_id::text FROM app.galleries g WHERE g.id::text = k.entity_id)
+	// NULL means no key. Args are named args referenced by SQL.
+	SQL  string
+	Args map[string]any
+
+	// EntityTypes restricts collapsing to these types (empty = all types).
+	EntityTypes []string
+	// MaxPerKey defaults to 1.
+	MaxPerKey int
+}
+
+// HitGroup is one entity type's slice of a grouped page.
+type HitGroup struct {
+	EntityType string
+	Hits       []SearchHit
+}
+
+func validateGrouping(g *GroupingOptions) error {
+	if g == nil {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	for _, q := range g.Quotas {
+		t := strings.TrimSpace(q.EntityType)
+		if t == "" {
+			return fmt.Errorf("invalid SearchOptions.Grouping: quota EntityType is required")
+		}
+		if q.Limit <= 0 {
+			return fmt.Errorf("invalid SearchOptions.Grouping: quota for %q must be > 0", t)
+		}
+		if _, ok := seen[t]; ok {
+			return fmt.Errorf("invalid SearchOptions.Grouping: duplicate quota for %q", t)
+		}
+		seen[t] = struct{}{}
+	}
+	if g.OverFetch < 0 {
+		return fmt.Errorf("invalid SearchOptions.Grouping: OverFetch must be >= 0")
+	}
+	if ck := g.Collapse; ck != nil {
+		hasSQL := strings.TrimSpace(ck.SQL) != ""
+		if (ck.Func == nil) == !hasSQL {
+			return fmt.Errorf("invalid SearchOptions.Grouping: exactly one of Collapse.Func or Collapse.SQL is required")
+		}
+		if ck.MaxPerKey < 0 {
+			return fmt.Errorf("invalid SearchOptions.Grouping: Collapse.MaxPerKey must be >= 0")
+		}
+	}
+	return nil
+}
+
+// groupingFingerprint renders the parts of g that affect result ordering.
+// Collapse.Func cannot be fingerprinted; page tokens assume the host keeps it
+// stable for a given query.
+func groupingFingerprint(g *GroupingOptions) string {
+	if g == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, q := range g.Quotas {
+		fmt.Fprintf(&b, "%s=%d,", strings.TrimSpace(q.EntityType), q.Limit)
+	}
+	if ck := g.Collapse; ck != nil {
+		fmt.Fprintf(&b, "|%t|%s|%s|%d",
+			ck.Func != nil,
+			filterFingerprint(ck.SQL, ck.Args),
+			strings.Join(cloneAndTrim(ck.EntityTypes), ","),
+			ck.MaxPerKey)
+	}
+	return b.String()
+}
+
+// groupedLimit returns the default page size implied by quotas, or 0 when some
+// searched entity type has no quota.
+func groupedLimit(g *GroupingOptions, entityTypes []string) int {
+	if g == nil || len(g.Quotas) == 0 {
+		return 0
+	}
+	quota := map[string]int{}
+	for _, q := range g.Quotas {
+		quota[strings.TrimSpace(q.EntityType)] = q.Limit
+	}
+	total := 0
+	for _, t := range entityTypes {
+		n, ok := quota[t]
+		if !ok {
+			return 0
+		}
+		total += n
+	}
+	return total
+}
+
+// applyGrouping drops hits beyond the collapse and quota limits, keeping the
+// order of hits.
+func (c *Client) applyGrouping(ctx context.Context, hits []SearchHit, g *GroupingOptions) ([]SearchHit, error) {
+	if g == nil {
+		return hits, nil
+	}
+
+	var keys map[entityKey]string
+	maxPerKey := 0
+	if g.Collapse != nil {
+		var err error
+		keys, err = c.collapseKeys(ctx, hits, g.Collapse)
+		if err != nil {
+			return nil, err
+		}
+		maxPerKey = g.Collapse.MaxPerKey
+		if maxPerKey <= 0 {
+			maxPerKey = 1
+		}
+	}
+	quota := map[string]int{}
+	for _, q := range g.Quotas {
+		quota[strings.TrimSpace(q.EntityType)] = q.Limit
+	}
+
+	perKey := map[string]int{}
+	perType := map[string]int{}
+	out := hits[:0:0]
+	for _, h := range hits {
+		if key, ok := keys[entityKey{h.EntityType, h.EntityID}]; ok {
+			// Keys are scoped by entity type.
+			scoped := h.EntityType + "\x1f" + key
+			if perKey[scoped] >= maxPerKey {
+				continue
+			}
+			perKey[scoped]++
+		}
+		if n, ok := quota[h.EntityType]; ok {
+			if perType[h.EntityType] >= n {
+				continue
+			}
+			perType[h.EntityType]++
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// collapseKeys resolves collapse keys for the hits subject to collapsing.
+func (c *Client) collapseKeys(ctx context.Context, hits []SearchHit, ck *CollapseKey) (map[entityKey]string, error) {
+	types := map[string]struct{}{}
+	for _, t := range cloneAndTrim(ck.EntityTypes) {
+		types[t] = struct{}{}
+	}
+	byType := map[string][]string{}
+	var order []string
+	for _, h := range hits {
+		if len(types) > 0 {
+			if _, ok := types[h.EntityType]; !ok {
+				continue
+			}
+		}
+		if _, ok := byType[h.EntityType]; !ok {
+			order = append(order, h.EntityType)
+		}
+		byType[h.EntityType] = append(byType[h.EntityType], h.EntityID)
+	}
+
+	out := map[entityKey]string{}
+	if ck.Func != nil {
+		for _, t := range order {
+			m, err := ck.Func(ctx, t, byType[t])
+			if err != nil {
+				return nil, fmt.Errorf("collapse key lookup for %q: %w", t, err)
+			}
+			for id, key := range m {
+				out[entityKey{t, id}] = key
+			}
+		}
+		return out, nil
+	}
+
+	var entityTypes, entityIDs []string
+	for _, t := range order {
+		for _, id := range byType[t] {
+			entityTypes = append(entityTypes, t)
+			entityIDs = append(entityIDs, id)
+		}
+	}
+	if len(entityIDs) == 0 {
+		return out, nil
+	}
+	args := pgx.NamedArgs{
+		"entity_types": entityTypes,
+		"entity_ids":   entityIDs,
+	}
+	for k, v := range ck.Args {
+		k = strings.TrimSpace(k)
+		if k == "" {
+			return nil, fmt.Errorf("empty Collapse.Args key")
+		}
+		if _, exists := args[k]; exists {
+			return nil, fmt.Errorf("Collapse.Args key %q conflicts with reserved arg", k)
+		}
+		args[k] = v
+	}
+	rows, err := c.pool.Query(ctx, fmt.Sprintf(`
+		SELECT k.entity_type, k.entity_id, (%s)::text
+		FROM unnest(@entity_types::text[], @entity_ids::text[]) AS k(entity_type, entity_id)
+	`, ck.SQL), args)
+	if err != nil {
+		return nil, fmt.Errorf("collapse key query: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t, id string
+		var key *string
+		if err := rows.Scan(&t, &id, &key); err != nil {
+			return nil, err
+		}
+		if key != nil {
+			out[entityKey{t, id}] = *key
+		}
+	}
+	return out, rows.Err()
+}
+
+// groupHits splits a page into groups: quota types first, in quota order, then
+// other types in order of first appearance.
+func groupHits(hits []SearchHit, g *GroupingOptions) []HitGroup {
+	index := map[string]int{}
+	var out []HitGroup
+	for _, q := range g.Quotas {
+		t := strings.TrimSpace(q.EntityType)
+		index[t] = len(out)
+		out = append(out, HitGroup{EntityType: t, Hits: []SearchHit{}})
+	}
+	for _, h := range hits {
+		i, ok := index[h.EntityType]
+		if !ok {
+			i = len(out)
+			index[h.EntityType] = i
+			out = append(out, HitGroup{EntityType: h.EntityType})
+		}
+		out[i].Hits = append(out[i].Hits, h)
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package searchkit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestApplyGrouping_QuotasAndCollapse(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{Pool: newTestPool(t), Schema: "test"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	artistOf := map[string]string{"g1": "a", "g2": "a", "g3": "a", "g4": "b"}
+	g := &GroupingOptions{
+		Quotas: []GroupQuota{{EntityType: "artist", Limit: 1}, {EntityType: "gallery", Limit: 3}},
+		Collapse: &CollapseKey{
+			EntityTypes: []string{"gallery"},
+			MaxPerKey:   2,
+			Func: func(_ context.Context, entityType string, ids []string) (map[string]string, error) {
+				if entityType != "gallery" {
+					t.Errorf("unexpected collapse lookup for %q", entityType)
+				}
+				out := map[string]string{}
+				for _, id := range ids {
+					if a, ok := artistOf[id]; ok {
+						out[id] = a
+					}
+				}
+				return out, nil
+			},
+		},
+	}
+	if err := validateGrouping(g); err != nil {
+		t.Fatalf("validateGrouping: %v", err)
+	}
+
+	hits := []SearchHit{
+		{EntityType: "gallery", EntityID: "g1"},
+		{EntityType: "artist", EntityID: "a"},
+		{EntityType: "gallery", EntityID: "g2"},
+		{EntityType: "gallery", EntityID: "g3"}, // third gallery by artist a
+		{EntityType: "artist", EntityID: "b"},   // over the artist quota
+		{EntityType: "gallery", EntityID: "g4"},
+		{EntityType: "gallery", EntityID: "g5"}, // over the gallery quota
+		{EntityType: "tag", EntityID: "t1"},     // no quota
+	}
+	got, err := client.applyGrouping(context.Background(), hits, g)
+	if err != nil {
+		t.Fatalf("applyGrouping: %v", err)
+	}
+	var ids []string
+	for _, h := range got {
+		ids = append(ids, h.EntityID)
+	}
+	want := []string{"g1", "a", "g2", "g4", "t1"}
+	if len(ids) != len(want) {
+		t.Fatalf("got %v; want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("got %v; want %v", ids, want)
+		}
+	}
+
+	groups := groupHits(got, g)
+	if len(groups) != 3 || groups[0].EntityType != "artist" || len(groups[1].Hits) != 3 || groups[2].EntityType != "tag" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+}
+
+func TestGroupedLimit(t *testing.T) {
+	t.Parallel()
+
+	g := &GroupingOptions{Quotas: []GroupQuota{{EntityType: "artist", Limit: 3}, {EntityType: "tag", Limit: 5}, {EntityType: "gallery", Limit: 12}}}
+	if got := groupedLimit(g, []string{"artist", "tag", "gallery"}); got != 20 {
+		t.Fatalf("groupedLimit = %d; want 20", got)
+	}
+	if got := groupedLimit(g, []string{"artist", "series"}); got != 0 {
+		t.Fatalf("groupedLimit with an unquoted type = %d; want 0", got)
+	}
+}
+
+func TestValidateGrouping(t *testing.T) {
+	t.Parallel()
+
+	bad := []*GroupingOptions{
+		{Quotas: []GroupQuota{{EntityType: "", Limit: 1}}},
+		{Quotas: []GroupQuota{{EntityType: "tag", Limit: 0}}},
+		{Quotas: []GroupQuota{{EntityType: "tag", Limit: 1}, {EntityType: "tag", Limit: 2}}},
+		{Collapse: &CollapseKey{}},
+		{Collapse: &CollapseKey{SQL: "k.entity_id", Func: func(context.Context, string, []string) (map[string]string, error) { return nil, nil }}},
+	}
+	for i, g := range bad {
+		if err := validateGrouping(g); err == nil {
+			t.Fatalf("case %d: expected error", i)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package searchkit
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+	"github.com/open-rails/searchkit/internal/textnormalize"
+	"github.com/open-rails/searchkit/search"
+)
+
+// HighlightOptions enables match highlighting on Search and Typeahead hits.
+//
+// Highlights are computed over search_documents.raw_document with the same
+// backend that matched the hit's language: ts_headline for FTS languages (so
+// stemmed matches are marked), PGroonga for CJK-script queries, and
+// trigram-aligned word spans otherwise.
+type HighlightOptions struct {
+	// StartMarker and EndMarker wrap each matched span in Highlight.Marked.
+	// Defaults (each separately): "<mark>" and "</mark>".
+	StartMarker string
+	EndMarker   string
+	// Escape is applied to the document text (not the markers) in
+	// Highlight.Marked. Defaults to html.EscapeString.
+	Escape func(string) string
+
+	// Snippet returns fragments around the matches instead of the whole
+	// document.
+	Snippet bool
+	// MaxWords bounds the length of each fragment in words (default 35).
+	MaxWords int
+	// MaxFragments is the maximum number of fragments (FTS only; default 1).
+	MaxFragments int
+	// FragmentDelimiter joins fragments (default " ... "). It must not
+	// contain '"' or ',' (ts_headline option syntax).
+	FragmentDelimiter string
+}
+
+// Highlight is a document (or snippet) with the spans that matched the query.
+type Highlight struct {
+	// Text is the plain, unescaped document text or snippet.
+	Text string
+	// Spans are the matched byte ranges of Text, in order.
+	Spans []HighlightSpan
+	// Marked is Text escaped with HighlightOptions.Escape, with every span
+	// wrapped in the configured markers.
+	Marked string
+}
+
+// HighlightSpan is the byte range [Start, End) of Highlight.Text.
+type HighlightSpan struct {
+	Start int
+	End   int
+}
+
+// Sentinels used to mark spans in backend output; they are parsed back into
+// Highlight.Spans and never reach callers.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+func validateHighlight(field string, o *HighlightOptions) error {
+	if o != nil && strings.ContainsAny(o.FragmentDelimiter, `",`) {
+		return fmt.Errorf("invalid %s.FragmentDelimiter: must not contain '\"' or ','", field)
+	}
+	return nil
+}
+
+func highlightFingerprint(o *HighlightOptions) string {
+	if o == nil {
+		return ""
+	}
+	return fmt.Sprintf("%q|%q|%t|%d|%d|%q|%t", o.StartMarker, o.EndMarker, o.Snippet, o.MaxWords, o.MaxFragments, o.FragmentDelimiter, o.Escape != nil)
+}
+
+// highlightDocs computes highlights for keys, routing each by its language the
+// same way retrieval does.
+func (c *Client) highlightDocs(ctx context.Context, q string, keys []search.DocKey, typeahead bool, opts *HighlightOptions) (map[search.DocKey]*Highlight, error) {
+	var ftsKeys, pgroongaKeys, trigramKeys []search.DocKey
+	for _, k := range keys {
+		route := lexicalRouting(k.Language, q, typeahead)
+		switch {
+		case route.useFTS:
+			ftsKeys = append(ftsKeys, k)
+		case route.usePGroonga:
+			pgroongaKeys = append(pgroongaKeys, k)
+		default:
+			trigramKeys = append(trigramKeys, k)
+		}
+	}
+
+	hopts := search.HeadlineOptions{
+		Schema:            c.schema,
+		StartSel:          highlightStart,
+		StopSel:           highlightStop,
+		Snippet:           opts.Snippet,
+		MaxWords:          opts.MaxWords,
+		MaxFragments:      opts.MaxFragments,
+		FragmentDelimiter: opts.FragmentDelimiter,
+	}
+	out := make(map[search.DocKey]*Highlight, len(keys))
+	if len(ftsKeys) > 0 {
+		marked, err := search.FTSHeadlines(ctx, c.pool, q, ftsKeys, hopts)
+		if err != nil {
+			return nil, fmt.Errorf("fts highlight: %w", err)
+		}
+		for k, m := range marked {
+			text, spans := parseMarkedText(m)
+			out[k] = renderHighlight(text, spans, opts)
+		}
+	}
+	if len(pgroongaKeys) > 0 {
+		marked, err := search.PGroongaHighlights(ctx, c.pool, q, pgroongaKeys, hopts)
+		if err != nil {
+			return nil, fmt.Errorf("pgroonga highlight: %w", err)
+		}
+		for k, m := range marked {
+			text, spans := parseMarkedText(m)
+			out[k] = renderHighlight(text, spans, opts)
+		}
+	}
+	if len(trigramKeys) > 0 {
+		docs, err := search.RawDocuments(ctx, c.pool, c.schema, trigramKeys)
+		if err != nil {
+			return nil, fmt.Errorf("trigram highlight: %w", err)
+		}
+		for k, doc := range docs {
+			text, spans := trigramHighlight(doc, q, opts)
+			out[k] = renderHighlight(text, spans, opts)
+		}
+	}
+	return out, nil
+}
+
+// parseMarkedText strips the highlight sentinels from s and returns the spans
+// they delimited. Unbalanced sentinels are dropped.
+func parseMarkedText(s string) (string, []HighlightSpan) {
+	var b strings.Builder
+	b.Grow(len(s))
+	var spans []HighlightSpan
+	open := -1
+	for _, r := range s {
+		switch string(r) {
+		case highlightStart:
+			open = b.Len()
+		case highlightStop:
+			if open >= 0 && b.Len() > open {
+				spans = append(spans, HighlightSpan{Start: open, End: b.Len()})
+			}
+			open = -1
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), spans
+}
+
+func renderHighlight(text string, spans []HighlightSpan, opts *HighlightOptions) *Highlight {
+	start, end := opts.StartMarker, opts.EndMarker
+	if start == "" {
+		start = "<mark>"
+	}
+	if end == "" {
+		end = "</mark>"
+	}
+	escape := opts.Escape
+	if escape == nil {
+		escape = html.EscapeString
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, sp := range spans {
+		b.WriteString(escape(text[pos:sp.Start]))
+		b.WriteString(start)
+		b.WriteString(escape(text[sp.Start:sp.End]))
+		b.WriteString(end)
+		pos = sp.End
+	}
+	b.WriteString(escape(text[pos:]))
+	return &Highlight{Text: text, Spans: spans, Marked: b.String()}
+}
+
+// trigramHighlight marks the words of doc that match a query token the way
+// trigram search does: after heavy normalization, a word matches when it
+// contains the token or is trigram-similar to it.
+func trigramHighlight(doc string, q string, opts *HighlightOptions) (string, []HighlightSpan) {
+	tokens := strings.Fields(textnormalize.Heavy(querynorm.ParseQuery(q).PlainText()))
+	words := wordSpans(doc)
+
+	var spans []HighlightSpan
+	first := -1
+	for i, w := range words {
+		if trigramWordMatches(textnormalize.Heavy(doc[w.Start:w.End]), tokens) {
+			spans = append(spans, w)
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+	if !opts.Snippet || len(words) == 0 {
+		return doc, spans
+	}
+
+	maxWords := opts.MaxWords
+	if maxWords <= 0 {
+		maxWords = 35
+	}
+	from := 0
+	if first > 0 {
+		from = first - maxWords/4
+		if from < 0 {
+			from = 0
+		}
+	}
+	to := from + maxWords
+	if to > len(words) {
+		to = len(words)
+	}
+	cutStart, cutEnd := words[from].Start, words[to-1].End
+	if from == 0 {
+		cutStart = 0
+	}
+	if to == len(words) {
+		cutEnd = len(doc)
+	}
+
+	var inside []HighlightSpan
+	for _, sp := range spans {
+		if sp.Start >= cutStart && sp.End <= cutEnd {
+			inside = append(inside, HighlightSpan{Start: sp.Start - cutStart, End: sp.End - cutStart})
+		}
+	}
+	return doc[cutStart:cutEnd], inside
+}
+
+// wordSpans returns the byte ranges of maximal letter/number runs in s.
+func wordSpans(s string) []HighlightSpan {
+	var out []HighlightSpan
+	start := -1
+	for i, r := range s {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			out = append(out, HighlightSpan{Start: start, End: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		out = append(out, HighlightSpan{Start: start, End: len(s)})
+	}
+	return out
+}
+
+func trigramWordMatches(word string, tokens []string) bool {
+	if word == "" {
+		return false
+	}
+	for _, tok := range tokens {
+		if strings.Contains(word, tok) {
+			return true
+		}
+		if utf8.RuneCountInString(tok) >= 3 && trigramSimilarity(word, tok) >= 0.5 {
+			return true
+		}
+	}
+	return false
+}
+
+// trigramSimilarity mirrors pg_trgm's similarity(): the Jaccard index of the
+// padded trigram sets of a and b.
+func trigramSimilarity(a, b string) float32 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			common++
+		}
+	}
+	return float32(common) / float32(len(ta)+len(tb)-common)
+}
+
+func trigrams(s string) map[string]struct{} {
+	out := map[string]struct{}{}
+	for _, w := range strings.Fields(s) {
+		rs := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(rs); i++ {
+			out[string(rs[i:i+3])] = struct{}{}
+		}
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package searchkit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseMarkedTextAndRender(t *testing.T) {
+	t.Parallel()
+
+	text, spans := parseMarkedText("a <b> \x02two\x03 & \x02factor\x03\x03")
+	if text != "a <b> two & factor" {
+		t.Fatalf("text = %q", text)
+	}
+	want := []HighlightSpan{{Start: 6, End: 9}, {Start: 12, End: 18}}
+	if !reflect.DeepEqual(spans, want) {
+		t.Fatalf("spans = %+v; want %+v", spans, want)
+	}
+
+	h := renderHighlight(text, spans, &HighlightOptions{})
+	if h.Marked != "a &lt;b&gt; <mark>two</mark> &amp; <mark>factor</mark>" {
+		t.Fatalf("Marked = %q", h.Marked)
+	}
+	h = renderHighlight(text, spans, &HighlightOptions{StartMarker: "[", EndMarker: "]", Escape: func(s string) string { return s }})
+	if h.Marked != "a <b> [two] & [factor]" {
+		t.Fatalf("Marked with custom markers = %q", h.Marked)
+	}
+	h = renderHighlight(text, spans, &HighlightOptions{StartMarker: "<em>"})
+	if h.Marked != "a &lt;b&gt; <em>two</mark> &amp; <em>factor</mark>" {
+		t.Fatalf("Marked with only StartMarker = %q", h.Marked)
+	}
+}
+
+func TestValidateHighlight(t *testing.T) {
+	t.Parallel()
+
+	if err := validateHighlight("SearchOptions.Highlight", &HighlightOptions{FragmentDelimiter: " | "}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, d := range []string{`"`, ", "} {
+		err := validateHighlight("SearchOptions.Highlight", &HighlightOptions{FragmentDelimiter: d})
+		if err == nil || !strings.Contains(err.Error(), "SearchOptions.Highlight.FragmentDelimiter") {
+			t.Fatalf("FragmentDelimiter %q: err = %v", d, err)
+		}
+	}
+}
+
+func TestTrigramHighlight(t *testing.T) {
+	t.Parallel()
+
+	doc := "Tentacle Café: the tentacles strike back"
+	text, spans := trigramHighlight(doc, "tentacle cafe", &HighlightOptions{})
+	if text != doc {
+		t.Fatalf("expected full document, got %q", text)
+	}
+	var got []string
+	for _, sp := range spans {
+		got = append(got, text[sp.Start:sp.End])
+	}
+	if want := []string{"Tentacle", "Café", "tentacles"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("matched %v; want %v", got, want)
+	}
+
+	text, spans = trigramHighlight("one two three four five six seven eight", "seven", &HighlightOptions{Snippet: true, MaxWords: 4})
+	if text != "six seven eight" || len(spans) != 1 || text[spans[0].Start:spans[0].End] != "seven" {
+		t.Fatalf("snippet = %q spans=%+v", text, spans)
+	}
+}
@@ -0,0 +1,432 @@
+package normalize
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed user query: clauses are ANDed.
+//
+// Syntax (everything else is plain words):
+//   - "quoted phrase"
+//   - a OR b (case-insensitive `or` between two terms)
+//   - not x / not "x y" (exclusion of the next term)
+//   - field:value / field:"a phrase" for the fields in FieldWeights
+//
+// A leading '-' is punctuation, not an operator (see QueryForEmbedding).
+type Query struct {
+	Clauses []Clause
+}
+
+// Clause is a set of alternative terms (ORed). Excluded clauses must not
+// match.
+type Clause struct {
+	Terms   []Term
+	Exclude bool
+}
+
+// Term is a word, a group of words that must all match, or a phrase.
+type Term struct {
+	// Field scopes the term to a document field (a FieldWeights key).
+	Field string
+	// Text is the term as typed, punctuation included ("c++", "2.0"; a phrase
+	// without its quotes). Backends split it into their own tokens, e.g. the
+	// parser of the text search config for FTS.
+	Text   string
+	Phrase bool
+}
+
+// FieldWeights maps the field names accepted in `field:value` to the tsvector
+// weights of field-weighted lexical documents.
+var FieldWeights = map[string]string{
+	"title":   "A",
+	"alias":   "B",
+	"aliases": "B",
+	"tag":     "C",
+	"tags":    "C",
+	"body":    "D",
+}
+
+type queryToken struct {
+	text   string
+	quoted bool
+	field  string
+}
+
+// ParseQuery parses user input into a Query. It never fails: unbalanced
+// quotes run to the end of the input and dangling operators are dropped or
+// treated as words.
+func ParseQuery(input string) Query {
+	toks := lexQuery(normalizeIntraTokenHyphens(strings.TrimSpace(input)))
+	var q Query
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		op := ""
+		if !t.quoted && t.field == "" {
+			op = strings.ToLower(t.text)
+		}
+		switch {
+		case op == "not" && i+1 < len(toks):
+			if term, ok := termOf(toks[i+1]); ok {
+				q.Clauses = append(q.Clauses, Clause{Terms: []Term{term}, Exclude: true})
+			}
+			i++
+			continue
+		case op == "or":
+			// Only binds two positive terms; otherwise it is dropped, as in
+			// websearch_to_tsquery.
+			if n := len(q.Clauses); n > 0 && !q.Clauses[n-1].Exclude && i+1 < len(toks) && !isOperator(toks[i+1]) {
+				if term, ok := termOf(toks[i+1]); ok {
+					q.Clauses[n-1].Terms = append(q.Clauses[n-1].Terms, term)
+				}
+				i++
+			}
+			continue
+		}
+		if term, ok := termOf(t); ok {
+			q.Clauses = append(q.Clauses, Clause{Terms: []Term{term}})
+		}
+	}
+	return q
+}
+
+func isOperator(t queryToken) bool {
+	if t.quoted || t.field != "" {
+		return false
+	}
+	op := strings.ToLower(t.text)
+	return op == "or" || op == "not"
+}
+
+func lexQuery(s string) []queryToken {
+	rs := []rune(s)
+	var out []queryToken
+	readQuoted := func(i int) (string, int) {
+		start := i
+		for i < len(rs) && rs[i] != '"' {
+			i++
+		}
+		text := string(rs[start:i])
+		if i < len(rs) {
+			i++ // closing quote
+		}
+		return text, i
+	}
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+		if r == '"' {
+			text, next := readQuoted(i + 1)
+			out = append(out, queryToken{text: text, quoted: true})
+			i = next
+			continue
+		}
+		start := i
+		for i < len(rs) && !unicode.IsSpace(rs[i]) && rs[i] != '"' {
+			i++
+		}
+		word := stripLeadingHyphens(string(rs[start:i]))
+		if name, rest, ok := strings.Cut(word, ":"); ok {
+			field := strings.ToLower(name)
+			if _, known := FieldWeights[field]; known {
+				if rest == "" && i < len(rs) && rs[i] == '"' {
+					text, next := readQuoted(i + 1)
+					out = append(out, queryToken{text: text, quoted: true, field: field})
+					i = next
+					continue
+				}
+				if rest != "" {
+					out = append(out, queryToken{text: rest, field: field})
+					continue
+				}
+			}
+		}
+		if word != "" {
+			out = append(out, queryToken{text: word})
+		}
+	}
+	return out
+}
+
+func termOf(t queryToken) (Term, bool) {
+	text, ok := termText(t.text)
+	if !ok {
+		return Term{}, false
+	}
+	return Term{Field: t.field, Text: text, Phrase: t.quoted && strings.Contains(text, " ")}, true
+}
+
+// termText collapses the spaces of s; ok is false when s has no letter or
+// number (punctuation alone matches nothing).
+func termText(s string) (text string, ok bool) {
+	text = strings.Join(strings.Fields(s), " ")
+	return text, strings.IndexFunc(text, isLetterOrNumber) >= 0
+}
+
+// HasPositive reports whether the query has a non-excluded clause.
+func (q Query) HasPositive() bool {
+	for _, c := range q.Clauses {
+		if !c.Exclude {
+			return true
+		}
+	}
+	return false
+}
+
+// Exclusions returns a query of the excluded clauses, as positive clauses (a
+// document matching it is excluded).
+func (q Query) Exclusions() Query {
+	var out Query
+	for _, c := range q.Clauses {
+		if c.Exclude {
+			out.Clauses = append(out.Clauses, Clause{Terms: c.Terms})
+		}
+	}
+	return out
+}
+
+// AnyOf returns a query matching documents that match any clause of q (the
+// clauses become alternatives of a single clause). Used to test exclusions:
+// a document is excluded if it matches any excluded clause.
+func (q Query) AnyOf() Query {
+	var terms []Term
+	for _, c := range q.Clauses {
+		terms = append(terms, c.Terms...)
+	}
+	if len(terms) == 0 {
+		return Query{}
+	}
+	return Query{Clauses: []Clause{{Terms: terms}}}
+}
+
+// PlainText returns the text of the first alternative of every positive
+// clause, space-separated.
+func (q Query) PlainText() string {
+	var texts []string
+	for _, c := range q.Clauses {
+		if !c.Exclude {
+			texts = append(texts, c.Terms[0].Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// PositiveText returns the text of every positive term (all alternatives),
+// space-separated in query order: the query as typed without operators,
+// quotes, field names or exclusions. Backends that take plain text
+// (embeddings, rerankers) use it.
+func (q Query) PositiveText() string {
+	var texts []string
+	for _, c := range q.Clauses {
+		if c.Exclude {
+			continue
+		}
+		for _, t := range c.Terms {
+			texts = append(texts, t.Text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// HasFieldScopes reports whether any term is scoped to a field.
+func (q Query) HasFieldScopes() bool {
+	for _, c := range q.Clauses {
+		for _, t := range c.Terms {
+			if t.Field != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Unscoped returns q with field scopes removed, for documents that have no
+// fields (flat lexical documents).
+func (q Query) Unscoped() Query {
+	out := Query{Clauses: make([]Clause, len(q.Clauses))}
+	for i, c := range q.Clauses {
+		terms := make([]Term, len(c.Terms))
+		for j, t := range c.Terms {
+			t.Field = ""
+			terms[j] = t
+		}
+		out.Clauses[i] = Clause{Terms: terms, Exclude: c.Exclude}
+	}
+	return out
+}
+
+// ExpandSynonyms replaces runs of plain word clauses matching a synonym key
+// (longest first) with one clause ORing the original words and each
+// alternative. Phrases, field-scoped terms, exclusions and OR clauses are not
+// expanded.
+func (q Query) ExpandSynonyms(syn Synonyms) Query {
+	if len(syn) == 0 || len(q.Clauses) == 0 {
+		return q
+	}
+	words := make([]string, len(q.Clauses))
+	plain := make([]bool, len(q.Clauses))
+	for i, c := range q.Clauses {
+		if !c.Exclude && len(c.Terms) == 1 && c.Terms[0].Field == "" && !c.Terms[0].Phrase {
+			words[i] = c.Terms[0].Text
+			plain[i] = true
+		}
+	}
+	matches := matchSynonyms(words, syn, func(i int) bool { return !plain[i] })
+	if len(matches) == 0 {
+		return q
+	}
+
+	var out Query
+	next := 0
+	for _, m := range matches {
+		out.Clauses = append(out.Clauses, q.Clauses[next:m.start]...)
+		group := Clause{Terms: []Term{{Text: strings.Join(words[m.start:m.end], " ")}}}
+		for _, alt := range m.alternatives {
+			if text, ok := termText(alt); ok {
+				group.Terms = append(group.Terms, Term{Text: text})
+			}
+		}
+		out.Clauses = append(out.Clauses, group)
+		next = m.end
+	}
+	out.Clauses = append(out.Clauses, q.Clauses[next:]...)
+	return out
+}
+
+// TSQuerySQL compiles q to a SQL expression of type tsquery. Every term's
+// text is turned into lexemes by the parser of the text search config
+// (plainto_tsquery, or phraseto_tsquery for phrases), as documents were
+// indexed, so "2.0" or "c++" match what to_tsvector stored. Alternatives are
+// joined with ||, clauses with && and exclusions negated with !!.
+// Field-scoped lexemes get the field's weight label.
+//
+// config is the SQL expression of the regconfig; bind binds a term's text
+// and returns its placeholder. Returns "" when q has no positive clause (a
+// query of only exclusions would match everything).
+func (q Query) TSQuerySQL(config string, bind func(text string) string) string {
+	if !q.HasPositive() {
+		return ""
+	}
+	parts := make([]string, 0, len(q.Clauses))
+	for _, c := range q.Clauses {
+		alts := make([]string, 0, len(c.Terms))
+		for _, t := range c.Terms {
+			fn := "plainto_tsquery"
+			if t.Phrase {
+				fn = "phraseto_tsquery"
+			}
+			expr := fn + "(" + config + ", " + bind(t.Text) + ")"
+			if weight := FieldWeights[t.Field]; weight != "" {
+				expr = weightTSQuery(expr, weight)
+			}
+			alts = append(alts, expr)
+		}
+		clause := group(alts, " || ")
+		if c.Exclude {
+			clause = "!!" + parenthesize(clause, false)
+		}
+		parts = append(parts, clause)
+	}
+	return group(parts, " && ")
+}
+
+// weightTSQuery labels every lexeme of the tsquery expression expr with
+// weight. tsquery has no setweight, so the label is appended to each quoted
+// lexeme of its text form (where ' and \ are doubled) and the text is cast
+// back.
+func weightTSQuery(expr string, weight string) string {
+	return "regexp_replace(" + expr + `::text, '''(?:[^''\\]|''''|\\.)*''', '\&:` + weight + `', 'g')::tsquery`
+}
+
+// PGroonga compiles q to PGroonga query syntax (`&@~`): every word or phrase
+// is double-quoted (escaping '"' and '\'), alternatives use OR, exclusions
+// use '-'. Field scopes are ignored. Returns "" when q has no positive clause.
+func (q Query) PGroonga() string {
+	if !q.HasPositive() {
+		return ""
+	}
+	var pos, neg []string
+	for _, c := range q.Clauses {
+		alts := make([]string, 0, len(c.Terms))
+		for _, t := range c.Terms {
+			if t.Phrase {
+				alts = append(alts, pgroongaQuote(t.Text))
+				continue
+			}
+			words := strings.Fields(t.Text)
+			ws := make([]string, 0, len(words))
+			for _, w := range words {
+				ws = append(ws, pgroongaQuote(w))
+			}
+			alts = append(alts, group(ws, " "))
+		}
+		clause := group(alts, " OR ")
+		if c.Exclude {
+			neg = append(neg, "-"+parenthesize(clause, len(alts) == 1 && !strings.Contains(clause, " ")))
+			continue
+		}
+		pos = append(pos, clause)
+	}
+	return strings.Join(append(pos, neg...), " ")
+}
+
+func pgroongaQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// TrigramProbes returns plain-text probes for backends without operators:
+// the first alternative of every positive clause, then one probe per other
+// alternative (substituted into the first), at most max (0 = no limit).
+// Exclusions are not part of any probe.
+func (q Query) TrigramProbes(max int) []string {
+	base := q.PlainText()
+	if base == "" {
+		return nil
+	}
+	out := []string{base}
+	seen := map[string]struct{}{base: {}}
+	for i, c := range q.Clauses {
+		if c.Exclude {
+			continue
+		}
+		for _, alt := range c.Terms[1:] {
+			if max > 0 && len(out) >= max {
+				return out
+			}
+			var texts []string
+			for j, other := range q.Clauses {
+				switch {
+				case other.Exclude:
+				case j == i:
+					texts = append(texts, alt.Text)
+				default:
+					texts = append(texts, other.Terms[0].Text)
+				}
+			}
+			p := strings.Join(texts, " ")
+			if _, ok := seen[p]; ok {
+				continue
+			}
+			seen[p] = struct{}{}
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func group(parts []string, sep string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+func parenthesize(s string, bare bool) string {
+	if bare || strings.HasPrefix(s, "(") {
+		return s
+	}
+	return "(" + s + ")"
+}
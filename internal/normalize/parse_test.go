@@ -0,0 +1,165 @@
+package normalize
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		in   string
+		want Query
+	}{
+		{in: "", want: Query{}},
+		{in: "two factor", want: Query{Clauses: []Clause{
+			{Terms: []Term{{Text: "two"}}},
+			{Terms: []Term{{Text: "factor"}}},
+		}}},
+		{in: `"two factor" codes`, want: Query{Clauses: []Clause{
+			{Terms: []Term{{Text: "two factor", Phrase: true}}},
+			{Terms: []Term{{Text: "codes"}}},
+		}}},
+		{in: "yuri OR drama", want: Query{Clauses: []Clause{
+			{Terms: []Term{{Text: "yuri"}, {Text: "drama"}}},
+		}}},
+		{in: `drama not "girls love"`, want: Query{Clauses: []Clause{
+			{Terms: []Term{{Text: "drama"}}},
+			{Terms: []Term{{Text: "girls love", Phrase: true}}, Exclude: true},
+		}}},
+		{in: `title:"blue sky" tags:yuri`, want: Query{Clauses: []Clause{
+			{Terms: []Term{{Field: "title", Text: "blue sky", Phrase: true}}},
+			{Terms: []Term{{Field: "tags", Text: "yuri"}}},
+		}}},
+		// Tokens are kept as typed; punctuation alone is dropped.
+		{in: "c++ 2.0 r&b ?", want: Query{Clauses: []Clause{
+			{Terms: []Term{{Text: "c++"}}},
+			{Terms: []Term{{Text: "2.0"}}},
+			{Terms: []Term{{Text: "r&b"}}},
+		}}},
+		// Leading '-' is punctuation; unknown fields and dangling operators
+		// are plain words or dropped.
+		{in: "-factor", want: Query{Clauses: []Clause{{Terms: []Term{{Text: "factor"}}}}}},
+		{in: "re:zero", want: Query{Clauses: []Clause{{Terms: []Term{{Text: "re:zero"}}}}}},
+		{in: "or drama not", want: Query{Clauses: []Clause{
+			{Terms: []Term{{Text: "drama"}}},
+			{Terms: []Term{{Text: "not"}}},
+		}}},
+		// Unbalanced quotes run to the end.
+		{in: `"two  factor`, want: Query{Clauses: []Clause{{Terms: []Term{{Text: "two factor", Phrase: true}}}}}},
+	}
+	for _, tc := range cases {
+		if got := ParseQuery(tc.in); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("ParseQuery(%q) = %+v; want %+v", tc.in, got, tc.want)
+		}
+	}
+}
+
+// tsquerySQL compiles q with config "cfg" and $n placeholders, returning the
+// bound texts.
+func tsquerySQL(q Query) (string, []string) {
+	var texts []string
+	sql := q.TSQuerySQL("cfg", func(text string) string {
+		texts = append(texts, text)
+		return fmt.Sprintf("$%d", len(texts))
+	})
+	return sql, texts
+}
+
+func TestQueryTSQuerySQL(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		in    string
+		want  string
+		texts []string
+	}{
+		{in: "", want: ""},
+		{in: "not drama", want: ""},
+		{in: "two-factor", want: "(plainto_tsquery(cfg, $1) && plainto_tsquery(cfg, $2))", texts: []string{"two", "factor"}},
+		{in: `"two factor" or 2fa`, want: "(phraseto_tsquery(cfg, $1) || plainto_tsquery(cfg, $2))", texts: []string{"two factor", "2fa"}},
+		{in: `drama not "girls love"`, want: "(plainto_tsquery(cfg, $1) && !!(phraseto_tsquery(cfg, $2)))", texts: []string{"drama", "girls love"}},
+		// The config's parser splits terms into lexemes, as it did documents.
+		{in: "c++ 2.0", want: "(plainto_tsquery(cfg, $1) && plainto_tsquery(cfg, $2))", texts: []string{"c++", "2.0"}},
+		{in: "what's new?", want: "(plainto_tsquery(cfg, $1) && plainto_tsquery(cfg, $2))", texts: []string{"what's", "new?"}},
+		{
+			in:    "title:sky",
+			want:  `regexp_replace(plainto_tsquery(cfg, $1)::text, '''(?:[^''\\]|''''|\\.)*''', '\&:A', 'g')::tsquery`,
+			texts: []string{"sky"},
+		},
+	}
+	for _, tc := range cases {
+		got, texts := tsquerySQL(ParseQuery(tc.in))
+		if got != tc.want || !reflect.DeepEqual(texts, tc.texts) {
+			t.Fatalf("ParseQuery(%q).TSQuerySQL() = %q %q; want %q %q", tc.in, got, texts, tc.want, tc.texts)
+		}
+	}
+}
+
+func TestQueryPGroonga(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"":                "",
+		"not 東京":          "",
+		"東京 タワー":          `"東京" "タワー"`,
+		"東京 or 大阪":        `("東京" OR "大阪")`,
+		`"東京 タワー" not 夜景`: `"東京 タワー" -"夜景"`,
+		`not "a b" c`:     `"c" -("a b")`,
+		"title:東京":        `"東京"`,
+	}
+	for in, want := range cases {
+		if got := ParseQuery(in).PGroonga(); got != want {
+			t.Fatalf("ParseQuery(%q).PGroonga() = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestQueryExclusions(t *testing.T) {
+	t.Parallel()
+
+	q := ParseQuery("drama not yuri not romance")
+	if !q.HasPositive() {
+		t.Fatalf("expected a positive clause")
+	}
+	if got, want := q.Exclusions().AnyOf().PositiveText(), "yuri romance"; got != want {
+		t.Fatalf("Exclusions().AnyOf().PositiveText() = %q; want %q", got, want)
+	}
+	if got, _ := tsquerySQL(q.Exclusions().AnyOf()); got != "(plainto_tsquery(cfg, $1) || plainto_tsquery(cfg, $2))" {
+		t.Fatalf("Exclusions().AnyOf() is not one OR clause: %q", got)
+	}
+	if got, want := q.TrigramProbes(0), []string{"drama"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("TrigramProbes = %v; want %v", got, want)
+	}
+	if got := ParseQuery("drama").Exclusions(); len(got.Clauses) != 0 {
+		t.Fatalf("expected no exclusions, got %+v", got)
+	}
+}
+
+func TestQueryPositiveTextAndScopes(t *testing.T) {
+	t.Parallel()
+
+	q := ParseQuery(`title:"blue archive" or ba not tag:yuri school`)
+	if got, want := q.PositiveText(), "blue archive ba school"; got != want {
+		t.Fatalf("PositiveText() = %q; want %q", got, want)
+	}
+	if got, want := ParseQuery(`what's new? not c++`).PositiveText(), "what's new?"; got != want {
+		t.Fatalf("PositiveText() = %q; want %q", got, want)
+	}
+	if !q.HasFieldScopes() {
+		t.Fatalf("expected field scopes")
+	}
+	flat := q.Unscoped()
+	if flat.HasFieldScopes() {
+		t.Fatalf("Unscoped() kept field scopes: %+v", flat)
+	}
+	want := "((phraseto_tsquery(cfg, $1) || plainto_tsquery(cfg, $2)) && !!(plainto_tsquery(cfg, $3)) && plainto_tsquery(cfg, $4))"
+	if got, _ := tsquerySQL(flat); got != want {
+		t.Fatalf("Unscoped().TSQuerySQL() = %q; want %q", got, want)
+	}
+	if q.Clauses[0].Terms[0].Field != "title" {
+		t.Fatalf("Unscoped() modified the original query")
+	}
+}
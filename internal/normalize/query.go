@@ -59,42 +59,3 @@ func QueryForEmbedding(input string) string {
 	}
 	return strings.Join(out, " ")
 }
-
-// QueryForFTS returns a cleaned query string intended for Postgres
-// websearch_to_tsquery.
-//
-// Behavior:
-//   - Hyphenated tokens are normalized (e.g. "two-factor" -> "two factor").
-//   - Leading '-' is treated as punctuation and removed from user input
-//     (e.g. "-factor" behaves like "factor").
-//   - Natural language negation "not X" is converted to "-X" for FTS only.
-//
-// NOTE: This only supports single-token negation (next whitespace-delimited
-// token). Quoted phrase negation (e.g. `not "two factor"`) is not handled.
-func QueryForFTS(input string) string {
-	q := normalizeIntraTokenHyphens(strings.TrimSpace(input))
-	if q == "" {
-		return ""
-	}
-
-	parts := strings.Fields(q)
-	out := make([]string, 0, len(parts))
-	for i := 0; i < len(parts); i++ {
-		p := stripLeadingHyphens(parts[i])
-		if p == "" {
-			continue
-		}
-		if strings.EqualFold(p, "not") && i+1 < len(parts) {
-			next := stripLeadingHyphens(parts[i+1])
-			i++
-			if next == "" {
-				continue
-			}
-			out = append(out, "-"+next)
-			continue
-		}
-		out = append(out, p)
-	}
-
-	return strings.Join(out, " ")
-}
@@ -0,0 +1,120 @@
+package normalize
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Synonyms maps a synonym key (see SynonymKey) to the alternatives it expands
+// to. Alternatives are plain query text ("girls love").
+type Synonyms map[string][]string
+
+// SynonymKey normalizes a term for Synonyms lookups: lowercased words with
+// surrounding punctuation removed, joined by single spaces.
+func SynonymKey(term string) string {
+	parts := strings.Fields(strings.ToLower(QueryForEmbedding(term)))
+	out := parts[:0]
+	for _, p := range parts {
+		if p = trimNonWord(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// KeyWord is a word of SynonymKey(q) and the byte span of q it came from.
+type KeyWord struct {
+	Word       string
+	Start, End int
+}
+
+// KeyWords splits q like SynonymKey does, keeping each word's span in q, so
+// that a match on the key can be mapped back to the text around it (quotes,
+// operators). q should already be QueryForEmbedding output, whose key equals
+// the key of the raw query.
+func KeyWords(q string) []KeyWord {
+	var out []KeyWord
+	for i := 0; i < len(q); {
+		r, size := utf8.DecodeRuneInString(q[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		j := i
+		for j < len(q) {
+			r, size := utf8.DecodeRuneInString(q[j:])
+			if unicode.IsSpace(r) {
+				break
+			}
+			j += size
+		}
+		field := q[i:j]
+		core := trimNonWord(field)
+		if core != "" {
+			start := i + strings.Index(field, core)
+			out = append(out, KeyWord{Word: strings.ToLower(core), Start: start, End: start + len(core)})
+		}
+		i = j
+	}
+	return out
+}
+
+func trimNonWord(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool { return !isLetterOrNumber(r) })
+}
+
+// maxWords returns the word count of the longest key in s.
+func (s Synonyms) maxWords() int {
+	n := 0
+	for k := range s {
+		if w := len(strings.Fields(k)); w > n {
+			n = w
+		}
+	}
+	return n
+}
+
+type synonymMatch struct {
+	start, end   int // token range [start, end)
+	alternatives []string
+}
+
+// matchSynonyms finds non-overlapping synonym keys in tokens, preferring the
+// longest match at each position. Tokens for which skip returns true are never
+// part of a match.
+func matchSynonyms(tokens []string, syn Synonyms, skip func(i int) bool) []synonymMatch {
+	if len(syn) == 0 {
+		return nil
+	}
+	maxWords := syn.maxWords()
+	var out []synonymMatch
+	for i := 0; i < len(tokens); {
+		matched := false
+		for n := min(maxWords, len(tokens)-i); n > 0; n-- {
+			words := make([]string, 0, n)
+			ok := true
+			for j := i; j < i+n; j++ {
+				w := trimNonWord(strings.ToLower(tokens[j]))
+				if w == "" || (skip != nil && skip(j)) {
+					ok = false
+					break
+				}
+				words = append(words, w)
+			}
+			if !ok {
+				continue
+			}
+			if alts := syn[strings.Join(words, " ")]; len(alts) > 0 {
+				out = append(out, synonymMatch{start: i, end: i + n, alternatives: alts})
+				i += n
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			i++
+		}
+	}
+	return out
+}
@@ -0,0 +1,114 @@
+package normalize
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSynonymKey(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"  Girls   Love ": "girls love",
+		"Yuri!":           "yuri",
+		"two-factor":      "two factor",
+		"...":             "",
+	}
+	for in, want := range cases {
+		if got := SynonymKey(in); got != want {
+			t.Fatalf("SynonymKey(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestKeyWords(t *testing.T) {
+	t.Parallel()
+
+	q := `"One Piece" not title:Luffy ...`
+	got := KeyWords(q)
+	want := []KeyWord{
+		{Word: "one", Start: 1, End: 4},
+		{Word: "piece", Start: 5, End: 10},
+		{Word: "not", Start: 12, End: 15},
+		{Word: "title:luffy", Start: 16, End: 27},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("KeyWords = %+v; want %+v", got, want)
+	}
+	var words []string
+	for _, w := range got {
+		words = append(words, w.Word)
+	}
+	if key := strings.Join(words, " "); key != SynonymKey(q) {
+		t.Fatalf("KeyWords joined = %q; want SynonymKey %q", key, SynonymKey(q))
+	}
+}
+
+func TestQueryExpandSynonyms(t *testing.T) {
+	t.Parallel()
+
+	syn := Synonyms{
+		"yuri":        {"girls love"},
+		"girls love":  {"yuri"},
+		"school":      {"academy"},
+		"two factor":  {"2fa"},
+		"not matched": {"x"},
+	}
+	cases := []struct {
+		in   string
+		want [][]string // texts of the positive clauses' alternatives
+	}{
+		{in: "", want: nil},
+		{in: "romance", want: [][]string{{"romance"}}},
+		{in: "Yuri romance", want: [][]string{{"Yuri", "girls love"}, {"romance"}}},
+		// Longest match wins.
+		{in: "girls love school", want: [][]string{{"girls love", "yuri"}, {"school", "academy"}}},
+		{in: "two-factor codes", want: [][]string{{"two factor", "2fa"}, {"codes"}}},
+		// Phrases, exclusions and OR clauses are kept literal.
+		{in: `"yuri school" drama`, want: [][]string{{"yuri school"}, {"drama"}}},
+		{in: "drama not yuri", want: [][]string{{"drama"}}},
+		{in: "yuri or drama", want: [][]string{{"yuri", "drama"}}},
+	}
+	for _, tc := range cases {
+		var got [][]string
+		for _, c := range ParseQuery(tc.in).ExpandSynonyms(syn).Clauses {
+			if c.Exclude {
+				continue
+			}
+			var alts []string
+			for _, term := range c.Terms {
+				alts = append(alts, term.Text)
+			}
+			got = append(got, alts)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("ExpandSynonyms(%q) = %q; want %q", tc.in, got, tc.want)
+		}
+	}
+
+	q := ParseQuery("two-factor")
+	if got := q.ExpandSynonyms(nil); !reflect.DeepEqual(got, q) {
+		t.Fatalf("expected no expansion without synonyms, got %+v", got)
+	}
+}
+
+func TestQueryTrigramProbes_Synonyms(t *testing.T) {
+	t.Parallel()
+
+	syn := Synonyms{
+		"yuri":   {"girls love", "shoujo ai"},
+		"school": {"academy"},
+	}
+	q := ParseQuery("yuri school").ExpandSynonyms(syn)
+	want := []string{"yuri school", "girls love school", "shoujo ai school", "yuri academy"}
+	if got := q.TrigramProbes(0); !reflect.DeepEqual(got, want) {
+		t.Fatalf("TrigramProbes = %v; want %v", got, want)
+	}
+	if got := q.TrigramProbes(2); len(got) != 2 {
+		t.Fatalf("expected probes capped at 2, got %v", got)
+	}
+	if got := ParseQuery("romance").ExpandSynonyms(syn).TrigramProbes(0); !reflect.DeepEqual(got, []string{"romance"}) {
+		t.Fatalf("expected only the base probe, got %v", got)
+	}
+}
@@ -0,0 +1,102 @@
+package searchkit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// languageChain is a resolved language mode: the languages to query, in
+// priority order (requested language first).
+type languageChain struct {
+	languages []string
+	// tiered ranks every hit of languages[i] ahead of hits of languages[i+1].
+	tiered bool
+	// onShortfall queries languages[i+1] only while fewer than minHits hits
+	// were found in languages[:i+1].
+	onShortfall bool
+	minHits     int
+}
+
+// resolveLanguageChain resolves language, mode and the fallback list of an
+// options struct. The legacy modes keep their meaning (fallback_en fuses both
+// languages together, weighted by FallbackLanguageWeight).
+func resolveLanguageChain(language string, mode LanguageMode, fallbacks []string, minHits int) (languageChain, error) {
+	switch mode {
+	case LanguageModeFallback, LanguageModeFallbackOnShortfall:
+	default:
+		languages, err := resolveLanguageModes(language, mode)
+		if err != nil {
+			return languageChain{}, err
+		}
+		return languageChain{languages: languages}, nil
+	}
+
+	lang := strings.ToLower(strings.TrimSpace(language))
+	if lang == "" {
+		lang = "en"
+	}
+	chain := languageChain{
+		languages:   []string{lang},
+		tiered:      true,
+		onShortfall: mode == LanguageModeFallbackOnShortfall,
+		minHits:     minHits,
+	}
+	seen := map[string]struct{}{lang: {}}
+	for _, l := range fallbacks {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if l == "" {
+			continue
+		}
+		if _, ok := seen[l]; ok {
+			continue
+		}
+		seen[l] = struct{}{}
+		chain.languages = append(chain.languages, l)
+	}
+	if len(cloneAndTrim(fallbacks)) == 0 {
+		return languageChain{}, fmt.Errorf("fallback languages are required for language mode %q", mode)
+	}
+	if minHits < 0 {
+		return languageChain{}, fmt.Errorf("fallback min hits must be >= 0")
+	}
+	return chain, nil
+}
+
+// fingerprint renders the parts of the chain that affect result ordering.
+func (c languageChain) fingerprint() string {
+	return fmt.Sprintf("%s|%t|%t|%d", strings.Join(c.languages, ","), c.tiered, c.onShortfall, c.minHits)
+}
+
+// tiers groups languages into the batches that are queried together: one batch
+// for all languages, or one per language when fallbacks depend on shortfall.
+func (c languageChain) tiers() [][]string {
+	if !c.onShortfall {
+		return [][]string{c.languages}
+	}
+	out := make([][]string, len(c.languages))
+	for i, l := range c.languages {
+		out[i] = []string{l}
+	}
+	return out
+}
+
+// shortfallThreshold is the number of hits below which the next fallback
+// language is queried. It never exceeds depth, so a full page of primary hits
+// never triggers fallbacks; combined with tiered ranking this keeps earlier
+// pages stable when later pages pull in fallback languages.
+func (c languageChain) shortfallThreshold(depth int) int {
+	if c.minHits > 0 && c.minHits < depth {
+		return c.minHits
+	}
+	return depth
+}
+
+// rank returns language's position in the chain (len(languages) if absent).
+func (c languageChain) rank(language string) int {
+	for i, l := range c.languages {
+		if l == language {
+			return i
+		}
+	}
+	return len(c.languages)
+}
@@ -0,0 +1,24 @@
+-- searchkit: shared query embedding cache.
+--
+-- Query vectors are cached per (model, normalized query) so several app
+-- instances can reuse provider results for popular queries. Rows carry their
+-- own expiry; expired rows are ignored on read and can be pruned with
+-- pg.QueryVectorCache.DeleteExpired. Embeddings are stored at full
+-- precision (vector), so cache hits return the provider's vectors unrounded.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS query_embedding_cache (
+    model text NOT NULL,
+    query text NOT NULL,
+    embedding vector NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (model, query)
+);
+
+CREATE INDEX IF NOT EXISTS idx_query_embedding_cache_expires_at
+    ON query_embedding_cache(expires_at);
+
+COMMIT;
@@ -0,0 +1,20 @@
+-- searchkit: index generation counter for result-cache invalidation.
+--
+-- The worker bumps `generation` whenever it writes or deletes search
+-- documents or embeddings. searchkit.Client includes the current generation
+-- in result-cache keys, so cached Search/Typeahead results from before the
+-- write are no longer served.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS search_generation (
+    id boolean PRIMARY KEY DEFAULT true CHECK (id),
+    generation bigint NOT NULL DEFAULT 0,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO search_generation (id, generation)
+VALUES (true, 0)
+ON CONFLICT (id) DO NOTHING;
+
+COMMIT;
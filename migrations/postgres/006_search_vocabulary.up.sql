@@ -0,0 +1,141 @@
+-- searchkit: per-language vocabulary for spelling suggestions.
+--
+-- `search_vocabulary` holds every term of the stored lexical documents with the
+-- number of documents containing it. Terms are the lexemes of the raw
+-- document under the `simple` config (lowercased surface words, so
+-- suggestions are real words rather than stems) plus the heavy-normalized
+-- tokens of `document` (what trigram search matches on).
+--
+-- Counts are maintained by statement-level triggers on `search_documents`, so
+-- they follow the worker's upserts and deletes without extra round trips.
+-- pg.RebuildSearchVocabulary recomputes the table from scratch.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS search_vocabulary (
+    language text NOT NULL,
+    term text NOT NULL,
+    doc_count bigint NOT NULL DEFAULT 0,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (language, term)
+);
+
+-- Fuzzy lookups (Client.Suggest).
+CREATE INDEX IF NOT EXISTS idx_search_vocabulary_term_trgm
+    ON search_vocabulary USING gin (term gin_trgm_ops);
+
+-- Terms whose count dropped to zero are pruned after each statement.
+CREATE INDEX IF NOT EXISTS idx_search_vocabulary_empty
+    ON search_vocabulary (language, term)
+ WHERE doc_count <= 0;
+
+CREATE OR REPLACE FUNCTION searchkit_document_terms(raw_document text, document text)
+RETURNS text[]
+LANGUAGE sql
+IMMUTABLE
+AS $$
+    SELECT coalesce(array_agg(DISTINCT t.term), '{}')
+    FROM (
+        SELECT unnest(tsvector_to_array(to_tsvector('simple', coalesce(raw_document, '')))) AS term
+        UNION
+        SELECT unnest(string_to_array(coalesce(document, ''), ' '))
+    ) t
+    WHERE char_length(t.term) BETWEEN 2 AND 64;
+$$;
+
+-- One function serves the INSERT/UPDATE/DELETE triggers; only the transition
+-- tables that exist for TG_OP are referenced.
+CREATE OR REPLACE FUNCTION searchkit_search_vocabulary_sync()
+RETURNS trigger
+LANGUAGE plpgsql
+SET search_path FROM CURRENT
+AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO search_vocabulary AS v (language, term, doc_count, updated_at)
+        SELECT n.language, t.term, count(*), now()
+        FROM new_rows n
+        CROSS JOIN LATERAL unnest(searchkit_document_terms(n.raw_document, n.document)) AS t(term)
+        GROUP BY n.language, t.term
+        ON CONFLICT (language, term) DO UPDATE SET
+            doc_count = v.doc_count + EXCLUDED.doc_count,
+            updated_at = now();
+    ELSIF TG_OP = 'UPDATE' THEN
+        -- Only rows whose indexed text changed are re-tokenized: the worker
+        -- rewrites documents (and attributes) that are mostly unchanged. Rows
+        -- whose key changed have no counterpart and count as changed.
+        WITH changed AS (
+            SELECT o.language AS old_language, o.raw_document AS old_raw_document, o.document AS old_document,
+                   n.language AS new_language, n.raw_document AS new_raw_document, n.document AS new_document
+            FROM old_rows o
+            FULL JOIN new_rows n
+              ON n.entity_type = o.entity_type
+             AND n.entity_id = o.entity_id
+             AND n.language = o.language
+            WHERE o.entity_id IS NULL
+               OR n.entity_id IS NULL
+               OR (n.raw_document, n.document) IS DISTINCT FROM (o.raw_document, o.document)
+        )
+        INSERT INTO search_vocabulary AS v (language, term, doc_count, updated_at)
+        SELECT d.language, d.term, sum(d.delta), now()
+        FROM (
+            SELECT c.new_language AS language, t.term, 1 AS delta
+            FROM changed c
+            CROSS JOIN LATERAL unnest(searchkit_document_terms(c.new_raw_document, c.new_document)) AS t(term)
+            WHERE c.new_language IS NOT NULL
+            UNION ALL
+            SELECT c.old_language, t.term, -1 AS delta
+            FROM changed c
+            CROSS JOIN LATERAL unnest(searchkit_document_terms(c.old_raw_document, c.old_document)) AS t(term)
+            WHERE c.old_language IS NOT NULL
+        ) d
+        GROUP BY d.language, d.term
+        HAVING sum(d.delta) <> 0
+        ON CONFLICT (language, term) DO UPDATE SET
+            doc_count = v.doc_count + EXCLUDED.doc_count,
+            updated_at = now();
+    ELSE
+        UPDATE search_vocabulary v
+        SET doc_count = v.doc_count - d.n,
+            updated_at = now()
+        FROM (
+            SELECT o.language, t.term, count(*) AS n
+            FROM old_rows o
+            CROSS JOIN LATERAL unnest(searchkit_document_terms(o.raw_document, o.document)) AS t(term)
+            GROUP BY o.language, t.term
+        ) d
+        WHERE v.language = d.language AND v.term = d.term;
+    END IF;
+
+    DELETE FROM search_vocabulary WHERE doc_count <= 0;
+    RETURN NULL;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS trg_search_documents_vocabulary_insert ON search_documents;
+CREATE TRIGGER trg_search_documents_vocabulary_insert
+    AFTER INSERT ON search_documents
+    REFERENCING NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION searchkit_search_vocabulary_sync();
+
+DROP TRIGGER IF EXISTS trg_search_documents_vocabulary_update ON search_documents;
+CREATE TRIGGER trg_search_documents_vocabulary_update
+    AFTER UPDATE ON search_documents
+    REFERENCING OLD TABLE AS old_rows NEW TABLE AS new_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION searchkit_search_vocabulary_sync();
+
+DROP TRIGGER IF EXISTS trg_search_documents_vocabulary_delete ON search_documents;
+CREATE TRIGGER trg_search_documents_vocabulary_delete
+    AFTER DELETE ON search_documents
+    REFERENCING OLD TABLE AS old_rows
+    FOR EACH STATEMENT EXECUTE FUNCTION searchkit_search_vocabulary_sync();
+
+-- Seed from existing documents.
+INSERT INTO search_vocabulary (language, term, doc_count)
+SELECT sd.language, t.term, count(*)
+FROM search_documents sd
+CROSS JOIN LATERAL unnest(searchkit_document_terms(sd.raw_document, sd.document)) AS t(term)
+GROUP BY sd.language, t.term
+ON CONFLICT (language, term) DO NOTHING;
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- searchkit: prefix indexes on `search_vocabulary` for term completion.
+--
+-- Client.CompleteTerms completes the word being typed from the vocabulary
+-- (migration 006):
+-- - `LIKE 'prefix%'` lookups use a text_pattern_ops btree (collation-independent).
+-- - Native-script CJK prefixes use PGroonga's prefix operator (`&^`), which
+--   needs the term_search opclass. Migration 003 is optional, so that index is
+--   only created when the pgroonga extension is installed.
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS idx_search_vocabulary_term_prefix
+    ON search_vocabulary (language, term text_pattern_ops);
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pgroonga') THEN
+        EXECUTE $sql$
+            CREATE INDEX IF NOT EXISTS idx_search_vocabulary_term_pgroonga_cjk
+                ON search_vocabulary
+             USING pgroonga (term pgroonga_text_term_search_ops_v2)
+             WHERE language IN ('ja', 'zh', 'ko')
+        $sql$;
+    END IF;
+END;
+$$;
+
+COMMIT;
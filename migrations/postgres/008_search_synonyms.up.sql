@@ -0,0 +1,23 @@
+-- searchkit: per-language synonym dictionaries for query expansion.
+--
+-- `term` is stored in normalized form (lowercased words separated by single
+-- spaces; see pg.UpsertSynonym). A one-way entry expands `term` to
+-- `synonyms`; a two-way entry makes `term` and every synonym expand to each
+-- other.
+--
+-- Managed through pg.UpsertSynonym / pg.DeleteSynonym, which bump
+-- `search_generation` so clients reload dictionaries and drop cached results.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS search_synonyms (
+    language text NOT NULL,
+    term text NOT NULL,
+    synonyms text[] NOT NULL,
+    mode text NOT NULL DEFAULT 'two_way' CHECK (mode IN ('one_way', 'two_way')),
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (language, term)
+);
+
+COMMIT;
@@ -0,0 +1,58 @@
+-- searchkit: table-driven language -> FTS regconfig mapping.
+--
+-- Replaces the hard-coded CASE in `searchkit_regconfig_for_language` (002)
+-- with a lookup in `search_language_configs`; unmapped languages still use
+-- `simple`. Hosts register languages (including custom configs such as
+-- `english_unaccent`) with pg.RegisterLanguageConfig.
+--
+-- A mapping change only affects new writes and queries by itself, so it also
+-- queues the language in `search_tsv_reindex`; the worker recomputes the
+-- stored `tsv` of that language in bounded batches, walking
+-- (entity_type, entity_id) from the cursor.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS search_language_configs (
+    language text PRIMARY KEY,
+    config text NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS search_tsv_reindex (
+    language text PRIMARY KEY,
+    cursor_entity_type text,
+    cursor_entity_id text,
+    requested_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Previous built-in mapping.
+INSERT INTO search_language_configs (language, config)
+VALUES
+    ('en', 'english'),
+    ('es', 'spanish'),
+    ('fr', 'french'),
+    ('de', 'german'),
+    ('it', 'italian'),
+    ('pt', 'portuguese'),
+    ('ru', 'russian')
+ON CONFLICT (language) DO NOTHING;
+
+-- STABLE (not IMMUTABLE) now that it reads a table; `search_path` is pinned
+-- so callers outside the host schema resolve the table and custom configs.
+CREATE OR REPLACE FUNCTION searchkit_regconfig_for_language(lang text)
+RETURNS regconfig
+LANGUAGE sql
+STABLE
+SET search_path FROM CURRENT
+AS $$
+    SELECT coalesce(
+        (SELECT c.config::regconfig
+         FROM search_language_configs c
+         WHERE c.language = lower(trim(coalesce(lang, '')))),
+        'simple'::regconfig
+    );
+$$;
+
+COMMIT;
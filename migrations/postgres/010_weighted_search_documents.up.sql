@@ -0,0 +1,47 @@
+-- searchkit: field-weighted lexical documents.
+--
+-- Hosts may return structured lexical documents (runtime.BuildLexicalDocument)
+-- whose fields carry a weight A-D (conventionally title, aliases, tags, body).
+-- searchkit stores:
+--   - `fields`: the weighted fields, as [{"weight": "A", "text": "..."}, ...]
+--   - `raw_title` / `title`: the A-weight text (raw, and heavy-normalized for
+--     trigram), used to boost title matches in trigram and PGroonga search.
+-- `raw_document` / `document` keep holding the full text, so nothing else
+-- changes. Flat string documents leave the new columns NULL.
+
+BEGIN;
+
+ALTER TABLE search_documents
+    ADD COLUMN IF NOT EXISTS fields jsonb,
+    ADD COLUMN IF NOT EXISTS raw_title text,
+    ADD COLUMN IF NOT EXISTS title text;
+
+CREATE INDEX IF NOT EXISTS idx_search_documents_title_trgm
+    ON search_documents USING gin (title gin_trgm_ops)
+ WHERE title IS NOT NULL;
+
+-- tsv for a stored document: setweight per field weight when `fields` is
+-- present, otherwise the unweighted vector of `raw_document` (as before).
+CREATE OR REPLACE FUNCTION searchkit_document_tsv(lang text, raw_document text, fields jsonb)
+RETURNS tsvector
+LANGUAGE sql
+STABLE
+SET search_path FROM CURRENT
+AS $$
+    SELECT CASE
+        WHEN fields IS NULL OR jsonb_typeof(fields) <> 'array' OR jsonb_array_length(fields) = 0 THEN
+            to_tsvector(searchkit_regconfig_for_language(lang), coalesce(raw_document, ''))
+        ELSE (
+            SELECT
+                setweight(to_tsvector(cfg, coalesce(string_agg(f->>'text', ' ') FILTER (WHERE f->>'weight' = 'A'), '')), 'A') ||
+                setweight(to_tsvector(cfg, coalesce(string_agg(f->>'text', ' ') FILTER (WHERE f->>'weight' = 'B'), '')), 'B') ||
+                setweight(to_tsvector(cfg, coalesce(string_agg(f->>'text', ' ') FILTER (WHERE f->>'weight' = 'C'), '')), 'C') ||
+                setweight(to_tsvector(cfg, coalesce(string_agg(f->>'text', ' ') FILTER (WHERE coalesce(f->>'weight', 'D') NOT IN ('A', 'B', 'C')), '')), 'D')
+            FROM jsonb_array_elements(fields) AS f,
+                 (SELECT searchkit_regconfig_for_language(lang) AS cfg) c
+            GROUP BY c.cfg
+        )
+    END;
+$$;
+
+COMMIT;
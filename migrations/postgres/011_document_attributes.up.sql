@@ -0,0 +1,27 @@
+-- searchkit: filterable document attributes.
+--
+-- Hosts may return a flat attribute map per entity (runtime.BuildAttributes,
+-- e.g. {"status": "live", "rating": 4, "artist_id": "a1"}). searchkit stores
+-- it on both search_documents and embedding_vectors so attribute filters
+-- (search.AttrEq/AttrIn/AttrRange/AttrExists) run inside retrieval without
+-- joining host tables.
+--
+-- The GIN indexes serve equality filters (`attributes @> ...`). Range filters
+-- compare `attributes -> 'key'`; pg.EnsureAttributeIndex creates btree
+-- expression indexes for the keys a host filters on.
+
+BEGIN;
+
+ALTER TABLE search_documents
+    ADD COLUMN IF NOT EXISTS attributes jsonb;
+
+ALTER TABLE embedding_vectors
+    ADD COLUMN IF NOT EXISTS attributes jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_search_documents_attributes
+    ON search_documents USING gin (attributes jsonb_path_ops);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_vectors_attributes
+    ON embedding_vectors USING gin (attributes jsonb_path_ops);
+
+COMMIT;
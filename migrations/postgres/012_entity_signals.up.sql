@@ -0,0 +1,22 @@
+-- searchkit: per-entity ranking signals for recency/popularity boosting.
+--
+-- Hosts may return a static rank (popularity: views, likes, ...) and a
+-- timestamp (published / live at) per entity (runtime.BuildSignals, or
+-- pg.UpsertSignals directly). Client.Search loads them for the fused
+-- candidates when SearchOptions.Boosts is set and combines exponential time
+-- decay and log popularity with the RRF score.
+--
+-- Signals are language-independent, so they are keyed by entity only.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS entity_signals (
+    entity_type text NOT NULL,
+    entity_id text NOT NULL,
+    static_rank double precision NOT NULL DEFAULT 0 CHECK (static_rank >= 0),
+    signal_at timestamptz,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (entity_type, entity_id)
+);
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- searchkit: query rules (merchandising).
+--
+-- A rule matches the normalized query (lowercased words separated by single
+-- spaces; see pg.UpsertSearchRule) exactly, by prefix or by regex, optionally
+-- for one language only, and carries a list of actions:
+--   [{"type": "pin", "entity_type": "gallery", "entity_id": "42", "position": 1},
+--    {"type": "hide", "entity_type": "gallery", "entity_id": "7"}, ...]
+-- Client.Search and Client.Typeahead apply the actions of matching rules to
+-- the fused ranking.
+--
+-- Managed through pg.UpsertSearchRule / pg.DeleteSearchRule, which bump
+-- `search_generation` so clients reload rules and drop cached results.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS search_rules (
+    id text PRIMARY KEY,
+    language text NOT NULL DEFAULT '',
+    match_type text NOT NULL CHECK (match_type IN ('exact', 'prefix', 'regex')),
+    pattern text NOT NULL,
+    actions jsonb NOT NULL DEFAULT '[]',
+    priority integer NOT NULL DEFAULT 0,
+    enabled boolean NOT NULL DEFAULT true,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMIT;
@@ -0,0 +1,156 @@
+package searchkit
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidPageToken is returned when a PageToken cannot be decoded or was
+// issued for a different query/options combination.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// maxPageDepth bounds how deep pagination can go (offset + page size). Each
+// page re-runs retrieval at depth offset+limit, so unbounded paging would turn
+// into unbounded backend scans.
+const maxPageDepth = 1000
+
+const pageTokenVersion = 1
+
+// pageCursor is the decoded form of an opaque page token.
+//
+// Offset is the position (in the ordered result list) right after the last
+// hit of the previous page. The last hit's key is kept as well so the next
+// page can resume after it even if the index changed and the hit moved.
+//
+// Clock pins "now" (Unix seconds) for rankings that depend on it (a recency
+// boost without an Origin), so every page is ranked against the first page's
+// clock.
+type pageCursor struct {
+	Version     int    `json:"v"`
+	Fingerprint string `json:"f"`
+	Offset      int    `json:"o"`
+	LastType    string `json:"t,omitempty"`
+	LastID      string `json:"i,omitempty"`
+	LastLang    string `json:"l,omitempty"`
+	Clock       int64  `json:"c,omitempty"`
+}
+
+type hitKey struct {
+	EntityType string
+	EntityID   string
+	Language   string
+}
+
+func (c pageCursor) last() (hitKey, bool) {
+	if c.LastType == "" && c.LastID == "" {
+		return hitKey{}, false
+	}
+	return hitKey{EntityType: c.LastType, EntityID: c.LastID, Language: c.LastLang}, true
+}
+
+func encodePageToken(c pageCursor) string {
+	c.Version = pageTokenVersion
+	b, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodePageToken decodes token and checks it was issued for fingerprint.
+// An empty token decodes to the first page.
+func decodePageToken(token string, fingerprint string) (pageCursor, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return pageCursor{Fingerprint: fingerprint}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	if c.Version != pageTokenVersion || c.Fingerprint != fingerprint {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	if c.Offset < 0 || c.Offset >= maxPageDepth || c.Clock < 0 {
+		return pageCursor{}, ErrInvalidPageToken
+	}
+	return c, nil
+}
+
+// paginate returns the [start, end) window of the page described by cur over
+// an ordered list of n hits.
+//
+// When the previous page's last hit is still present, the page starts right
+// after it (so hits shifting up or down between requests do not produce
+// duplicates); otherwise it falls back to the recorded offset.
+func paginate(n int, limit int, cur pageCursor, keyAt func(i int) hitKey) (start, end int) {
+	start = cur.Offset
+	if last, ok := cur.last(); ok {
+		for i := 0; i < n; i++ {
+			if keyAt(i) == last {
+				start = i + 1
+				break
+			}
+		}
+	}
+	if start > n {
+		start = n
+	}
+	end = start + limit
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+// nextPageToken returns the token for the page following [start, end) of
+// the page described by cur (whose fingerprint and clock it keeps), or ""
+// when there is nothing more to fetch.
+//
+// more reports whether results exist beyond end, either because the ordered
+// list continues or because at least one backend filled its requested depth.
+func nextPageToken(cur pageCursor, end int, limit int, last hitKey, more bool) string {
+	if !more || end <= 0 || end+limit > maxPageDepth {
+		return ""
+	}
+	return encodePageToken(pageCursor{
+		Fingerprint: cur.Fingerprint,
+		Offset:      end,
+		LastType:    last.EntityType,
+		LastID:      last.EntityID,
+		LastLang:    last.Language,
+		Clock:       cur.Clock,
+	})
+}
+
+// queryFingerprint hashes the parts of a request that determine its result
+// ordering. Page tokens are only valid for requests with the same fingerprint.
+func queryFingerprint(parts ...string) string {
+	h := sha1.Sum([]byte(strings.Join(parts, "\x1f")))
+	return hex.EncodeToString(h[:8])
+}
+
+// filterFingerprint renders FilterSQL + FilterArgs into a stable string.
+func filterFingerprint(filterSQL string, filterArgs map[string]any) string {
+	keys := make([]string, 0, len(filterArgs))
+	for k := range filterArgs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(filterSQL))
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\x1e%s=%#v", k, filterArgs[k])
+	}
+	return b.String()
+}
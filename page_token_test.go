@@ -0,0 +1,118 @@
+package searchkit
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPageToken_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	last := hitKey{EntityType: "gallery", EntityID: "42", Language: "en"}
+	tok := nextPageToken(pageCursor{Fingerprint: "fp"}, 20, 20, last, true)
+	if tok == "" {
+		t.Fatalf("expected token")
+	}
+	cur, err := decodePageToken(tok, "fp")
+	if err != nil {
+		t.Fatalf("decodePageToken: %v", err)
+	}
+	if cur.Offset != 20 {
+		t.Fatalf("expected offset 20, got %d", cur.Offset)
+	}
+	if got, ok := cur.last(); !ok || got != last {
+		t.Fatalf("expected last key %+v, got %+v", last, got)
+	}
+
+	if _, err := decodePageToken(tok, "other"); !errors.Is(err, ErrInvalidPageToken) {
+		t.Fatalf("expected ErrInvalidPageToken for fingerprint mismatch, got %v", err)
+	}
+	if _, err := decodePageToken("not-a-token!", "fp"); !errors.Is(err, ErrInvalidPageToken) {
+		t.Fatalf("expected ErrInvalidPageToken for garbage, got %v", err)
+	}
+}
+
+func TestPageToken_PinsClock(t *testing.T) {
+	t.Parallel()
+
+	last := hitKey{EntityType: "gallery", EntityID: "42", Language: "en"}
+	tok := nextPageToken(pageCursor{Fingerprint: "fp", Clock: 1700000000}, 20, 20, last, true)
+	cur, err := decodePageToken(tok, "fp")
+	if err != nil {
+		t.Fatalf("decodePageToken: %v", err)
+	}
+	if cur.Clock != 1700000000 {
+		t.Fatalf("expected clock 1700000000, got %d", cur.Clock)
+	}
+	// Later pages keep the first page's clock.
+	cur, err = decodePageToken(nextPageToken(cur, 40, 20, last, true), "fp")
+	if err != nil || cur.Clock != 1700000000 {
+		t.Fatalf("expected the clock to carry over, got %d (%v)", cur.Clock, err)
+	}
+}
+
+func TestNextPageToken_Stops(t *testing.T) {
+	t.Parallel()
+
+	last := hitKey{EntityType: "gallery", EntityID: "1"}
+	if tok := nextPageToken(pageCursor{Fingerprint: "fp"}, 10, 10, last, false); tok != "" {
+		t.Fatalf("expected no token when there is nothing more")
+	}
+	if tok := nextPageToken(pageCursor{Fingerprint: "fp"}, maxPageDepth-5, 10, last, true); tok != "" {
+		t.Fatalf("expected no token past maxPageDepth")
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	t.Parallel()
+
+	keys := []hitKey{
+		{EntityType: "g", EntityID: "a"},
+		{EntityType: "g", EntityID: "new"}, // inserted since the previous page
+		{EntityType: "g", EntityID: "b"},
+		{EntityType: "g", EntityID: "c"},
+		{EntityType: "g", EntityID: "d"},
+	}
+	keyAt := func(i int) hitKey { return keys[i] }
+
+	start, end := paginate(len(keys), 2, pageCursor{}, keyAt)
+	if start != 0 || end != 2 {
+		t.Fatalf("first page = [%d,%d); want [0,2)", start, end)
+	}
+
+	// Previous page ended at "b" (offset 2); "b" moved down by one.
+	cur := pageCursor{Offset: 2, LastType: "g", LastID: "b"}
+	start, end = paginate(len(keys), 2, cur, keyAt)
+	if start != 3 || end != 5 {
+		t.Fatalf("resumed page = [%d,%d); want [3,5)", start, end)
+	}
+
+	// Last key vanished: fall back to offset.
+	cur = pageCursor{Offset: 4, LastType: "g", LastID: "gone"}
+	start, end = paginate(len(keys), 2, cur, keyAt)
+	if start != 4 || end != 5 {
+		t.Fatalf("offset page = [%d,%d); want [4,5)", start, end)
+	}
+}
+
+func TestClientSearch_InvalidPageToken(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{
+		Pool:   newTestPool(t),
+		Schema: "test",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	_, err = client.Search(context.Background(), "two factor", SearchOptions{
+		Mode:               SearchModeLexical,
+		LexicalEntityTypes: []string{"gallery"},
+		PageToken:          nextPageToken(pageCursor{Fingerprint: "fp"}, 10, 10, hitKey{EntityType: "g", EntityID: "1"}, true),
+	})
+	if !errors.Is(err, ErrInvalidPageToken) {
+		t.Fatalf("expected ErrInvalidPageToken, got %v", err)
+	}
+}
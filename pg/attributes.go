@@ -0,0 +1,163 @@
+package pg
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Attributes is a flat map of filterable document attributes (status,
+// live_at, rating, artist_id, ...). Values must be JSON-encodable; time.Time
+// values are stored as Unix seconds so they compare as numbers.
+type Attributes map[string]any
+
+// MarshalAttributes encodes attrs the way they are stored.
+func MarshalAttributes(attrs Attributes) ([]byte, error) {
+	out := make(map[string]any, len(attrs))
+	for k, v := range attrs {
+		if strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("empty attribute key")
+		}
+		switch t := v.(type) {
+		case time.Time:
+			v = t.Unix()
+		case *time.Time:
+			if t == nil {
+				v = nil
+			} else {
+				v = t.Unix()
+			}
+		}
+		out[k] = v
+	}
+	return json.Marshal(out)
+}
+
+// UpsertAttributes stores attributes for one (entity_type, language) on the
+// entities' search_documents row and on their embedding_vectors rows (all
+// models). Rows are not created; entities without rows are ignored. When a
+// row changed, the search generation is bumped once, so cached results that
+// the new attributes would filter out are not served.
+//
+// Hosts call this when only attributes change (e.g. a status flip), without
+// rebuilding documents.
+func UpsertAttributes(ctx context.Context, pool *pgxpool.Pool, schema string, entityType string, language string, attrs map[string]Attributes) error {
+	_, err := storeAttributes(ctx, pool, schema, entityType, language, attrs, true)
+	return err
+}
+
+// StoreAttributes is UpsertAttributes without the generation bump: it reports
+// whether a row changed, so callers writing several batches (the worker) bump
+// the generation once for all of them.
+func StoreAttributes(ctx context.Context, pool *pgxpool.Pool, schema string, entityType string, language string, attrs map[string]Attributes) (bool, error) {
+	return storeAttributes(ctx, pool, schema, entityType, language, attrs, false)
+}
+
+func storeAttributes(ctx context.Context, pool *pgxpool.Pool, schema string, entityType string, language string, attrs map[string]Attributes, bump bool) (bool, error) {
+	if pool == nil {
+		return false, fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(entityType) == "" {
+		return false, fmt.Errorf("entityType is required")
+	}
+	if strings.TrimSpace(language) == "" {
+		return false, fmt.Errorf("language is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return false, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	ids := make([]string, 0, len(attrs))
+	for id := range attrs {
+		if strings.TrimSpace(id) != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+	sort.Strings(ids)
+	values := make([]*string, len(ids))
+	for i, id := range ids {
+		if attrs[id] == nil {
+			continue
+		}
+		b, err := MarshalAttributes(attrs[id])
+		if err != nil {
+			return false, fmt.Errorf("attributes for %q: %w", id, err)
+		}
+		s := string(b)
+		values[i] = &s
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var changed int64
+	for _, table := range []string{searchDocumentsTable, embeddingVectorsTable} {
+		tag, err := tx.Exec(ctx, fmt.Sprintf(`
+			UPDATE %s.%s t
+			SET attributes = a.attributes::jsonb
+			FROM unnest($3::text[], $4::text[]) AS a(entity_id, attributes)
+			WHERE t.entity_type = $1
+			  AND t.language = $2
+			  AND t.entity_id = a.entity_id
+			  AND t.attributes IS DISTINCT FROM a.attributes::jsonb
+		`, qs, table), entityType, language, ids, values)
+		if err != nil {
+			return false, err
+		}
+		changed += tag.RowsAffected()
+	}
+	if changed == 0 {
+		return false, nil
+	}
+	if bump {
+		if _, err := tx.Exec(ctx, bumpSearchGenerationSQL(qs)); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit(ctx)
+}
+
+// EnsureAttributeIndex creates btree expression indexes on
+// `attributes -> key` for search_documents and embedding_vectors, for keys
+// used in range filters (search.AttrRange). Equality filters are served by
+// the GIN indexes from migration 011.
+//
+// This must NOT run inside a transaction because it uses CREATE INDEX CONCURRENTLY.
+func EnsureAttributeIndex(ctx context.Context, pool *pgxpool.Pool, schema string, key string) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	if strings.TrimSpace(key) == "" {
+		return fmt.Errorf("key is required")
+	}
+
+	h := sha1.Sum([]byte(key))
+	suffix := hex.EncodeToString(h[:8])
+	for _, table := range []string{searchDocumentsTable, embeddingVectorsTable} {
+		if _, err := pool.Exec(ctx, fmt.Sprintf(`
+			CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_%s_attr__%s
+			ON %s.%s ((attributes -> %s))
+		`, table, suffix, qs, table, quoteLiteral(key))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMarshalAttributes(t *testing.T) {
+	liveAt := time.Unix(1700000000, 0)
+	b, err := MarshalAttributes(Attributes{"status": "live", "live_at": liveAt, "hidden_at": (*time.Time)(nil), "rating": 4})
+	if err != nil {
+		t.Fatalf("MarshalAttributes: %v", err)
+	}
+	if got, want := string(b), `{"hidden_at":null,"live_at":1700000000,"rating":4,"status":"live"}`; got != want {
+		t.Fatalf("MarshalAttributes = %s; want %s", got, want)
+	}
+	if _, err := MarshalAttributes(Attributes{" ": 1}); err == nil {
+		t.Fatalf("expected an error for an empty key")
+	}
+}
+
+func TestUpsertAttributes_Integration(t *testing.T) {
+	pool := newMigratedTestPool(t, "s_attrs",
+		"005_search_generation.up.sql",
+		"011_document_attributes.up.sql",
+	)
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO s_attrs.search_documents (entity_type, entity_id, language, document) VALUES
+			('gallery', '1', 'en', 'one'),
+			('gallery', '2', 'en', 'two'),
+			('gallery', '1', 'ja', 'ichi');
+		INSERT INTO s_attrs.embedding_vectors (entity_type, entity_id, model, language) VALUES
+			('gallery', '1', 'm1', 'en'),
+			('gallery', '1', 'm2', 'en');
+	`)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	generation := func() int64 {
+		t.Helper()
+		gen, err := SearchGeneration(ctx, pool, "s_attrs")
+		if err != nil {
+			t.Fatalf("SearchGeneration: %v", err)
+		}
+		return gen
+	}
+	// attrs returns the stored attributes of entity 1 per table row.
+	attrs := func() []string {
+		t.Helper()
+		rows, err := pool.Query(ctx, `
+			SELECT coalesce(attributes::text, '') FROM s_attrs.search_documents WHERE entity_id = '1' AND language = 'en'
+			UNION ALL
+			(SELECT coalesce(attributes::text, '') FROM s_attrs.embedding_vectors WHERE entity_id = '1' ORDER BY model)
+		`)
+		if err != nil {
+			t.Fatalf("read attributes: %v", err)
+		}
+		defer rows.Close()
+		var out []string
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+
+	gen := generation()
+	err = UpsertAttributes(ctx, pool, "s_attrs", "gallery", "en", map[string]Attributes{
+		"1": {"status": "live"},
+		"2": {"status": "draft"},
+		"3": {"status": "live"}, // no rows: ignored
+	})
+	if err != nil {
+		t.Fatalf("UpsertAttributes: %v", err)
+	}
+	for _, got := range attrs() {
+		if got != `{"status": "live"}` {
+			t.Fatalf("expected every row of entity 1 to be updated, got %v", attrs())
+		}
+	}
+	// Four rows of two entities changed: one bump.
+	if next := generation(); next != gen+1 {
+		t.Fatalf("expected one generation bump, got %d -> %d", gen, next)
+	}
+
+	var ja *string
+	if err := pool.QueryRow(ctx, `SELECT attributes::text FROM s_attrs.search_documents WHERE entity_id = '1' AND language = 'ja'`).Scan(&ja); err != nil {
+		t.Fatalf("read ja: %v", err)
+	}
+	if ja != nil {
+		t.Fatalf("expected other languages to be untouched, got %s", *ja)
+	}
+
+	// Unchanged attributes: no bump.
+	gen = generation()
+	if err := UpsertAttributes(ctx, pool, "s_attrs", "gallery", "en", map[string]Attributes{"1": {"status": "live"}}); err != nil {
+		t.Fatalf("UpsertAttributes: %v", err)
+	}
+	if next := generation(); next != gen {
+		t.Fatalf("expected no bump for unchanged attributes, got %d -> %d", gen, next)
+	}
+
+	// StoreAttributes reports the change and leaves the bump to the caller.
+	changed, err := StoreAttributes(ctx, pool, "s_attrs", "gallery", "en", map[string]Attributes{"1": nil})
+	if err != nil || !changed {
+		t.Fatalf("StoreAttributes = %v, %v; want a change", changed, err)
+	}
+	if next := generation(); next != gen {
+		t.Fatalf("expected StoreAttributes not to bump, got %d -> %d", gen, next)
+	}
+	for _, got := range attrs() {
+		if got != "" {
+			t.Fatalf("expected nil attributes to clear the rows, got %v", attrs())
+		}
+	}
+}
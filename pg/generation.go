@@ -0,0 +1,57 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SearchGeneration returns the current index generation from
+// `<schema>.search_generation` (0 if the row does not exist yet).
+func SearchGeneration(ctx context.Context, pool *pgxpool.Pool, schema string) (int64, error) {
+	if pool == nil {
+		return 0, fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return 0, fmt.Errorf("invalid schema: %w", err)
+	}
+	var gen int64
+	err = pool.QueryRow(ctx, fmt.Sprintf(`
+		SELECT generation
+		FROM %s.search_generation
+		WHERE id
+	`, qs)).Scan(&gen)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return gen, err
+}
+
+// BumpSearchGeneration increments the index generation, invalidating cached
+// search results. The worker calls this after writing documents or
+// embeddings; hosts that write searchkit tables themselves should call it too.
+func BumpSearchGeneration(ctx context.Context, pool *pgxpool.Pool, schema string) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	_, err = pool.Exec(ctx, bumpSearchGenerationSQL(qs))
+	return err
+}
+
+func bumpSearchGenerationSQL(qs string) string {
+	return fmt.Sprintf(`
+		INSERT INTO %s.search_generation (id, generation, updated_at)
+		VALUES (true, 1, now())
+		ON CONFLICT (id) DO UPDATE SET
+			generation = %s.search_generation.generation + 1,
+			updated_at = now()
+	`, qs, qs)
+}
@@ -0,0 +1,75 @@
+package pg
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/open-rails/searchkit/migrations"
+)
+
+// newMigratedTestPool returns a pool whose connections resolve schema first,
+// after recreating schema with minimal search_documents and embedding_vectors
+// tables (no vector column) and applying the named migrations to it. It skips the test unless SEARCHKIT_TEST_URL is
+// set.
+func newMigratedTestPool(t *testing.T, schema string, names ...string) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("SEARCHKIT_TEST_URL")
+	if dsn == "" {
+		t.Skip("SEARCHKIT_TEST_URL not set")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+
+	ctx := context.Background()
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("parse dsn: %v", err)
+	}
+	// The migrations use unqualified names.
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema + ", public"
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("pgxpool: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	_, err = pool.Exec(ctx, `
+		DROP SCHEMA IF EXISTS `+qs+` CASCADE;
+		CREATE SCHEMA `+qs+`;
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+		CREATE TABLE `+qs+`.search_documents (
+			entity_type text NOT NULL,
+			entity_id text NOT NULL,
+			language text NOT NULL,
+			document text NOT NULL,
+			created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (entity_type, entity_id, language)
+		);
+		CREATE TABLE `+qs+`.embedding_vectors (
+			entity_type text NOT NULL,
+			entity_id text NOT NULL,
+			model text NOT NULL,
+			language text NOT NULL,
+			PRIMARY KEY (entity_type, entity_id, model, language)
+		);
+	`)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	for _, name := range names {
+		sql, err := fs.ReadFile(migrations.Postgres, name)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if _, err := pool.Exec(ctx, string(sql)); err != nil {
+			t.Fatalf("apply %s: %v", name, err)
+		}
+	}
+	return pool
+}
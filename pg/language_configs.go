@@ -0,0 +1,264 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LanguageConfig is a `<schema>.search_language_configs` mapping.
+type LanguageConfig struct {
+	Language string
+	// Config is the Postgres text search configuration (regconfig) name.
+	Config string
+	// ReindexPending reports that stored tsv vectors of Language are still
+	// being recomputed by the worker.
+	ReindexPending bool
+}
+
+// RegisterLanguageConfig maps language to a Postgres text search
+// configuration (e.g. "nl" -> "dutch", or a host-created "english_unaccent").
+//
+// The config must exist. When the mapping changes, the language's stored tsv
+// vectors are queued for recomputation (see ReindexSearchDocumentsTSV) and
+// the search generation is bumped.
+func RegisterLanguageConfig(ctx context.Context, pool *pgxpool.Pool, schema string, language string, config string) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		return fmt.Errorf("language is required")
+	}
+	config = strings.TrimSpace(config)
+	if config == "" {
+		return fmt.Errorf("config is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// Resolve under the schema's search_path, like the lookup function does.
+	if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL search_path = %s, public", qs)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, "SELECT $1::regconfig", config); err != nil {
+		return fmt.Errorf("invalid text search config %q: %w", config, err)
+	}
+
+	var previous string
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+		SELECT config FROM %s.search_language_configs WHERE language = $1
+	`, qs), language).Scan(&previous)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if previous == config {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s.search_language_configs (language, config, updated_at)
+		VALUES ($1, $2, now())
+		ON CONFLICT (language) DO UPDATE SET
+			config = EXCLUDED.config,
+			updated_at = now()
+	`, qs), language, config); err != nil {
+		return err
+	}
+	if err := queueTSVReindex(ctx, tx, qs, language); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// UnregisterLanguageConfig removes the mapping for language, which then uses
+// `simple`. Stored tsv vectors are queued for recomputation.
+func UnregisterLanguageConfig(ctx context.Context, pool *pgxpool.Pool, schema string, language string) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" {
+		return fmt.Errorf("language is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+		DELETE FROM %s.search_language_configs WHERE language = $1
+	`, qs), language)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if err := queueTSVReindex(ctx, tx, qs, language); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// queueTSVReindex (re)starts the tsv recomputation of language from the
+// beginning and bumps the search generation.
+func queueTSVReindex(ctx context.Context, tx pgx.Tx, qs string, language string) error {
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s.search_tsv_reindex (language, requested_at, updated_at)
+		VALUES ($1, now(), now())
+		ON CONFLICT (language) DO UPDATE SET
+			cursor_entity_type = NULL,
+			cursor_entity_id = NULL,
+			requested_at = now(),
+			updated_at = now()
+	`, qs), language); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, bumpSearchGenerationSQL(qs))
+	return err
+}
+
+// ListLanguageConfigs returns the registered mappings ordered by language.
+func ListLanguageConfigs(ctx context.Context, pool *pgxpool.Pool, schema string) ([]LanguageConfig, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT c.language, c.config, r.language IS NOT NULL
+		FROM %[1]s.search_language_configs c
+		LEFT JOIN %[1]s.search_tsv_reindex r ON r.language = c.language
+		ORDER BY c.language ASC
+	`, qs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []LanguageConfig{}
+	for rows.Next() {
+		var c LanguageConfig
+		if err := rows.Scan(&c.Language, &c.Config, &c.ReindexPending); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// ReindexSearchDocumentsTSV recomputes the tsv of at most batchSize
+// search_documents rows of the oldest queued language, advancing its cursor,
+// and dequeues the language once it is done. It returns the number of rows
+// updated and whether any language was queued.
+//
+// The queue row is locked with SKIP LOCKED, so concurrent workers process
+// different languages.
+func ReindexSearchDocumentsTSV(ctx context.Context, pool *pgxpool.Pool, schema string, batchSize int) (int, bool, error) {
+	if pool == nil {
+		return 0, false, fmt.Errorf("pool is required")
+	}
+	if batchSize <= 0 {
+		return 0, false, fmt.Errorf("batchSize must be > 0")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var language string
+	var cursorType, cursorID *string
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+		SELECT language, cursor_entity_type, cursor_entity_id
+		FROM %s.search_tsv_reindex
+		ORDER BY requested_at ASC, language ASC
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`, qs)).Scan(&language, &cursorType, &cursorID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	var updated, scanned int
+	var lastType, lastID *string
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+		WITH batch AS (
+			SELECT entity_type, entity_id
+			FROM %[1]s.search_documents
+			WHERE language = $1
+			  AND ($2::text IS NULL OR (entity_type, entity_id) > ($2::text, $3::text))
+			ORDER BY entity_type ASC, entity_id ASC
+			LIMIT $4
+		),
+		upd AS (
+			UPDATE %[1]s.search_documents sd
+			SET tsv = %[1]s.searchkit_document_tsv(
+				sd.language,
+				coalesce(sd.raw_document, sd.document, ''),
+				sd.fields
+			)
+			FROM batch b
+			WHERE sd.entity_type = b.entity_type
+			  AND sd.entity_id = b.entity_id
+			  AND sd.language = $1
+			RETURNING sd.entity_type, sd.entity_id
+		)
+		SELECT
+			(SELECT count(*) FROM upd),
+			(SELECT count(*) FROM batch),
+			(SELECT entity_type FROM batch ORDER BY entity_type DESC, entity_id DESC LIMIT 1),
+			(SELECT entity_id FROM batch ORDER BY entity_type DESC, entity_id DESC LIMIT 1)
+	`, qs), language, cursorType, cursorID, batchSize).Scan(&updated, &scanned, &lastType, &lastID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if scanned < batchSize {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`
+			DELETE FROM %s.search_tsv_reindex WHERE language = $1
+		`, qs), language)
+	} else {
+		_, err = tx.Exec(ctx, fmt.Sprintf(`
+			UPDATE %s.search_tsv_reindex
+			SET cursor_entity_type = $2, cursor_entity_id = $3, updated_at = now()
+			WHERE language = $1
+		`, qs), language, lastType, lastID)
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, err
+	}
+	return updated, true, nil
+}
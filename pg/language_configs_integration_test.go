@@ -0,0 +1,121 @@
+package pg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestLanguageConfigs_Integration(t *testing.T) {
+	pool := newMigratedTestPool(t, "s_langcfg",
+		"002_fts_search_documents.up.sql",
+		"005_search_generation.up.sql",
+		"009_search_language_configs.up.sql",
+		"010_weighted_search_documents.up.sql",
+	)
+	ctx := context.Background()
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO s_langcfg.search_documents (entity_type, entity_id, language, document, raw_document, tsv)
+		VALUES ('gallery', '1', 'nl', 'de katten lopen', 'De katten lopen', to_tsvector('simple', 'De katten lopen'))
+	`)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	// tsvMatches reports whether the stored tsv of the document equals its
+	// vector under config.
+	tsvMatches := func(config string) bool {
+		t.Helper()
+		var ok bool
+		if err := pool.QueryRow(ctx, `
+			SELECT tsv = to_tsvector($1::regconfig, raw_document)
+			FROM s_langcfg.search_documents
+			WHERE entity_id = '1'
+		`, config).Scan(&ok); err != nil {
+			t.Fatalf("read tsv: %v", err)
+		}
+		return ok
+	}
+	reindexAll := func() {
+		t.Helper()
+		for i := 0; ; i++ {
+			_, queued, err := ReindexSearchDocumentsTSV(ctx, pool, "s_langcfg", 10)
+			if err != nil {
+				t.Fatalf("ReindexSearchDocumentsTSV: %v", err)
+			}
+			if !queued {
+				return
+			}
+			if i > 10 {
+				t.Fatalf("ReindexSearchDocumentsTSV did not drain the queue")
+			}
+		}
+	}
+
+	if err := RegisterLanguageConfig(ctx, pool, "s_langcfg", "nl", "no_such_config"); err == nil {
+		t.Fatalf("expected an error for an unknown config")
+	}
+	if err := RegisterLanguageConfig(ctx, pool, "s_langcfg", " ", "dutch"); err == nil {
+		t.Fatalf("expected an error for an empty language")
+	}
+	if err := UnregisterLanguageConfig(ctx, pool, "s_langcfg", " "); err == nil {
+		t.Fatalf("expected an error for an empty language")
+	}
+
+	gen, err := SearchGeneration(ctx, pool, "s_langcfg")
+	if err != nil {
+		t.Fatalf("SearchGeneration: %v", err)
+	}
+	if err := RegisterLanguageConfig(ctx, pool, "s_langcfg", "NL", "dutch"); err != nil {
+		t.Fatalf("RegisterLanguageConfig: %v", err)
+	}
+	if next, err := SearchGeneration(ctx, pool, "s_langcfg"); err != nil || next <= gen {
+		t.Fatalf("expected the generation to be bumped past %d, got %d (%v)", gen, next, err)
+	}
+	var resolved string
+	if err := pool.QueryRow(ctx, `SELECT s_langcfg.searchkit_regconfig_for_language('nl')::text`).Scan(&resolved); err != nil {
+		t.Fatalf("searchkit_regconfig_for_language: %v", err)
+	}
+	if resolved != "dutch" {
+		t.Fatalf("searchkit_regconfig_for_language(nl) = %q; want dutch", resolved)
+	}
+
+	configs, err := ListLanguageConfigs(ctx, pool, "s_langcfg")
+	if err != nil {
+		t.Fatalf("ListLanguageConfigs: %v", err)
+	}
+	var nl *LanguageConfig
+	for i := range configs {
+		if configs[i].Language == "nl" {
+			nl = &configs[i]
+		}
+	}
+	if nl == nil || !reflect.DeepEqual(*nl, LanguageConfig{Language: "nl", Config: "dutch", ReindexPending: true}) {
+		t.Fatalf("expected a pending nl -> dutch mapping, got %+v", configs)
+	}
+	if !tsvMatches("simple") {
+		t.Fatalf("stored tsv changed before the reindex ran")
+	}
+
+	reindexAll()
+	if !tsvMatches("dutch") {
+		t.Fatalf("expected the reindexed tsv to use the dutch config")
+	}
+	configs, err = ListLanguageConfigs(ctx, pool, "s_langcfg")
+	if err != nil {
+		t.Fatalf("ListLanguageConfigs: %v", err)
+	}
+	for _, c := range configs {
+		if c.ReindexPending {
+			t.Fatalf("expected no pending reindex, got %+v", configs)
+		}
+	}
+
+	if err := UnregisterLanguageConfig(ctx, pool, "s_langcfg", "nl"); err != nil {
+		t.Fatalf("UnregisterLanguageConfig: %v", err)
+	}
+	reindexAll()
+	if !tsvMatches("simple") {
+		t.Fatalf("expected the tsv to fall back to simple after unregistering")
+	}
+}
@@ -0,0 +1,93 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pgvector "github.com/pgvector/pgvector-go"
+)
+
+const queryEmbeddingCacheTable = "query_embedding_cache"
+
+// maxCachedQueryBytes skips caching unusually long queries (they are unlikely
+// to repeat and would bloat the primary key index).
+const maxCachedQueryBytes = 1024
+
+// QueryVectorCache is a Postgres-backed runtime.QueryVectorCache stored in
+// `<schema>.query_embedding_cache`, shared by every app instance using the
+// same schema.
+type QueryVectorCache struct {
+	pool   *pgxpool.Pool
+	schema string
+	ttl    time.Duration
+}
+
+// NewQueryVectorCache returns a cache whose entries expire after ttl
+// (default 24h).
+func NewQueryVectorCache(pool *pgxpool.Pool, schema string, ttl time.Duration) (*QueryVectorCache, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &QueryVectorCache{pool: pool, schema: qs, ttl: ttl}, nil
+}
+
+func (c *QueryVectorCache) Get(ctx context.Context, model string, query string) ([]float32, bool, error) {
+	if strings.TrimSpace(model) == "" || query == "" || len(query) > maxCachedQueryBytes {
+		return nil, false, nil
+	}
+	q := fmt.Sprintf(`
+		SELECT embedding
+		FROM %s.%s
+		WHERE model = $1 AND query = $2 AND expires_at > now()
+	`, c.schema, queryEmbeddingCacheTable)
+	var vec pgvector.Vector
+	if err := c.pool.QueryRow(ctx, q, model, query).Scan(&vec); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return vec.Slice(), true, nil
+}
+
+func (c *QueryVectorCache) Set(ctx context.Context, model string, query string, vec []float32) error {
+	if strings.TrimSpace(model) == "" || query == "" || len(query) > maxCachedQueryBytes || len(vec) == 0 {
+		return nil
+	}
+	q := fmt.Sprintf(`
+		INSERT INTO %s.%s (model, query, embedding, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, now() + make_interval(secs => $4), now(), now())
+		ON CONFLICT (model, query) DO UPDATE SET
+			embedding = EXCLUDED.embedding,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = now()
+	`, c.schema, queryEmbeddingCacheTable)
+	_, err := c.pool.Exec(ctx, q, model, query, pgvector.NewVector(vec), c.ttl.Seconds())
+	return err
+}
+
+// DeleteExpired prunes expired cache rows and returns how many were deleted.
+// Hosts can call it from their periodic worker.
+func (c *QueryVectorCache) DeleteExpired(ctx context.Context) (int64, error) {
+	q := fmt.Sprintf(`
+		DELETE FROM %s.%s
+		WHERE expires_at <= now()
+	`, c.schema, queryEmbeddingCacheTable)
+	tag, err := c.pool.Exec(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
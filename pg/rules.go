@@ -0,0 +1,308 @@
+package pg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+)
+
+type RuleMatchType string
+
+const (
+	// RuleMatchExact matches queries equal to Pattern.
+	RuleMatchExact RuleMatchType = "exact"
+	// RuleMatchPrefix matches queries starting with Pattern ("naru" matches
+	// "naruto shippuden").
+	RuleMatchPrefix RuleMatchType = "prefix"
+	// RuleMatchRegex matches queries matching Pattern (Go RE2 syntax).
+	RuleMatchRegex RuleMatchType = "regex"
+)
+
+type RuleActionType string
+
+const (
+	// RuleActionPin places the entity at Position, inserting it when
+	// retrieval did not find it but it has a search document within the
+	// request's scope (languages, entity types, filters, exclusions).
+	RuleActionPin RuleActionType = "pin"
+	// RuleActionBoost multiplies the entity's score by Factor (default 2).
+	RuleActionBoost RuleActionType = "boost"
+	// RuleActionBury multiplies the entity's score by Factor (default 0.5).
+	RuleActionBury RuleActionType = "bury"
+	// RuleActionHide removes the entity from the results.
+	RuleActionHide RuleActionType = "hide"
+	// RuleActionRewrite replaces the matched part of the query with Query
+	// before it is searched: the whole query for exact rules, the prefix for
+	// prefix rules (only when it ends at a word boundary), and every match for
+	// regex rules (Query may reference groups as $1). The rest of the query,
+	// including quotes, `not` and `field:` prefixes, is kept as typed.
+	RuleActionRewrite RuleActionType = "rewrite"
+	// RuleActionRedirect returns Target (e.g. a landing page URL) instead of
+	// running the search.
+	RuleActionRedirect RuleActionType = "redirect"
+)
+
+// SearchRule is a `<schema>.search_rules` entry.
+//
+// Rules match the normalized query: lowercased words with surrounding
+// punctuation removed, joined by single spaces (as synonym terms are).
+type SearchRule struct {
+	ID string
+	// Language restricts the rule to requests in that language ("" = all).
+	Language string
+	Match    RuleMatchType
+	// Pattern is stored normalized for exact and prefix rules.
+	Pattern string
+	Actions []RuleAction
+	// Priority orders matching rules (higher first), e.g. whose pins come
+	// first.
+	Priority int
+	// Disabled rules are kept but not applied.
+	Disabled bool
+}
+
+// RuleAction is one action of a SearchRule: on an entity (pin, boost, bury,
+// hide) or on the query (rewrite, redirect).
+type RuleAction struct {
+	Type       RuleActionType `json:"type"`
+	EntityType string         `json:"entity_type,omitempty"`
+	EntityID   string         `json:"entity_id,omitempty"`
+	// Position is the 1-based position of a pinned entity. 0 places it right
+	// after the preceding pin (first when there is none).
+	Position int `json:"position,omitempty"`
+	// Factor is the score multiplier of boost (> 1) and bury (< 1) actions.
+	Factor float32 `json:"factor,omitempty"`
+	// Query is the replacement text of rewrite actions.
+	Query string `json:"query,omitempty"`
+	// Target is the redirect target of redirect actions, returned to the host
+	// as is.
+	Target string `json:"target,omitempty"`
+}
+
+// NormalizeSearchRule validates r and returns it in stored form.
+func NormalizeSearchRule(r SearchRule) (SearchRule, error) {
+	r.ID = strings.TrimSpace(r.ID)
+	if r.ID == "" {
+		return r, fmt.Errorf("id is required")
+	}
+	r.Language = strings.ToLower(strings.TrimSpace(r.Language))
+	switch r.Match {
+	case RuleMatchExact, RuleMatchPrefix:
+		r.Pattern = querynorm.SynonymKey(r.Pattern)
+	case RuleMatchRegex:
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			return r, fmt.Errorf("invalid regex pattern: %w", err)
+		}
+	default:
+		return r, fmt.Errorf("invalid match type %q", r.Match)
+	}
+	if strings.TrimSpace(r.Pattern) == "" {
+		return r, fmt.Errorf("pattern is required")
+	}
+	if len(r.Actions) == 0 {
+		return r, fmt.Errorf("at least one action is required")
+	}
+	actions := make([]RuleAction, len(r.Actions))
+	for i, a := range r.Actions {
+		a.EntityType = strings.TrimSpace(a.EntityType)
+		a.EntityID = strings.TrimSpace(a.EntityID)
+		switch a.Type {
+		case RuleActionPin, RuleActionBoost, RuleActionBury, RuleActionHide:
+			if a.EntityType == "" || a.EntityID == "" {
+				return r, fmt.Errorf("action %d: entity_type and entity_id are required", i)
+			}
+		}
+		switch a.Type {
+		case RuleActionPin:
+			if a.Position < 0 {
+				return r, fmt.Errorf("action %d: position must be >= 0", i)
+			}
+		case RuleActionBoost:
+			if a.Factor == 0 {
+				a.Factor = 2
+			}
+			if a.Factor <= 1 {
+				return r, fmt.Errorf("action %d: boost factor must be > 1", i)
+			}
+		case RuleActionBury:
+			if a.Factor == 0 {
+				a.Factor = 0.5
+			}
+			if a.Factor <= 0 || a.Factor >= 1 {
+				return r, fmt.Errorf("action %d: bury factor must be in (0, 1)", i)
+			}
+		case RuleActionHide:
+		case RuleActionRewrite:
+			a.Query = strings.Join(strings.Fields(a.Query), " ")
+			if a.Query == "" {
+				return r, fmt.Errorf("action %d: query is required", i)
+			}
+		case RuleActionRedirect:
+			a.Target = strings.TrimSpace(a.Target)
+			if a.Target == "" {
+				return r, fmt.Errorf("action %d: target is required", i)
+			}
+		default:
+			return r, fmt.Errorf("action %d: invalid type %q", i, a.Type)
+		}
+		actions[i] = a
+	}
+	r.Actions = actions
+	return r, nil
+}
+
+// UpsertSearchRule creates or replaces the rule with r.ID. The search
+// generation is bumped in the same transaction, which makes clients reload
+// their rules and drop cached results.
+func UpsertSearchRule(ctx context.Context, pool *pgxpool.Pool, schema string, r SearchRule) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	r, err := NormalizeSearchRule(r)
+	if err != nil {
+		return err
+	}
+	actions, err := json.Marshal(r.Actions)
+	if err != nil {
+		return err
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s.search_rules (id, language, match_type, pattern, actions, priority, enabled, updated_at)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6, $7, now())
+		ON CONFLICT (id) DO UPDATE SET
+			language = EXCLUDED.language,
+			match_type = EXCLUDED.match_type,
+			pattern = EXCLUDED.pattern,
+			actions = EXCLUDED.actions,
+			priority = EXCLUDED.priority,
+			enabled = EXCLUDED.enabled,
+			updated_at = now()
+	`, qs), r.ID, r.Language, string(r.Match), r.Pattern, string(actions), r.Priority, !r.Disabled); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, bumpSearchGenerationSQL(qs)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteSearchRule removes the rule with id. Deleting a missing rule is not an
+// error.
+func DeleteSearchRule(ctx context.Context, pool *pgxpool.Pool, schema string, id string) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+		DELETE FROM %s.search_rules
+		WHERE id = $1
+	`, qs), strings.TrimSpace(id))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, bumpSearchGenerationSQL(qs)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// InvalidSearchRulesError reports stored rules that ListSearchRules skipped
+// because they do not decode or validate (e.g. rows edited out of band).
+type InvalidSearchRulesError struct {
+	// Errs holds one error per skipped rule, naming its id.
+	Errs []error
+}
+
+func (e *InvalidSearchRulesError) Error() string {
+	return fmt.Sprintf("%d invalid search rule(s) skipped: %v", len(e.Errs), errors.Join(e.Errs...))
+}
+
+func (e *InvalidSearchRulesError) Unwrap() []error { return e.Errs }
+
+// ListSearchRules returns the rules for language ("" = all rules; otherwise
+// rules for that language and language-independent ones), highest priority
+// first, then by id. Disabled rules are included.
+//
+// A rule whose row does not decode or validate (see NormalizeSearchRule) does
+// not fail the others: it is skipped, and the valid rules are returned with an
+// *InvalidSearchRulesError.
+func ListSearchRules(ctx context.Context, pool *pgxpool.Pool, schema string, language string) ([]SearchRule, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT id, language, match_type, pattern, actions, priority, enabled
+		FROM %s.search_rules
+		WHERE $1 = '' OR language IN ('', $1)
+		ORDER BY priority DESC, id ASC
+	`, qs), strings.ToLower(strings.TrimSpace(language)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SearchRule{}
+	var invalid []error
+	for rows.Next() {
+		var r SearchRule
+		var match string
+		var actions []byte
+		var enabled bool
+		if err := rows.Scan(&r.ID, &r.Language, &match, &r.Pattern, &actions, &r.Priority, &enabled); err != nil {
+			return nil, err
+		}
+		r.Match = RuleMatchType(match)
+		r.Disabled = !enabled
+		if err := json.Unmarshal(actions, &r.Actions); err != nil {
+			invalid = append(invalid, fmt.Errorf("rule %q: invalid actions: %w", r.ID, err))
+			continue
+		}
+		valid, err := NormalizeSearchRule(r)
+		if err != nil {
+			invalid = append(invalid, fmt.Errorf("rule %q: %w", r.ID, err))
+			continue
+		}
+		out = append(out, valid)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(invalid) > 0 {
+		return out, &InvalidSearchRulesError{Errs: invalid}
+	}
+	return out, nil
+}
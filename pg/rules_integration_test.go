@@ -0,0 +1,47 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestListSearchRules_Integration_SkipsInvalidRows(t *testing.T) {
+	pool := newMigratedTestPool(t, "s_rules",
+		"005_search_generation.up.sql",
+		"013_search_rules.up.sql",
+	)
+	ctx := context.Background()
+
+	if err := UpsertSearchRule(ctx, pool, "s_rules", SearchRule{
+		ID:      "good",
+		Match:   RuleMatchExact,
+		Pattern: "naruto",
+		Actions: []RuleAction{{Type: RuleActionHide, EntityType: "gallery", EntityID: "1"}},
+	}); err != nil {
+		t.Fatalf("UpsertSearchRule: %v", err)
+	}
+	// Rows edited out of band: actions that are not a list, and a match type
+	// this version does not know (e.g. written by a newer one).
+	_, err := pool.Exec(ctx, `
+		ALTER TABLE s_rules.search_rules DROP CONSTRAINT search_rules_match_type_check;
+		INSERT INTO s_rules.search_rules (id, match_type, pattern, actions) VALUES
+			('bad_actions', 'exact', 'naruto', '{"type": "hide"}'),
+			('bad_match', 'fuzzy', 'naruto', '[{"type": "hide", "entity_type": "gallery", "entity_id": "2"}]');
+	`)
+	if err != nil {
+		t.Fatalf("insert invalid rules: %v", err)
+	}
+
+	rules, err := ListSearchRules(ctx, pool, "s_rules", "")
+	var invalid *InvalidSearchRulesError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("expected *InvalidSearchRulesError, got %v", err)
+	}
+	if len(invalid.Errs) != 2 {
+		t.Fatalf("expected 2 skipped rules, got %v", invalid.Errs)
+	}
+	if len(rules) != 1 || rules[0].ID != "good" {
+		t.Fatalf("expected only the valid rule, got %+v", rules)
+	}
+}
@@ -2,6 +2,7 @@ package pg
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -17,6 +18,117 @@ const searchDocumentsTable = "search_documents"
 // Documents are heavy-normalized by searchkit before storage so host apps can pass
 // "raw-ish" display strings.
 func UpsertSearchDocuments(ctx context.Context, pool *pgxpool.Pool, schema string, entityType string, language string, docs map[string]string) error {
+	rows := make(map[string]searchDocumentRow, len(docs))
+	for id, raw := range docs {
+		rows[id] = searchDocumentRow{raw: strings.TrimSpace(raw)}
+	}
+	return upsertSearchDocumentRows(ctx, pool, schema, entityType, language, rows)
+}
+
+// LexicalWeight is a Postgres tsvector weight label.
+type LexicalWeight string
+
+const (
+	// LexicalWeightA is the highest weight, conventionally the title. A-weight
+	// text also gets the trigram/PGroonga title boost.
+	LexicalWeightA LexicalWeight = "A"
+	// LexicalWeightB is conventionally used for aliases / alternative titles.
+	LexicalWeightB LexicalWeight = "B"
+	// LexicalWeightC is conventionally used for tags.
+	LexicalWeightC LexicalWeight = "C"
+	// LexicalWeightD is the lowest weight (body text) and the default.
+	LexicalWeightD LexicalWeight = "D"
+)
+
+// LexicalField is one weighted field of a LexicalDocument.
+type LexicalField struct {
+	Weight LexicalWeight
+	Text   string
+}
+
+// LexicalDocument is a field-weighted lexical document.
+type LexicalDocument struct {
+	Fields []LexicalField
+}
+
+// Text returns the document's full text: non-empty fields ordered by weight
+// (A first), one per line.
+func (d LexicalDocument) Text() string {
+	var parts []string
+	for _, w := range []LexicalWeight{LexicalWeightA, LexicalWeightB, LexicalWeightC, LexicalWeightD} {
+		for _, f := range d.Fields {
+			if f.weight() == w {
+				if t := strings.TrimSpace(f.Text); t != "" {
+					parts = append(parts, t)
+				}
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// Title returns the A-weight text.
+func (d LexicalDocument) Title() string {
+	var parts []string
+	for _, f := range d.Fields {
+		if f.weight() == LexicalWeightA {
+			if t := strings.TrimSpace(f.Text); t != "" {
+				parts = append(parts, t)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f LexicalField) weight() LexicalWeight {
+	switch f.Weight {
+	case LexicalWeightA, LexicalWeightB, LexicalWeightC:
+		return f.Weight
+	default:
+		return LexicalWeightD
+	}
+}
+
+// UpsertLexicalDocuments upserts field-weighted lexical documents for one
+// (entity_type, language).
+//
+// The stored tsv uses setweight per field, the full text (LexicalDocument.Text)
+// feeds trigram/PGroonga as with UpsertSearchDocuments, and the A-weight text
+// is stored separately for title boosts. Documents without text are deleted.
+func UpsertLexicalDocuments(ctx context.Context, pool *pgxpool.Pool, schema string, entityType string, language string, docs map[string]LexicalDocument) error {
+	rows := make(map[string]searchDocumentRow, len(docs))
+	for id, doc := range docs {
+		type storedField struct {
+			Weight LexicalWeight `json:"weight"`
+			Text   string        `json:"text"`
+		}
+		stored := make([]storedField, 0, len(doc.Fields))
+		for _, f := range doc.Fields {
+			if t := strings.TrimSpace(f.Text); t != "" {
+				stored = append(stored, storedField{Weight: f.weight(), Text: t})
+			}
+		}
+		fields, err := json.Marshal(stored)
+		if err != nil {
+			return err
+		}
+		rows[id] = searchDocumentRow{
+			raw:      doc.Text(),
+			rawTitle: doc.Title(),
+			fields:   fields,
+		}
+	}
+	return upsertSearchDocumentRows(ctx, pool, schema, entityType, language, rows)
+}
+
+type searchDocumentRow struct {
+	raw      string
+	rawTitle string
+	// fields is the weighted-field JSON (nil for flat documents).
+	fields []byte
+}
+
+func upsertSearchDocumentRows(ctx context.Context, pool *pgxpool.Pool, schema string, entityType string, language string, docs map[string]searchDocumentRow) error {
 	if pool == nil {
 		return fmt.Errorf("pool is required")
 	}
@@ -54,10 +166,13 @@ func UpsertSearchDocuments(ctx context.Context, pool *pgxpool.Pool, schema strin
 	idArr := make([]string, 0, len(ids))
 	docArr := make([]string, 0, len(ids))
 	rawArr := make([]string, 0, len(ids))
+	rawTitleArr := make([]*string, 0, len(ids))
+	titleArr := make([]*string, 0, len(ids))
+	fieldsArr := make([]*string, 0, len(ids))
 	var deleteIDs []string
 	for _, id := range ids {
-		raw := docs[id]
-		rawTrim := strings.TrimSpace(raw)
+		row := docs[id]
+		rawTrim := strings.TrimSpace(row.raw)
 		norm := strings.TrimSpace(textnormalize.Heavy(rawTrim))
 		if norm == "" {
 			deleteIDs = append(deleteIDs, id)
@@ -69,6 +184,20 @@ func UpsertSearchDocuments(ctx context.Context, pool *pgxpool.Pool, schema strin
 			rawTrim = norm
 		}
 		rawArr = append(rawArr, rawTrim)
+
+		var rawTitle, title, fields *string
+		if t := strings.TrimSpace(row.rawTitle); t != "" {
+			if n := strings.TrimSpace(textnormalize.Heavy(t)); n != "" {
+				rawTitle, title = &t, &n
+			}
+		}
+		if row.fields != nil {
+			f := string(row.fields)
+			fields = &f
+		}
+		rawTitleArr = append(rawTitleArr, rawTitle)
+		titleArr = append(titleArr, title)
+		fieldsArr = append(fieldsArr, fields)
 	}
 
 	if len(idArr) > 0 {
@@ -77,26 +206,35 @@ func UpsertSearchDocuments(ctx context.Context, pool *pgxpool.Pool, schema strin
 				SELECT
 					unnest($3::text[]) AS entity_id,
 					unnest($4::text[]) AS raw_document,
-					unnest($5::text[]) AS document
+					unnest($5::text[]) AS document,
+					unnest($6::text[]) AS raw_title,
+					unnest($7::text[]) AS title,
+					unnest($8::text[])::jsonb AS fields
 			)
-			INSERT INTO %s.%s (entity_type, entity_id, language, raw_document, document, tsv, created_at, updated_at)
+			INSERT INTO %s.%s (entity_type, entity_id, language, raw_document, document, raw_title, title, fields, tsv, created_at, updated_at)
 			SELECT
 				$1,
 				rows.entity_id,
 				$2,
 				rows.raw_document,
 				rows.document,
-				to_tsvector(%s.searchkit_regconfig_for_language($2), rows.raw_document),
+				rows.raw_title,
+				rows.title,
+				rows.fields,
+				%s.searchkit_document_tsv($2, rows.raw_document, rows.fields),
 				now(),
 				now()
 			FROM rows
 			ON CONFLICT (entity_type, entity_id, language) DO UPDATE SET
 				raw_document = EXCLUDED.raw_document,
 				document = EXCLUDED.document,
+				raw_title = EXCLUDED.raw_title,
+				title = EXCLUDED.title,
+				fields = EXCLUDED.fields,
 				tsv = EXCLUDED.tsv,
 				updated_at = now()
 		`, qs, searchDocumentsTable, qs)
-		if _, err := pool.Exec(ctx, q, entityType, language, idArr, rawArr, docArr); err != nil {
+		if _, err := pool.Exec(ctx, q, entityType, language, idArr, rawArr, docArr, rawTitleArr, titleArr, fieldsArr); err != nil {
 			return err
 		}
 	}
@@ -0,0 +1,86 @@
+package pg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLexicalDocument_TextAndTitle(t *testing.T) {
+	doc := LexicalDocument{Fields: []LexicalField{
+		{Text: "a long body"},
+		{Weight: LexicalWeightC, Text: "tag1 tag2"},
+		{Weight: LexicalWeightA, Text: " Main Title "},
+		{Weight: LexicalWeightB, Text: "Alias"},
+		{Weight: LexicalWeightA, Text: "Subtitle"},
+		{Weight: LexicalWeightA, Text: "   "},
+		{Weight: "Z", Text: "unknown weight"},
+	}}
+
+	if got, want := doc.Text(), "Main Title\nSubtitle\nAlias\ntag1 tag2\na long body\nunknown weight"; got != want {
+		t.Fatalf("Text() = %q; want %q", got, want)
+	}
+	if got, want := doc.Title(), "Main Title Subtitle"; got != want {
+		t.Fatalf("Title() = %q; want %q", got, want)
+	}
+	if got := (LexicalDocument{Fields: []LexicalField{{Text: "body"}}}).Title(); got != "" {
+		t.Fatalf("Title() without A fields = %q; want empty", got)
+	}
+}
+
+func TestUpsertLexicalDocuments_Integration(t *testing.T) {
+	pool := newMigratedTestPool(t, "s_lexdocs",
+		"002_fts_search_documents.up.sql",
+		"010_weighted_search_documents.up.sql",
+	)
+	ctx := context.Background()
+
+	err := UpsertLexicalDocuments(ctx, pool, "s_lexdocs", "gallery", "en", map[string]LexicalDocument{
+		"1": {Fields: []LexicalField{
+			{Weight: LexicalWeightA, Text: "Blue Archive"},
+			{Weight: LexicalWeightC, Text: "school"},
+		}},
+		"2": {Fields: []LexicalField{{Text: "   "}}},
+	})
+	if err != nil {
+		t.Fatalf("UpsertLexicalDocuments: %v", err)
+	}
+
+	var (
+		raw, rawTitle, title string
+		fields               []byte
+		titleWeighted        bool
+		tagWeighted          bool
+		count                int
+	)
+	err = pool.QueryRow(ctx, `
+		SELECT
+			raw_document,
+			raw_title,
+			title,
+			fields::text,
+			tsv @@ to_tsquery('english', 'blue:A'),
+			tsv @@ to_tsquery('english', 'school:C')
+		FROM s_lexdocs.search_documents
+		WHERE entity_type = 'gallery' AND entity_id = '1' AND language = 'en'
+	`).Scan(&raw, &rawTitle, &title, &fields, &titleWeighted, &tagWeighted)
+	if err != nil {
+		t.Fatalf("read document: %v", err)
+	}
+	if raw != "Blue Archive\nschool" || rawTitle != "Blue Archive" || title != "blue archive" {
+		t.Fatalf("unexpected stored text: raw=%q raw_title=%q title=%q", raw, rawTitle, title)
+	}
+	if string(fields) != `[{"text": "Blue Archive", "weight": "A"}, {"text": "school", "weight": "C"}]` {
+		t.Fatalf("unexpected stored fields: %s", fields)
+	}
+	if !titleWeighted || !tagWeighted {
+		t.Fatalf("expected per-field tsv weights (A=%v, C=%v)", titleWeighted, tagWeighted)
+	}
+
+	// Documents without text are not stored.
+	if err := pool.QueryRow(ctx, `SELECT count(*) FROM s_lexdocs.search_documents WHERE entity_id = '2'`).Scan(&count); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected the empty document to be absent, got %d rows", count)
+	}
+}
@@ -0,0 +1,141 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Signals are host ranking signals for one entity, stored in
+// `<schema>.entity_signals` and used by SearchOptions.Boosts.
+type Signals struct {
+	// StaticRank is a non-negative popularity score (views, likes, ...).
+	StaticRank float64
+	// Timestamp is the entity's recency reference (e.g. published or live
+	// at). Zero means unknown: the entity gets no recency boost.
+	Timestamp time.Time
+}
+
+// SignalKey identifies an entity in entity_signals.
+type SignalKey struct {
+	EntityType string
+	EntityID   string
+}
+
+// UpsertSignals creates or replaces the signals of entities of entityType.
+// When a row changed, the search generation is bumped so cached results
+// ranked with the old signals are not served.
+func UpsertSignals(ctx context.Context, pool *pgxpool.Pool, schema string, entityType string, signals map[string]Signals) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(entityType) == "" {
+		return fmt.Errorf("entityType is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	ids := make([]string, 0, len(signals))
+	for id := range signals {
+		if strings.TrimSpace(id) != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Strings(ids)
+	ranks := make([]float64, len(ids))
+	stamps := make([]*time.Time, len(ids))
+	for i, id := range ids {
+		s := signals[id]
+		if s.StaticRank < 0 || math.IsNaN(s.StaticRank) || math.IsInf(s.StaticRank, 0) {
+			return fmt.Errorf("signals for %q: StaticRank must be a finite number >= 0", id)
+		}
+		ranks[i] = s.StaticRank
+		if !s.Timestamp.IsZero() {
+			ts := s.Timestamp.UTC()
+			stamps[i] = &ts
+		}
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s.entity_signals AS t (entity_type, entity_id, static_rank, signal_at, updated_at)
+		SELECT $1, s.entity_id, s.static_rank, s.signal_at, CURRENT_TIMESTAMP
+		FROM unnest($2::text[], $3::float8[], $4::timestamptz[]) AS s(entity_id, static_rank, signal_at)
+		ON CONFLICT (entity_type, entity_id) DO UPDATE SET
+			static_rank = EXCLUDED.static_rank,
+			signal_at = EXCLUDED.signal_at,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE t.static_rank IS DISTINCT FROM EXCLUDED.static_rank
+		   OR t.signal_at IS DISTINCT FROM EXCLUDED.signal_at
+	`, qs), entityType, ids, ranks, stamps)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, bumpSearchGenerationSQL(qs)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// LoadSignals returns the stored signals for keys (entities without signals
+// are omitted).
+func LoadSignals(ctx context.Context, pool *pgxpool.Pool, schema string, keys []SignalKey) (map[SignalKey]Signals, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	out := map[SignalKey]Signals{}
+	if len(keys) == 0 {
+		return out, nil
+	}
+	entityTypes := make([]string, len(keys))
+	entityIDs := make([]string, len(keys))
+	for i, k := range keys {
+		entityTypes[i] = k.EntityType
+		entityIDs[i] = k.EntityID
+	}
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT s.entity_type, s.entity_id, s.static_rank, s.signal_at
+		FROM unnest($1::text[], $2::text[]) AS k(entity_type, entity_id)
+		JOIN %s.entity_signals s ON s.entity_type = k.entity_type AND s.entity_id = k.entity_id
+	`, qs), entityTypes, entityIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var k SignalKey
+		var s Signals
+		var at *time.Time
+		if err := rows.Scan(&k.EntityType, &k.EntityID, &s.StaticRank, &at); err != nil {
+			return nil, err
+		}
+		if at != nil {
+			s.Timestamp = *at
+		}
+		out[k] = s
+	}
+	return out, rows.Err()
+}
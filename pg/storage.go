@@ -53,3 +53,25 @@ func (s *PostgresStorage) UpsertTextEmbedding(ctx context.Context, entityType st
 	_, err := s.pool.Exec(ctx, q, entityType, entityID, model, language, pgvector.NewHalfVector(embedding))
 	return err
 }
+
+// UpsertAttributes stores document attributes (see UpsertAttributes).
+func (s *PostgresStorage) UpsertAttributes(ctx context.Context, entityType string, language string, attrs map[string]Attributes) error {
+	return UpsertAttributes(ctx, s.pool, s.schema, entityType, language, attrs)
+}
+
+// StoreAttributes stores document attributes without bumping the search
+// generation (see StoreAttributes).
+func (s *PostgresStorage) StoreAttributes(ctx context.Context, entityType string, language string, attrs map[string]Attributes) (bool, error) {
+	return StoreAttributes(ctx, s.pool, s.schema, entityType, language, attrs)
+}
+
+// BumpSearchGeneration invalidates cached search results (see
+// BumpSearchGeneration).
+func (s *PostgresStorage) BumpSearchGeneration(ctx context.Context) error {
+	return BumpSearchGeneration(ctx, s.pool, s.schema)
+}
+
+// UpsertSignals stores entity ranking signals (see UpsertSignals).
+func (s *PostgresStorage) UpsertSignals(ctx context.Context, entityType string, signals map[string]Signals) error {
+	return UpsertSignals(ctx, s.pool, s.schema, entityType, signals)
+}
@@ -0,0 +1,199 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+)
+
+type SynonymMode string
+
+const (
+	// SynonymModeOneWay expands Term to Synonyms only ("yuri" finds
+	// "girls love", not the reverse).
+	SynonymModeOneWay SynonymMode = "one_way"
+	// SynonymModeTwoWay makes Term and every synonym expand to each other.
+	SynonymModeTwoWay SynonymMode = "two_way"
+)
+
+// Synonym is a `<schema>.search_synonyms` entry.
+type Synonym struct {
+	Language string
+	Term     string
+	Synonyms []string
+	// Mode defaults to SynonymModeTwoWay.
+	Mode SynonymMode
+}
+
+// UpsertSynonym creates or replaces the synonym entry for (Language, Term).
+//
+// Term is stored normalized (lowercased, punctuation-trimmed words), so
+// entries differing only in case or spacing replace each other. The search
+// generation is bumped in the same transaction, which makes clients reload
+// their dictionaries and drop cached results.
+func UpsertSynonym(ctx context.Context, pool *pgxpool.Pool, schema string, s Synonym) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	language := strings.TrimSpace(s.Language)
+	if language == "" {
+		return fmt.Errorf("language is required")
+	}
+	term := querynorm.SynonymKey(s.Term)
+	if term == "" {
+		return fmt.Errorf("term is required")
+	}
+	mode := s.Mode
+	if mode == "" {
+		mode = SynonymModeTwoWay
+	}
+	if mode != SynonymModeOneWay && mode != SynonymModeTwoWay {
+		return fmt.Errorf("invalid synonym mode %q", mode)
+	}
+	synonyms := make([]string, 0, len(s.Synonyms))
+	seen := map[string]struct{}{term: {}}
+	for _, syn := range s.Synonyms {
+		syn = strings.Join(strings.Fields(syn), " ")
+		key := querynorm.SynonymKey(syn)
+		if key == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		synonyms = append(synonyms, syn)
+	}
+	if len(synonyms) == 0 {
+		return fmt.Errorf("at least one synonym is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %s.search_synonyms (language, term, synonyms, mode, updated_at)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (language, term) DO UPDATE SET
+			synonyms = EXCLUDED.synonyms,
+			mode = EXCLUDED.mode,
+			updated_at = now()
+	`, qs), language, term, synonyms, string(mode)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, bumpSearchGenerationSQL(qs)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// DeleteSynonym removes the entry for (language, term). Deleting a missing
+// entry is not an error.
+func DeleteSynonym(ctx context.Context, pool *pgxpool.Pool, schema string, language string, term string) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+		DELETE FROM %s.search_synonyms
+		WHERE language = $1 AND term = $2
+	`, qs), strings.TrimSpace(language), querynorm.SynonymKey(term))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, bumpSearchGenerationSQL(qs)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ListSynonyms returns the synonym entries for language ("" = all languages),
+// ordered by language and term.
+func ListSynonyms(ctx context.Context, pool *pgxpool.Pool, schema string, language string) ([]Synonym, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT language, term, synonyms, mode
+		FROM %s.search_synonyms
+		WHERE $1 = '' OR language = $1
+		ORDER BY language ASC, term ASC
+	`, qs), strings.TrimSpace(language))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Synonym{}
+	for rows.Next() {
+		var s Synonym
+		var mode string
+		if err := rows.Scan(&s.Language, &s.Term, &s.Synonyms, &mode); err != nil {
+			return nil, err
+		}
+		s.Mode = SynonymMode(mode)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// SynonymExpansions builds the lookup used for query expansion from entries:
+// normalized term -> alternatives. Two-way entries map every member to all
+// other members.
+func SynonymExpansions(entries []Synonym) map[string][]string {
+	out := map[string][]string{}
+	add := func(from string, to string) {
+		key := querynorm.SynonymKey(from)
+		if key == "" || key == querynorm.SynonymKey(to) {
+			return
+		}
+		for _, existing := range out[key] {
+			if querynorm.SynonymKey(existing) == querynorm.SynonymKey(to) {
+				return
+			}
+		}
+		out[key] = append(out[key], to)
+	}
+	for _, e := range entries {
+		for _, syn := range e.Synonyms {
+			add(e.Term, syn)
+		}
+		if e.Mode != SynonymModeTwoWay {
+			continue
+		}
+		members := append([]string{e.Term}, e.Synonyms...)
+		for _, from := range members[1:] {
+			for _, to := range members {
+				add(from, to)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package pg
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RebuildSearchVocabulary recomputes `<schema>.search_vocabulary` from
+// `search_documents`.
+//
+// The vocabulary is normally maintained incrementally by triggers on
+// search_documents (migration 006); this is a repair tool, e.g. after bulk
+// loads with triggers disabled.
+func RebuildSearchVocabulary(ctx context.Context, pool *pgxpool.Pool, schema string) error {
+	if pool == nil {
+		return fmt.Errorf("pool is required")
+	}
+	qs, err := quoteIdent(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`DELETE FROM %s.search_vocabulary`, qs)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`
+		INSERT INTO %[1]s.search_vocabulary (language, term, doc_count)
+		SELECT sd.language, t.term, count(*)
+		FROM %[1]s.search_documents sd
+		CROSS JOIN LATERAL unnest(%[1]s.searchkit_document_terms(sd.raw_document, sd.document)) AS t(term)
+		GROUP BY sd.language, t.term
+	`, qs)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,75 @@
+package pg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSearchVocabularyTrigger_Integration(t *testing.T) {
+	pool := newMigratedTestPool(t, "s_vocab",
+		"002_fts_search_documents.up.sql",
+		"006_search_vocabulary.up.sql",
+	)
+	ctx := context.Background()
+
+	counts := func() map[string]int64 {
+		t.Helper()
+		rows, err := pool.Query(ctx, `SELECT term, doc_count FROM s_vocab.search_vocabulary WHERE language = 'en'`)
+		if err != nil {
+			t.Fatalf("read vocabulary: %v", err)
+		}
+		defer rows.Close()
+		out := map[string]int64{}
+		for rows.Next() {
+			var term string
+			var n int64
+			if err := rows.Scan(&term, &n); err != nil {
+				t.Fatalf("scan: %v", err)
+			}
+			out[term] = n
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatalf("rows: %v", err)
+		}
+		return out
+	}
+
+	_, err := pool.Exec(ctx, `
+		INSERT INTO s_vocab.search_documents (entity_type, entity_id, language, document, raw_document)
+		VALUES
+			('gallery', '1', 'en', 'red fox', 'Red fox'),
+			('gallery', '2', 'en', 'red hen', 'Red hen')
+	`)
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	want := map[string]int64{"red": 2, "fox": 1, "hen": 1}
+	if got := counts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after insert: %v; want %v", got, want)
+	}
+
+	// One row keeps its text (only updated_at changes); the other changes.
+	_, err = pool.Exec(ctx, `
+		UPDATE s_vocab.search_documents
+		SET raw_document = CASE entity_id WHEN '2' THEN 'Blue hen' ELSE raw_document END,
+		    document = CASE entity_id WHEN '2' THEN 'blue hen' ELSE document END,
+		    updated_at = now()
+	`)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	want = map[string]int64{"red": 1, "fox": 1, "hen": 1, "blue": 1}
+	if got := counts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after update: %v; want %v", got, want)
+	}
+
+	// A key change moves the row's terms to the new language.
+	if _, err := pool.Exec(ctx, `UPDATE s_vocab.search_documents SET language = 'de' WHERE entity_id = '1'`); err != nil {
+		t.Fatalf("update language: %v", err)
+	}
+	want = map[string]int64{"hen": 1, "blue": 1}
+	if got := counts(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("after language change: %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package searchkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/open-rails/searchkit/search"
+)
+
+// Reranker scores query/document pairs with a cross-encoder for
+// SearchOptions.Rerank (e.g. reranker.HTTPReranker).
+type Reranker interface {
+	// Rerank returns one relevance score per document (index-aligned, higher
+	// is more relevant).
+	Rerank(ctx context.Context, query string, documents []string) ([]float32, error)
+}
+
+// RerankDocumentsFunc returns the text the reranker scores for each hit
+// (missing keys are not reranked). See ClientConfig.RerankDocuments.
+type RerankDocumentsFunc func(ctx context.Context, keys []search.DocKey) (map[search.DocKey]string, error)
+
+// RerankOptions reranks the top of the fused ranking with ClientConfig.Reranker
+// as a second stage.
+//
+// The TopN hits after fusion, boosting and search rules (except pins) are
+// scored by the reranker against the query's positive terms (without
+// operators, field names or excluded words) and reordered by that score,
+// which becomes their SearchHit.Score as is, on the reranker's scale (e.g.
+// logits, possibly negative). Hits below TopN, and hits without document
+// text, keep their fused order and score after the reranked ones, so Score
+// is not comparable, nor monotonic, across the two groups.
+type RerankOptions struct {
+	// TopN is the number of fused hits reranked (default 50). Backends
+	// retrieve at least this deep.
+	TopN int
+}
+
+// RerankExplanation shows how a hit was reranked (HitExplanation.Rerank).
+type RerankExplanation struct {
+	// FusedScore and FusedRank (1-based) are the hit's score and position
+	// before reranking.
+	FusedScore float32
+	FusedRank  int
+	// Score is the reranker's relevance score.
+	Score float32
+}
+
+const defaultRerankTopN = 50
+
+func (c *Client) validateRerank(r *RerankOptions) error {
+	if r == nil {
+		return nil
+	}
+	if c.reranker == nil {
+		return fmt.Errorf("Reranker is required for SearchOptions.Rerank")
+	}
+	if r.TopN < 0 {
+		return fmt.Errorf("invalid SearchOptions.Rerank: TopN must be >= 0")
+	}
+	return nil
+}
+
+func rerankTopN(r *RerankOptions) int {
+	if r == nil {
+		return 0
+	}
+	if r.TopN > 0 {
+		return r.TopN
+	}
+	return defaultRerankTopN
+}
+
+func rerankFingerprint(r *RerankOptions) string {
+	if r == nil {
+		return ""
+	}
+	return fmt.Sprintf("rerank=%d", rerankTopN(r))
+}
+
+// rerankDocuments returns the text to rerank hits with: from
+// ClientConfig.RerankDocuments when set, else the stored raw documents.
+func (c *Client) rerankDocuments(ctx context.Context, keys []search.DocKey) (map[search.DocKey]string, error) {
+	if c.rerankDocs != nil {
+		return c.rerankDocs(ctx, keys)
+	}
+	return search.RawDocuments(ctx, c.pool, c.schema, keys)
+}
+
+// applyRerank reranks the top of hits (see RerankOptions). The reranker call
+// is bounded by timeout; its failure is returned as a BackendFailure for
+// BackendRerank, leaving hits unchanged.
+func (c *Client) applyRerank(ctx context.Context, query string, hits []SearchHit, r *RerankOptions, timeout time.Duration) ([]SearchHit, *BackendFailure) {
+	n := min(rerankTopN(r), len(hits))
+	if n == 0 {
+		return hits, nil
+	}
+	keys := make([]search.DocKey, n)
+	for i, h := range hits[:n] {
+		keys[i] = search.DocKey{EntityType: h.EntityType, EntityID: h.EntityID, Language: h.Language}
+	}
+	var scored []int
+	var scores []float32
+	failure := runBackendCall(ctx, BackendRerank, "", timeout, func(ctx context.Context) error {
+		docs, err := c.rerankDocuments(ctx, keys)
+		if err != nil {
+			return fmt.Errorf("load rerank documents: %w", err)
+		}
+		texts := make([]string, 0, n)
+		for i, k := range keys {
+			if text, ok := docs[k]; ok && text != "" {
+				scored = append(scored, i)
+				texts = append(texts, text)
+			}
+		}
+		if len(texts) == 0 {
+			return nil
+		}
+		scores, err = c.reranker.Rerank(ctx, query, texts)
+		if err != nil {
+			return err
+		}
+		if len(scores) != len(texts) {
+			return fmt.Errorf("expected %d rerank scores, got %d", len(texts), len(scores))
+		}
+		return nil
+	})
+	if failure != nil {
+		return hits, failure
+	}
+	rerankHits(hits[:n], scored, scores)
+	return hits, nil
+}
+
+// rerankHits moves hits[scored[i]] to the front ordered by scores[i] (stable
+// on ties), setting their score. Unscored hits follow in their fused order.
+func rerankHits(hits []SearchHit, scored []int, scores []float32) {
+	if len(scored) == 0 {
+		return
+	}
+	type entry struct {
+		hit   SearchHit
+		score float32
+		rank  int
+	}
+	byIndex := make(map[int]float32, len(scored))
+	for i, idx := range scored {
+		byIndex[idx] = scores[i]
+	}
+	var top, rest []entry
+	for i, h := range hits {
+		if s, ok := byIndex[i]; ok {
+			top = append(top, entry{hit: h, score: s, rank: i + 1})
+		} else {
+			rest = append(rest, entry{hit: h})
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool { return top[i].score > top[j].score })
+
+	i := 0
+	for _, e := range top {
+		h := e.hit
+		if h.Explain != nil {
+			h.Explain.Rerank = &RerankExplanation{FusedScore: h.Score, FusedRank: e.rank, Score: e.score}
+		}
+		h.Score = e.score
+		hits[i] = h
+		i++
+	}
+	for _, e := range rest {
+		hits[i] = e.hit
+		i++
+	}
+}
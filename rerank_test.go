@@ -0,0 +1,111 @@
+package searchkit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-rails/searchkit/reranker"
+	"github.com/open-rails/searchkit/search"
+)
+
+type fakeReranker struct {
+	query string
+	docs  []string
+	err   error
+}
+
+// Rerank scores documents by length.
+func (f *fakeReranker) Rerank(_ context.Context, query string, documents []string) ([]float32, error) {
+	f.query, f.docs = query, documents
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := make([]float32, len(documents))
+	for i, d := range documents {
+		out[i] = float32(len(d))
+	}
+	return out, nil
+}
+
+func TestClientApplyRerank(t *testing.T) {
+	t.Parallel()
+
+	rr := &fakeReranker{}
+	client, err := NewClient(ClientConfig{
+		Pool:     newTestPool(t),
+		Schema:   "test",
+		Reranker: rr,
+		RerankDocuments: func(_ context.Context, keys []search.DocKey) (map[search.DocKey]string, error) {
+			docs := map[string]string{"a": "x", "b": "xxx", "d": "xxxxx"}
+			out := map[search.DocKey]string{}
+			for _, k := range keys {
+				if d, ok := docs[k.EntityID]; ok {
+					out[k] = d
+				}
+			}
+			return out, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	hits := []SearchHit{
+		{EntityType: "gallery", EntityID: "a", Language: "en", Score: 0.04, Explain: &HitExplanation{}},
+		{EntityType: "gallery", EntityID: "c", Language: "en", Score: 0.03},
+		{EntityType: "gallery", EntityID: "b", Language: "en", Score: 0.02, Explain: &HitExplanation{}},
+		{EntityType: "gallery", EntityID: "d", Language: "en", Score: 0.01},
+	}
+	hits, failure := client.applyRerank(context.Background(), "naruto", hits, &RerankOptions{TopN: 3}, 0)
+	if failure != nil {
+		t.Fatalf("applyRerank: %v", failure)
+	}
+	if rr.query != "naruto" || !reflect.DeepEqual(rr.docs, []string{"x", "xxx"}) {
+		t.Fatalf("reranker got %q %v", rr.query, rr.docs)
+	}
+	// b outscores a; c has no text and d is below TopN, so both keep their
+	// fused order after the reranked hits.
+	var got []string
+	for _, h := range hits {
+		got = append(got, h.EntityID)
+	}
+	if !reflect.DeepEqual(got, []string{"b", "a", "c", "d"}) {
+		t.Fatalf("order = %v", got)
+	}
+	if hits[0].Score != 3 || hits[3].Score != 0.01 {
+		t.Fatalf("unexpected scores %+v", hits)
+	}
+	if e := hits[0].Explain.Rerank; e == nil || e.FusedRank != 3 || e.FusedScore != 0.02 || e.Score != 3 {
+		t.Fatalf("unexpected rerank explanation %+v", e)
+	}
+
+	rr.err = errors.New("boom")
+	before := append([]SearchHit(nil), hits...)
+	hits, failure = client.applyRerank(context.Background(), "naruto", hits, &RerankOptions{}, 0)
+	if failure == nil || failure.Backend != BackendRerank || !reflect.DeepEqual(hits, before) {
+		t.Fatalf("expected rerank failure with unchanged hits, got %v", failure)
+	}
+}
+
+func TestClientSearch_RerankRequiresReranker(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{Pool: newTestPool(t), Schema: "test"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	_, err = client.Search(context.Background(), "naruto", SearchOptions{
+		Mode:        SearchModeLexical,
+		EntityTypes: []string{"gallery"},
+		Rerank:      &RerankOptions{},
+	})
+	if err == nil || !strings.Contains(err.Error(), "Reranker is required") {
+		t.Fatalf("expected Reranker required error, got %v", err)
+	}
+}
+
+// reranker.HTTPReranker implements the client's Reranker.
+var _ Reranker = (*reranker.HTTPReranker)(nil)
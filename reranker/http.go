@@ -0,0 +1,197 @@
+package reranker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPFormat selects the request/response shape of an HTTP rerank endpoint.
+type HTTPFormat string
+
+const (
+	// FormatCohere is the `/v1/rerank` shape shared by Cohere, Jina, Voyage
+	// and most hosted rerankers:
+	//
+	//	{"model": ..., "query": ..., "documents": [...], "top_n": N}
+	//	=> {"results": [{"index": i, "relevance_score": s}, ...]}
+	FormatCohere HTTPFormat = "cohere"
+	// FormatTEI is Hugging Face text-embeddings-inference's `/rerank`:
+	//
+	//	{"query": ..., "texts": [...], "raw_scores": false}
+	//	=> [{"index": i, "score": s}, ...]
+	FormatTEI HTTPFormat = "tei"
+)
+
+type HTTPConfig struct {
+	// URL is the full rerank endpoint (e.g. https://api.jina.ai/v1/rerank).
+	URL    string
+	APIKey string // sent as a Bearer token when set
+	Model  string // optional for FormatTEI (the server serves one model)
+	// Format defaults to FormatCohere.
+	Format HTTPFormat
+	// Timeout bounds each HTTP request (default 30s).
+	Timeout time.Duration
+	// BatchSize is the maximum number of documents per request (default 32).
+	// Larger inputs are split into sequential requests.
+	BatchSize int
+	// HTTPClient overrides the default client (Timeout is then ignored).
+	HTTPClient *http.Client
+}
+
+// HTTPReranker calls an HTTP rerank endpoint; use it as
+// searchkit.ClientConfig.Reranker.
+type HTTPReranker struct {
+	client    *http.Client
+	url       string
+	apiKey    string
+	model     string
+	format    HTTPFormat
+	batchSize int
+}
+
+func NewHTTP(cfg HTTPConfig) (*HTTPReranker, error) {
+	if strings.TrimSpace(cfg.URL) == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+	format := cfg.Format
+	if format == "" {
+		format = FormatCohere
+	}
+	switch format {
+	case FormatCohere:
+		if strings.TrimSpace(cfg.Model) == "" {
+			return nil, fmt.Errorf("model is required")
+		}
+	case FormatTEI:
+	default:
+		return nil, fmt.Errorf("invalid format %q", cfg.Format)
+	}
+	client := cfg.HTTPClient
+	if client == nil {
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+	batchSize := cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = 32
+	}
+	return &HTTPReranker{
+		client:    client,
+		url:       strings.TrimSpace(cfg.URL),
+		apiKey:    cfg.APIKey,
+		model:     strings.TrimSpace(cfg.Model),
+		format:    format,
+		batchSize: batchSize,
+	}, nil
+}
+
+func (r *HTTPReranker) Rerank(ctx context.Context, query string, documents []string) ([]float32, error) {
+	out := make([]float32, len(documents))
+	for start := 0; start < len(documents); start += r.batchSize {
+		end := min(start+r.batchSize, len(documents))
+		scores, err := r.rerankBatch(ctx, query, documents[start:end])
+		if err != nil {
+			return nil, err
+		}
+		copy(out[start:end], scores)
+	}
+	return out, nil
+}
+
+type cohereRequest struct {
+	Model           string   `json:"model"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+type teiRequest struct {
+	Query     string   `json:"query"`
+	Texts     []string `json:"texts"`
+	RawScores bool     `json:"raw_scores"`
+}
+
+type rerankResult struct {
+	Index          int      `json:"index"`
+	RelevanceScore *float64 `json:"relevance_score"`
+	Score          *float64 `json:"score"`
+}
+
+func (r *HTTPReranker) rerankBatch(ctx context.Context, query string, documents []string) ([]float32, error) {
+	var body any
+	switch r.format {
+	case FormatTEI:
+		body = teiRequest{Query: query, Texts: documents}
+	default:
+		body = cohereRequest{Model: r.model, Query: query, Documents: documents, TopN: len(documents)}
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("rerank: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var results []rerankResult
+	switch r.format {
+	case FormatTEI:
+		err = json.NewDecoder(resp.Body).Decode(&results)
+	default:
+		var wrapped struct {
+			Results []rerankResult `json:"results"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&wrapped)
+		results = wrapped.Results
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rerank: decode response: %w", err)
+	}
+	if len(results) != len(documents) {
+		return nil, fmt.Errorf("expected %d rerank results, got %d", len(documents), len(results))
+	}
+
+	out := make([]float32, len(documents))
+	seen := make([]bool, len(documents))
+	for _, res := range results {
+		if res.Index < 0 || res.Index >= len(documents) || seen[res.Index] {
+			return nil, fmt.Errorf("rerank: invalid result index %d", res.Index)
+		}
+		seen[res.Index] = true
+		switch {
+		case res.RelevanceScore != nil:
+			out[res.Index] = float32(*res.RelevanceScore)
+		case res.Score != nil:
+			out[res.Index] = float32(*res.Score)
+		default:
+			return nil, fmt.Errorf("rerank: result %d has no score", res.Index)
+		}
+	}
+	return out, nil
+}
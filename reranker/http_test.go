@@ -0,0 +1,118 @@
+package reranker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHTTPReranker_CohereBatches(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		var req cohereRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		if req.Model != "jina-reranker-v2" || req.Query != "naruto" || req.TopN != len(req.Documents) {
+			t.Errorf("unexpected request %+v", req)
+		}
+		mu.Lock()
+		batches = append(batches, req.Documents)
+		mu.Unlock()
+
+		// Results come back sorted by relevance, not input order.
+		type result struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		}
+		var results []result
+		for i := len(req.Documents) - 1; i >= 0; i-- {
+			results = append(results, result{Index: i, RelevanceScore: float64(len(req.Documents[i])) / 10})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"results": results})
+	}))
+	defer srv.Close()
+
+	r, err := NewHTTP(HTTPConfig{URL: srv.URL + "/v1/rerank", APIKey: "secret", Model: "jina-reranker-v2", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("NewHTTP: %v", err)
+	}
+	scores, err := r.Rerank(context.Background(), "naruto", []string{"a", "bb", "ccc"})
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	if !reflect.DeepEqual(scores, []float32{0.1, 0.2, 0.3}) {
+		t.Fatalf("scores = %v", scores)
+	}
+	if !reflect.DeepEqual(batches, [][]string{{"a", "bb"}, {"ccc"}}) {
+		t.Fatalf("batches = %v", batches)
+	}
+}
+
+func TestHTTPReranker_TEI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req teiRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Texts) != 2 {
+			t.Errorf("unexpected request %+v (%v)", req, err)
+		}
+		_, _ = w.Write([]byte(`[{"index":1,"score":0.9},{"index":0,"score":0.2}]`))
+	}))
+	defer srv.Close()
+
+	r, err := NewHTTP(HTTPConfig{URL: srv.URL + "/rerank", Format: FormatTEI})
+	if err != nil {
+		t.Fatalf("NewHTTP: %v", err)
+	}
+	scores, err := r.Rerank(context.Background(), "q", []string{"x", "y"})
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	if !reflect.DeepEqual(scores, []float32{0.2, 0.9}) {
+		t.Fatalf("scores = %v", scores)
+	}
+}
+
+func TestHTTPReranker_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/short":
+			_, _ = w.Write([]byte(`{"results":[{"index":0,"relevance_score":1}]}`))
+		default:
+			http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		path string
+		want string
+	}{
+		{path: "/down", want: "model not loaded"},
+		{path: "/short", want: "expected 2 rerank results"},
+		{path: "/slow", want: "Client.Timeout"},
+	} {
+		r, err := NewHTTP(HTTPConfig{URL: srv.URL + tc.path, Model: "m", Timeout: 50 * time.Millisecond})
+		if err != nil {
+			t.Fatalf("NewHTTP: %v", err)
+		}
+		if _, err := r.Rerank(context.Background(), "q", []string{"x", "y"}); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("%s: err = %v; want %q", tc.path, err, tc.want)
+		}
+	}
+
+	if _, err := NewHTTP(HTTPConfig{URL: srv.URL}); err == nil {
+		t.Fatalf("expected model required error")
+	}
+}
@@ -0,0 +1,284 @@
+package searchkit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/open-rails/searchkit/internal/lru"
+	"github.com/open-rails/searchkit/pg"
+	"golang.org/x/sync/singleflight"
+)
+
+// ResultCache stores Search/Typeahead responses.
+//
+// Keys already encode everything that determines a response (normalized
+// query, languages, entity types, model, filter fingerprint, page) plus the
+// index generation, so implementations never need explicit invalidation:
+// entries from older generations are simply never read again and age out.
+// Implementations must be safe for concurrent use.
+type ResultCache interface {
+	Get(key string) (value any, ok bool)
+	Set(key string, value any, ttl time.Duration)
+}
+
+// NewLRUResultCache returns an in-process ResultCache holding up to size
+// entries (default 10000).
+func NewLRUResultCache(size int) ResultCache {
+	if size <= 0 {
+		size = 10000
+	}
+	return lruResultCache{cache: lru.New[string, any](size)}
+}
+
+type lruResultCache struct {
+	cache *lru.Cache[string, any]
+}
+
+func (c lruResultCache) Get(key string) (any, bool) { return c.cache.Get(key) }
+
+func (c lruResultCache) Set(key string, value any, ttl time.Duration) {
+	c.cache.Set(key, value, ttl)
+}
+
+// generationTracker caches the schema's index generation for a short interval
+// so cache lookups do not cost a query each. Concurrent refreshes share one
+// query; readers never wait on a lock. A failed read is also remembered for
+// the interval, so a schema without `search_generation` is not queried on
+// every request.
+type generationTracker struct {
+	state atomic.Pointer[generationState]
+	group singleflight.Group
+	every time.Duration
+}
+
+type generationState struct {
+	gen       int64
+	err       error
+	fetchedAt time.Time
+}
+
+// generationQueryTimeout bounds the shared generation query, which does not
+// run under any single caller's context.
+const generationQueryTimeout = 5 * time.Second
+
+func (g *generationTracker) set(gen int64, fetchedAt time.Time) {
+	g.state.Store(&generationState{gen: gen, fetchedAt: fetchedAt})
+}
+
+func (c *Client) currentGeneration(ctx context.Context) (int64, error) {
+	g := &c.generation
+	if st := g.state.Load(); st != nil && time.Since(st.fetchedAt) < g.every {
+		return st.gen, st.err
+	}
+	ch := g.group.DoChan("generation", func() (any, error) {
+		// Detached from the caller that started the refresh, so its
+		// cancellation does not fail the others waiting on it.
+		qctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), generationQueryTimeout)
+		defer cancel()
+		gen, err := pg.SearchGeneration(qctx, c.pool, c.schema)
+		if err != nil {
+			g.state.Store(&generationState{err: err, fetchedAt: time.Now()})
+			return int64(0), err
+		}
+		g.set(gen, time.Now())
+		return gen, nil
+	})
+	select {
+	case r := <-ch:
+		if r.Err != nil {
+			return 0, r.Err
+		}
+		return r.Val.(int64), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+// generationValue caches a value derived from Postgres (synonyms, rules) until
+// the index generation changes. Concurrent reloads share one query that runs
+// detached from the callers' contexts, and readers never wait on a lock.
+//
+// These values refine results but are not required to produce them, so a
+// failed load (e.g. the table's migration was not applied) keeps the previous
+// value (the zero value before the first load) and is retried after the
+// generation refresh interval rather than failing the request.
+type generationValue[T any] struct {
+	state atomic.Pointer[generationValueState[T]]
+	group singleflight.Group
+}
+
+type generationValueState[T any] struct {
+	val      T
+	gen      int64
+	failedAt time.Time // zero when val was loaded for gen
+}
+
+func (v *generationValue[T]) get(ctx context.Context, c *Client, load func(context.Context) (T, error)) T {
+	st := v.state.Load()
+	var cur T
+	if st != nil {
+		cur = st.val
+	}
+	gen, err := c.currentGeneration(ctx)
+	if err != nil {
+		return cur
+	}
+	if st != nil && st.gen == gen && (st.failedAt.IsZero() || time.Since(st.failedAt) < c.generation.every) {
+		return cur
+	}
+	ch := v.group.DoChan("load", func() (any, error) {
+		qctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), generationQueryTimeout)
+		defer cancel()
+		next := &generationValueState[T]{gen: gen}
+		if val, err := load(qctx); err != nil {
+			next.val, next.failedAt = cur, time.Now()
+		} else {
+			next.val = val
+		}
+		v.state.Store(next)
+		return next, nil
+	})
+	select {
+	case r := <-ch:
+		return r.Val.(*generationValueState[T]).val
+	case <-ctx.Done():
+		return cur
+	}
+}
+
+// resultCacheKey returns the cache key for a request, or "" when caching is
+// disabled or the generation cannot be read (the request then bypasses the
+// cache rather than failing).
+func (c *Client) resultCacheKey(ctx context.Context, kind string, ttl time.Duration, parts ...string) string {
+	if c.resultCache == nil || ttl <= 0 {
+		return ""
+	}
+	gen, err := c.currentGeneration(ctx)
+	if err != nil {
+		return ""
+	}
+	return kind + ":" + queryFingerprint(append(parts, fmt.Sprint(gen))...)
+}
+
+func (c *Client) cachedSearch(key string) (*SearchResult, bool) {
+	if key == "" {
+		return nil, false
+	}
+	v, ok := c.resultCache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	res, ok := v.(*SearchResult)
+	if !ok {
+		return nil, false
+	}
+	// Hand out a copy so callers cannot mutate the cached response.
+	return cloneSearchResult(res), true
+}
+
+func (c *Client) storeSearch(key string, res *SearchResult) {
+	// Degraded responses are not cached: the failing backend may be back on the
+	// next request.
+	if key == "" || len(res.Failures) > 0 || (res.Facets != nil && len(res.Facets.Failures) > 0) {
+		return
+	}
+	c.resultCache.Set(key, cloneSearchResult(res), c.searchCacheTTL)
+}
+
+func (c *Client) cachedTypeahead(key string) (*TypeaheadResult, bool) {
+	if key == "" {
+		return nil, false
+	}
+	v, ok := c.resultCache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	res, ok := v.(*TypeaheadResult)
+	if !ok {
+		return nil, false
+	}
+	return cloneTypeaheadResult(res), true
+}
+
+func (c *Client) storeTypeahead(key string, res *TypeaheadResult) {
+	if key == "" {
+		return
+	}
+	c.resultCache.Set(key, cloneTypeaheadResult(res), c.typeaheadCacheTTL)
+}
+
+// cloneSearchResult deep-copies res, so neither the caller that stored it nor
+// the callers served from the cache share slices or pointers with the cached
+// entry.
+func cloneSearchResult(res *SearchResult) *SearchResult {
+	out := *res
+	out.Hits = cloneSearchHits(res.Hits)
+	out.Failures = slices.Clone(res.Failures)
+	if res.Facets != nil {
+		facets := *res.Facets
+		facets.Counts = slices.Clone(res.Facets.Counts)
+		facets.Failures = slices.Clone(res.Facets.Failures)
+		out.Facets = &facets
+	}
+	if res.Groups != nil {
+		out.Groups = make([]HitGroup, len(res.Groups))
+		for i, g := range res.Groups {
+			out.Groups[i] = HitGroup{EntityType: g.EntityType, Hits: cloneSearchHits(g.Hits)}
+		}
+	}
+	out.MatchedRules = slices.Clone(res.MatchedRules)
+	return &out
+}
+
+func cloneSearchHits(hits []SearchHit) []SearchHit {
+	if hits == nil {
+		return nil
+	}
+	out := make([]SearchHit, len(hits))
+	for i, h := range hits {
+		h.LanguageVariants = slices.Clone(h.LanguageVariants)
+		if h.Explain != nil {
+			ex := *h.Explain
+			ex.Lists = slices.Clone(ex.Lists)
+			if ex.Boost != nil {
+				b := *ex.Boost
+				ex.Boost = &b
+			}
+			if ex.Rerank != nil {
+				r := *ex.Rerank
+				ex.Rerank = &r
+			}
+			h.Explain = &ex
+		}
+		h.Highlight = cloneHighlight(h.Highlight)
+		out[i] = h
+	}
+	return out
+}
+
+// cloneTypeaheadResult is cloneSearchResult for typeahead responses.
+func cloneTypeaheadResult(res *TypeaheadResult) *TypeaheadResult {
+	out := *res
+	if res.Hits != nil {
+		out.Hits = make([]TypeaheadHit, len(res.Hits))
+		for i, h := range res.Hits {
+			h.LanguageVariants = slices.Clone(h.LanguageVariants)
+			h.Highlight = cloneHighlight(h.Highlight)
+			out.Hits[i] = h
+		}
+	}
+	out.MatchedRules = slices.Clone(res.MatchedRules)
+	return &out
+}
+
+func cloneHighlight(h *Highlight) *Highlight {
+	if h == nil {
+		return nil
+	}
+	out := *h
+	out.Spans = slices.Clone(h.Spans)
+	return &out
+}
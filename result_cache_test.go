@@ -0,0 +1,138 @@
+package searchkit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResultCache_GenerationInvalidates(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{
+		Pool:        newTestPool(t),
+		Schema:      "test",
+		ResultCache: NewLRUResultCache(16),
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	// Pretend the generation was just read so no query is issued.
+	client.generation.set(7, time.Now())
+	client.generation.every = time.Hour
+
+	ctx := context.Background()
+	key := client.resultCacheKey(ctx, "search", client.searchCacheTTL, "fp", "", "20")
+	if key == "" {
+		t.Fatalf("expected cache key")
+	}
+	client.storeSearch(key, &SearchResult{Hits: []SearchHit{{EntityType: "gallery", EntityID: "1"}}})
+
+	res, ok := client.cachedSearch(key)
+	if !ok || len(res.Hits) != 1 {
+		t.Fatalf("expected cached result, got %+v ok=%v", res, ok)
+	}
+	res.Hits[0].EntityID = "mutated"
+	if again, _ := client.cachedSearch(key); again.Hits[0].EntityID != "1" {
+		t.Fatalf("cached hits were mutated through a returned result")
+	}
+
+	client.generation.set(8, time.Now())
+	if key2 := client.resultCacheKey(ctx, "search", client.searchCacheTTL, "fp", "", "20"); key2 == key {
+		t.Fatalf("expected key to change with generation")
+	}
+}
+
+func TestResultCache_SkipsDegradedResults(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{
+		Pool:        newTestPool(t),
+		Schema:      "test",
+		ResultCache: NewLRUResultCache(16),
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	client.storeSearch("k", &SearchResult{Failures: []BackendFailure{{Backend: BackendSemantic}}})
+	if _, ok := client.cachedSearch("k"); ok {
+		t.Fatalf("expected degraded result not to be cached")
+	}
+}
+
+func TestResultCache_CopiesResults(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{
+		Pool:        newTestPool(t),
+		Schema:      "test",
+		ResultCache: NewLRUResultCache(16),
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	newHit := func() SearchHit {
+		return SearchHit{
+			EntityType:       "gallery",
+			EntityID:         "1",
+			LanguageVariants: []string{"en", "ja"},
+			Explain: &HitExplanation{
+				Lists:  []ListContribution{{Backend: BackendFTS, Rank: 1}},
+				Boost:  &BoostExplanation{Multiplier: 1},
+				Rerank: &RerankExplanation{Score: 1},
+			},
+			Highlight: &Highlight{Text: "a", Spans: []HighlightSpan{{Start: 0, End: 1}}},
+		}
+	}
+	newResult := func() *SearchResult {
+		return &SearchResult{
+			Hits:         []SearchHit{newHit()},
+			Groups:       []HitGroup{{EntityType: "gallery", Hits: []SearchHit{newHit()}}},
+			MatchedRules: []string{"r1"},
+			Facets:       &FacetResult{Counts: []FacetCount{{EntityType: "gallery", Count: 1}}},
+		}
+	}
+	mutate := func(res *SearchResult) {
+		for _, hits := range [][]SearchHit{res.Hits, res.Groups[0].Hits} {
+			h := &hits[0]
+			h.LanguageVariants[0] = "x"
+			h.Explain.Lists[0].Rank = 9
+			h.Explain.Boost.Multiplier = 9
+			h.Explain.Rerank.Score = 9
+			h.Highlight.Text = "x"
+			h.Highlight.Spans[0].End = 9
+		}
+		res.MatchedRules[0] = "x"
+		res.Facets.Counts[0].Count = 9
+	}
+
+	stored := newResult()
+	client.storeSearch("k", stored)
+	mutate(stored)
+	got, ok := client.cachedSearch("k")
+	if !ok {
+		t.Fatalf("expected cached result")
+	}
+	if !reflect.DeepEqual(got, newResult()) {
+		t.Fatalf("cached result changed by the caller that stored it: %+v", got)
+	}
+	mutate(got)
+	again, _ := client.cachedSearch("k")
+	if !reflect.DeepEqual(again, newResult()) {
+		t.Fatalf("cached result changed by a caller served from the cache: %+v", again)
+	}
+
+	ta := &TypeaheadResult{
+		Hits:         []TypeaheadHit{{EntityID: "1", LanguageVariants: []string{"en"}, Highlight: &Highlight{Spans: []HighlightSpan{{End: 1}}}}},
+		MatchedRules: []string{"r1"},
+	}
+	client.storeTypeahead("t", ta)
+	ta.Hits[0].LanguageVariants[0] = "x"
+	ta.Hits[0].Highlight.Spans[0].End = 9
+	ta.MatchedRules[0] = "x"
+	gotTA, _ := client.cachedTypeahead("t")
+	if gotTA.Hits[0].LanguageVariants[0] != "en" || gotTA.Hits[0].Highlight.Spans[0].End != 1 || gotTA.MatchedRules[0] != "r1" {
+		t.Fatalf("cached typeahead result changed by the caller that stored it: %+v", gotTA)
+	}
+}
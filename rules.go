@@ -0,0 +1,429 @@
+package searchkit
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+	"github.com/open-rails/searchkit/pg"
+	"github.com/open-rails/searchkit/search"
+)
+
+type compiledRule struct {
+	pg.SearchRule
+	re *regexp.Regexp
+}
+
+// matches reports whether the rule applies to a request in language for the
+// normalized query key.
+func (r compiledRule) matches(language string, key string) bool {
+	if r.Language != "" && r.Language != language {
+		return false
+	}
+	switch r.Match {
+	case pg.RuleMatchExact:
+		return key == r.Pattern
+	case pg.RuleMatchPrefix:
+		return strings.HasPrefix(key, r.Pattern)
+	case pg.RuleMatchRegex:
+		return r.re != nil && r.re.MatchString(key)
+	default:
+		return false
+	}
+}
+
+// loadRules returns the enabled rules, highest priority first. They are
+// reloaded when the index generation changes, which
+// pg.UpsertSearchRule/DeleteSearchRule bump. Rules are optional, like
+// synonyms: when they cannot be loaded, queries run without them.
+func (c *Client) loadRules(ctx context.Context) []compiledRule {
+	return c.rules.get(ctx, c, func(ctx context.Context) ([]compiledRule, error) {
+		// Invalid rows are skipped by ListSearchRules; the others still apply.
+		rules, err := pg.ListSearchRules(ctx, c.pool, c.schema, "")
+		var invalid *pg.InvalidSearchRulesError
+		if err != nil && !errors.As(err, &invalid) {
+			return nil, err
+		}
+		compiled := make([]compiledRule, 0, len(rules))
+		for _, r := range rules {
+			if r.Disabled {
+				continue
+			}
+			cr := compiledRule{SearchRule: r}
+			if r.Match == pg.RuleMatchRegex {
+				// Patterns are validated on upsert; skip rules edited into an
+				// invalid state out of band.
+				if cr.re, err = regexp.Compile(r.Pattern); err != nil {
+					continue
+				}
+			}
+			compiled = append(compiled, cr)
+		}
+		return compiled, nil
+	})
+}
+
+// matchingRules returns the enabled rules matching userText in language,
+// highest priority first.
+func (c *Client) matchingRules(ctx context.Context, language string, userText string) []pg.SearchRule {
+	key := querynorm.SynonymKey(userText)
+	if key == "" {
+		return nil
+	}
+	language = strings.ToLower(strings.TrimSpace(language))
+	var out []pg.SearchRule
+	for _, r := range c.loadRules(ctx) {
+		if r.matches(language, key) {
+			out = append(out, r.SearchRule)
+		}
+	}
+	return out
+}
+
+// queryRewrite is the outcome of the rewrite/redirect rules for a query.
+type queryRewrite struct {
+	// query is the rewritten query ("" when no rewrite applied).
+	query    string
+	redirect string
+	// ruleID is the rule that rewrote or redirected the query.
+	ruleID string
+}
+
+// rewriteQuery applies the first matching rule (by priority) that has a
+// redirect or rewrite action; redirects win within that rule. Rewrites are
+// not chained.
+//
+// When the rules cannot be loaded the query is searched as typed; a database
+// outage is reported by retrieval.
+func (c *Client) rewriteQuery(ctx context.Context, language string, userText string) queryRewrite {
+	return rewriteWith(c.loadRules(ctx), strings.ToLower(strings.TrimSpace(language)), userText)
+}
+
+func rewriteWith(rules []compiledRule, language string, userText string) queryRewrite {
+	q := querynorm.QueryForEmbedding(userText)
+	words := querynorm.KeyWords(q)
+	if len(words) == 0 {
+		return queryRewrite{}
+	}
+	keyWords := make([]string, len(words))
+	for i, w := range words {
+		keyWords[i] = w.Word
+	}
+	key := strings.Join(keyWords, " ")
+	for _, r := range rules {
+		var rewrite, redirect *pg.RuleAction
+		for i := range r.Actions {
+			switch a := &r.Actions[i]; a.Type {
+			case pg.RuleActionRewrite:
+				if rewrite == nil {
+					rewrite = a
+				}
+			case pg.RuleActionRedirect:
+				if redirect == nil {
+					redirect = a
+				}
+			}
+		}
+		if (rewrite == nil && redirect == nil) || !r.matches(language, key) {
+			continue
+		}
+		if redirect != nil {
+			return queryRewrite{redirect: redirect.Target, ruleID: r.ID}
+		}
+		var edits []keyEdit
+		switch r.Match {
+		case pg.RuleMatchExact:
+			edits = []keyEdit{{start: 0, end: len(key), text: rewrite.Query}}
+		case pg.RuleMatchPrefix:
+			// "snk" rewrites "snk season 2", not "snky".
+			if len(key) > len(r.Pattern) && key[len(r.Pattern)] != ' ' {
+				continue
+			}
+			edits = []keyEdit{{start: 0, end: len(r.Pattern), text: rewrite.Query}}
+		case pg.RuleMatchRegex:
+			for _, m := range r.re.FindAllStringSubmatchIndex(key, -1) {
+				if m[0] == m[1] {
+					continue
+				}
+				edits = append(edits, keyEdit{start: m[0], end: m[1], text: string(r.re.ExpandString(nil, rewrite.Query, key, m))})
+			}
+		}
+		out := strings.Join(strings.Fields(applyKeyEdits(q, words, edits)), " ")
+		if out == q || querynorm.SynonymKey(out) == "" {
+			continue
+		}
+		return queryRewrite{query: out, ruleID: r.ID}
+	}
+	return queryRewrite{}
+}
+
+// keyEdit replaces the byte range [start, end) of a query key with text.
+type keyEdit struct {
+	start, end int
+	text       string
+}
+
+// applyKeyEdits applies edits (ordered, non-overlapping) made on the key of
+// q (words joined by single spaces) to q itself: each replaces the text the
+// matched key span came from, keeping everything around it, such as quotes,
+// `not` and `field:` prefixes.
+func applyKeyEdits(q string, words []querynorm.KeyWord, edits []keyEdit) string {
+	var b strings.Builder
+	pos := 0
+	for _, e := range edits {
+		start, end := keySpanToQuery(words, e.start, false), keySpanToQuery(words, e.end, true)
+		if start < pos || end < start {
+			continue
+		}
+		b.WriteString(q[pos:start])
+		b.WriteString(e.text)
+		pos = end
+	}
+	b.WriteString(q[pos:])
+	return b.String()
+}
+
+// keySpanToQuery maps a byte offset of the key to q. Inside a word whose
+// lowercase form changed length, the offset widens to the whole word (its
+// end when end is set).
+func keySpanToQuery(words []querynorm.KeyWord, off int, end bool) int {
+	ks := 0
+	for _, w := range words {
+		ke := ks + len(w.Word)
+		switch {
+		case off <= ks:
+			return w.Start
+		case off < ke:
+			if w.End-w.Start == len(w.Word) {
+				return w.Start + off - ks
+			}
+			if end {
+				return w.End
+			}
+			return w.Start
+		case off == ke:
+			return w.End
+		}
+		ks = ke + 1
+	}
+	return words[len(words)-1].End
+}
+
+// matchedRuleIDs lists the rewrite/redirect rule, then the rules in fx.
+func matchedRuleIDs(rw queryRewrite, fx *ruleEffects) []string {
+	var out []string
+	if rw.ruleID != "" {
+		out = append(out, rw.ruleID)
+	}
+	for _, id := range fx.ids {
+		if id != rw.ruleID {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// ruleEffects are the combined actions of the rules matching a request.
+type ruleEffects struct {
+	ids     []string
+	hidden  map[entityKey]struct{}
+	factors map[entityKey]float32
+	pins    []rulePin
+}
+
+type rulePin struct {
+	key      entityKey
+	position int
+}
+
+// newRuleEffects combines the actions of rules (highest priority first).
+// Hiding wins over every other action, and the first pin of an entity wins.
+func newRuleEffects(rules []pg.SearchRule) *ruleEffects {
+	fx := &ruleEffects{hidden: map[entityKey]struct{}{}, factors: map[entityKey]float32{}}
+	for _, r := range rules {
+		fx.ids = append(fx.ids, r.ID)
+		for _, a := range r.Actions {
+			if a.Type == pg.RuleActionHide {
+				fx.hidden[entityKey{a.EntityType, a.EntityID}] = struct{}{}
+			}
+		}
+	}
+	pinned := map[entityKey]struct{}{}
+	next := 1
+	for _, r := range rules {
+		for _, a := range r.Actions {
+			k := entityKey{a.EntityType, a.EntityID}
+			if _, ok := fx.hidden[k]; ok {
+				continue
+			}
+			switch a.Type {
+			case pg.RuleActionBoost, pg.RuleActionBury:
+				if _, ok := fx.factors[k]; !ok {
+					fx.factors[k] = 1
+				}
+				fx.factors[k] *= a.Factor
+			case pg.RuleActionPin:
+				if _, ok := pinned[k]; ok {
+					continue
+				}
+				pinned[k] = struct{}{}
+				pos := a.Position
+				if pos <= 0 {
+					pos = next
+				}
+				next = pos + 1
+				fx.pins = append(fx.pins, rulePin{key: k, position: pos})
+			}
+		}
+	}
+	sort.SliceStable(fx.pins, func(i, j int) bool { return fx.pins[i].position < fx.pins[j].position })
+	return fx
+}
+
+// hideAndScale drops hidden entities and applies boost/bury factors. rescored
+// reports whether any score changed (the caller re-sorts).
+func hideAndScale[H any](hits []H, fx *ruleEffects, key func(*H) entityKey, score func(*H) *float32) (out []H, rescored bool) {
+	if fx == nil || (len(fx.hidden) == 0 && len(fx.factors) == 0) {
+		return hits, false
+	}
+	out = hits[:0:0]
+	for _, h := range hits {
+		k := key(&h)
+		if _, ok := fx.hidden[k]; ok {
+			continue
+		}
+		if f, ok := fx.factors[k]; ok {
+			*score(&h) *= f
+			rescored = true
+		}
+		out = append(out, h)
+	}
+	return out, rescored
+}
+
+// missingPins returns the pinned entities of fx that are not in hits.
+func missingPins[H any](hits []H, fx *ruleEffects, key func(*H) entityKey) []entityKey {
+	if fx == nil || len(fx.pins) == 0 {
+		return nil
+	}
+	present := make(map[entityKey]struct{}, len(hits))
+	for i := range hits {
+		present[key(&hits[i])] = struct{}{}
+	}
+	var out []entityKey
+	for _, p := range fx.pins {
+		if _, ok := present[p.key]; !ok {
+			out = append(out, p.key)
+		}
+	}
+	return out
+}
+
+// pinScope is the part of a request that a pinned entity must satisfy to be
+// shown although retrieval did not return it.
+type pinScope struct {
+	languages   []string
+	entityTypes []string
+	filterSQL   string
+	filterArgs  map[string]any
+	constraints string
+}
+
+// resolvePins looks up the pinned entities missing from the ranking (see
+// missingPins) and returns the language of the first document of each that
+// passes scope, in scope.languages order. Pins without one (deleted, filtered
+// out, excluded, or of an entity type that was not searched) are dropped.
+func (c *Client) resolvePins(ctx context.Context, missing []entityKey, scope pinScope) (map[entityKey]string, error) {
+	out := map[entityKey]string{}
+	for _, lang := range scope.languages {
+		var keys []search.DocKey
+		for _, k := range missing {
+			if _, ok := out[k]; !ok {
+				keys = append(keys, search.DocKey{EntityType: k.entityType, EntityID: k.entityID})
+			}
+		}
+		if len(keys) == 0 {
+			break
+		}
+		found, err := search.MatchingDocuments(ctx, c.pool, keys, search.DocumentOptions{
+			Schema:      c.schema,
+			Language:    lang,
+			EntityTypes: scope.entityTypes,
+			FilterSQL:   scope.filterSQL,
+			FilterArgs:  scope.filterArgs,
+			Constraints: scope.constraints,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range found {
+			out[entityKey{k.EntityType, k.EntityID}] = k.Language
+		}
+	}
+	return out, nil
+}
+
+// pinHits moves pinned entities to their positions. Other language variants
+// of a pinned entity are dropped. Pinned entities missing from hits are
+// inserted with newHit, or dropped when it reports false (see resolvePins).
+//
+// Pins are applied last, after grouping and collapse, so they keep their
+// positions.
+func pinHits[H any](hits []H, fx *ruleEffects, key func(*H) entityKey, newHit func(entityKey) (H, bool)) []H {
+	if fx == nil || len(fx.pins) == 0 {
+		return hits
+	}
+	found := map[entityKey]H{}
+	pinned := map[entityKey]struct{}{}
+	for _, p := range fx.pins {
+		pinned[p.key] = struct{}{}
+	}
+	rest := hits[:0:0]
+	for _, h := range hits {
+		k := key(&h)
+		if _, ok := pinned[k]; !ok {
+			rest = append(rest, h)
+			continue
+		}
+		if _, ok := found[k]; !ok {
+			found[k] = h
+		}
+	}
+
+	out := rest
+	for _, p := range fx.pins {
+		h, ok := found[p.key]
+		if !ok {
+			if h, ok = newHit(p.key); !ok {
+				continue
+			}
+		}
+		i := p.position - 1
+		if i > len(out) {
+			i = len(out)
+		}
+		out = append(out, h)
+		copy(out[i+1:], out[i:])
+		out[i] = h
+	}
+	return out
+}
+
+func searchHitKey(h *SearchHit) entityKey {
+	return entityKey{h.EntityType, h.EntityID}
+}
+
+func searchHitScore(h *SearchHit) *float32 {
+	return &h.Score
+}
+
+func typeaheadHitKey(h *TypeaheadHit) entityKey {
+	return entityKey{h.EntityType, h.EntityID}
+}
+
+func typeaheadHitScore(h *TypeaheadHit) *float32 {
+	return &h.Score
+}
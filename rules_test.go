@@ -0,0 +1,132 @@
+package searchkit
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/open-rails/searchkit/pg"
+)
+
+func TestCompiledRuleMatches(t *testing.T) {
+	t.Parallel()
+
+	exact := compiledRule{SearchRule: pg.SearchRule{Match: pg.RuleMatchExact, Pattern: "naruto"}}
+	prefix := compiledRule{SearchRule: pg.SearchRule{Match: pg.RuleMatchPrefix, Pattern: "naru", Language: "en"}}
+	re := compiledRule{SearchRule: pg.SearchRule{Match: pg.RuleMatchRegex, Pattern: `^one ?piece\b`}, re: regexp.MustCompile(`^one ?piece\b`)}
+
+	for _, tc := range []struct {
+		rule     compiledRule
+		language string
+		key      string
+		want     bool
+	}{
+		{rule: exact, language: "en", key: "naruto", want: true},
+		{rule: exact, language: "en", key: "naruto shippuden", want: false},
+		{rule: prefix, language: "en", key: "naruto shippuden", want: true},
+		{rule: prefix, language: "ja", key: "naruto", want: false},
+		{rule: re, language: "es", key: "onepiece film red", want: true},
+		{rule: re, language: "es", key: "film one piece", want: false},
+	} {
+		if got := tc.rule.matches(tc.language, tc.key); got != tc.want {
+			t.Fatalf("%s %q matches(%q, %q) = %v; want %v", tc.rule.Match, tc.rule.Pattern, tc.language, tc.key, got, tc.want)
+		}
+	}
+}
+
+func TestApplyRuleEffects(t *testing.T) {
+	t.Parallel()
+
+	fx := newRuleEffects([]pg.SearchRule{
+		{ID: "promo", Actions: []pg.RuleAction{
+			{Type: pg.RuleActionPin, EntityType: "gallery", EntityID: "y"},
+			{Type: pg.RuleActionPin, EntityType: "gallery", EntityID: "new"},
+			{Type: pg.RuleActionPin, EntityType: "tag", EntityID: "t"},
+			{Type: pg.RuleActionBury, EntityType: "gallery", EntityID: "a", Factor: 0.1},
+		}},
+		{ID: "cleanup", Actions: []pg.RuleAction{
+			{Type: pg.RuleActionHide, EntityType: "gallery", EntityID: "z"},
+			{Type: pg.RuleActionPin, EntityType: "gallery", EntityID: "z", Position: 1},
+			{Type: pg.RuleActionPin, EntityType: "gallery", EntityID: "y", Position: 4},
+		}},
+	})
+	if !reflect.DeepEqual(fx.ids, []string{"promo", "cleanup"}) {
+		t.Fatalf("ids = %v", fx.ids)
+	}
+
+	hits := []SearchHit{
+		{EntityType: "gallery", EntityID: "a", Language: "en", Score: 0.05},
+		{EntityType: "gallery", EntityID: "z", Language: "en", Score: 0.04},
+		{EntityType: "gallery", EntityID: "b", Language: "en", Score: 0.03},
+		{EntityType: "gallery", EntityID: "y", Language: "en", Score: 0.02},
+		{EntityType: "gallery", EntityID: "y", Language: "es", Score: 0.01},
+	}
+	hits, rescored := hideAndScale(hits, fx, searchHitKey, searchHitScore)
+	if !rescored {
+		t.Fatalf("expected bury to rescore")
+	}
+	sortSearchHits(hits, languageChain{})
+	// missingPins lists what resolvePins looks up; the lookup found "new"
+	// but not the tag (its entity type was not searched).
+	missing := missingPins(hits, fx, searchHitKey)
+	if len(missing) != 2 {
+		t.Fatalf("missingPins = %v; want the new gallery and the tag", missing)
+	}
+	resolved := map[entityKey]string{{"gallery", "new"}: "en"}
+	hits = pinHits(hits, fx, searchHitKey, func(k entityKey) (SearchHit, bool) {
+		lang, ok := resolved[k]
+		return SearchHit{EntityType: k.entityType, EntityID: k.entityID, Language: lang}, ok
+	})
+
+	var got []string
+	for _, h := range hits {
+		got = append(got, h.EntityID+"/"+h.Language)
+	}
+	// y keeps its first pin (position 1), the inserted "new" follows it; the
+	// tag pin is dropped because tags were not searched; z is hidden.
+	want := []string{"y/en", "new/en", "b/en", "a/en"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("hits = %v; want %v", got, want)
+	}
+}
+
+func TestRewriteWith(t *testing.T) {
+	t.Parallel()
+
+	rules := []compiledRule{
+		{SearchRule: pg.SearchRule{ID: "landing", Match: pg.RuleMatchExact, Pattern: "one piece", Actions: []pg.RuleAction{
+			{Type: pg.RuleActionRewrite, Query: "ignored"},
+			{Type: pg.RuleActionRedirect, Target: "/series/one-piece"},
+		}}},
+		{SearchRule: pg.SearchRule{ID: "pin-only", Match: pg.RuleMatchPrefix, Pattern: "snk", Actions: []pg.RuleAction{
+			{Type: pg.RuleActionPin, EntityType: "gallery", EntityID: "1"},
+		}}},
+		{SearchRule: pg.SearchRule{ID: "snk", Match: pg.RuleMatchPrefix, Pattern: "snk", Language: "en", Actions: []pg.RuleAction{
+			{Type: pg.RuleActionRewrite, Query: "shingeki no kyojin"},
+		}}},
+		{SearchRule: pg.SearchRule{ID: "ep", Match: pg.RuleMatchRegex, Pattern: `\bep (\d+)`, Actions: []pg.RuleAction{
+			{Type: pg.RuleActionRewrite, Query: "episode $1"},
+		}}, re: regexp.MustCompile(`\bep (\d+)`)},
+	}
+	for _, tc := range []struct {
+		language string
+		query    string
+		want     queryRewrite
+	}{
+		{language: "en", query: "One Piece!", want: queryRewrite{redirect: "/series/one-piece", ruleID: "landing"}},
+		{language: "en", query: "SNK season 2", want: queryRewrite{query: "shingeki no kyojin season 2", ruleID: "snk"}},
+		{language: "ja", query: "snk", want: queryRewrite{}},
+		{language: "ja", query: "bleach ep 12", want: queryRewrite{query: "bleach episode 12", ruleID: "ep"}},
+		{language: "en", query: "naruto", want: queryRewrite{}},
+		// Prefixes are word-bounded.
+		{language: "en", query: "snky", want: queryRewrite{}},
+		// Only the matched span is replaced: quotes, `not`, field prefixes and
+		// the rest of the query are kept as typed.
+		{language: "en", query: `"SNK" not Spoilers`, want: queryRewrite{query: `"shingeki no kyojin" not Spoilers`, ruleID: "snk"}},
+		{language: "ja", query: "title:ep 12 not EP 3", want: queryRewrite{query: "title:episode 12 not episode 3", ruleID: "ep"}},
+	} {
+		if got := rewriteWith(rules, tc.language, tc.query); got != tc.want {
+			t.Fatalf("rewriteWith(%q, %q) = %+v; want %+v", tc.language, tc.query, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"context"
+	"time"
+
+	"github.com/open-rails/searchkit/internal/lru"
+	"github.com/open-rails/searchkit/pg"
+)
+
+var (
+	_ QueryVectorCache = (*LRUQueryVectorCache)(nil)
+	_ QueryVectorCache = (*pg.QueryVectorCache)(nil)
+)
+
+// QueryVectorCache caches query embeddings for EmbedQueryText.
+//
+// Keys are the model name plus the normalized query text (see
+// normalize.QueryForEmbedding). Implementations must be safe for concurrent
+// use. Errors are treated as cache misses: a broken cache never fails a search.
+//
+// searchkit ships two implementations:
+//   - NewLRUQueryVectorCache: in-process LRU with TTL.
+//   - pg.NewQueryVectorCache: a Postgres table shared by several app instances.
+type QueryVectorCache interface {
+	Get(ctx context.Context, model string, query string) (vec []float32, ok bool, err error)
+	Set(ctx context.Context, model string, query string, vec []float32) error
+}
+
+// LRUQueryVectorCache is an in-process QueryVectorCache with LRU eviction and
+// a per-entry TTL.
+type LRUQueryVectorCache struct {
+	ttl   time.Duration
+	cache *lru.Cache[lruKey, []float32]
+}
+
+type lruKey struct {
+	model string
+	query string
+}
+
+// NewLRUQueryVectorCache returns a cache holding up to size entries (default
+// 10000). ttl <= 0 disables expiry.
+func NewLRUQueryVectorCache(size int, ttl time.Duration) *LRUQueryVectorCache {
+	if size <= 0 {
+		size = 10000
+	}
+	return &LRUQueryVectorCache{
+		ttl:   ttl,
+		cache: lru.New[lruKey, []float32](size),
+	}
+}
+
+func (c *LRUQueryVectorCache) Get(_ context.Context, model string, query string) ([]float32, bool, error) {
+	vec, ok := c.cache.Get(lruKey{model: model, query: query})
+	if !ok {
+		return nil, false, nil
+	}
+	return cloneVec(vec), true, nil
+}
+
+func (c *LRUQueryVectorCache) Set(_ context.Context, model string, query string, vec []float32) error {
+	if len(vec) == 0 {
+		return nil
+	}
+	c.cache.Set(lruKey{model: model, query: query}, cloneVec(vec), c.ttl)
+	return nil
+}
+
+// Len returns the number of cached entries (including expired entries not yet
+// evicted).
+func (c *LRUQueryVectorCache) Len() int {
+	return c.cache.Len()
+}
+
+// cloneVec copies vectors in and out of caches so callers mutating a returned
+// vector (e.g. in-place normalization) cannot corrupt cached entries.
+func cloneVec(vec []float32) []float32 {
+	out := make([]float32, len(vec))
+	copy(out, vec)
+	return out
+}
@@ -0,0 +1,108 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/open-rails/searchkit/embedder"
+	"github.com/open-rails/searchkit/internal/normalize"
+)
+
+func TestLRUQueryVectorCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUQueryVectorCache(2, 0)
+
+	_ = c.Set(ctx, "m", "a", []float32{1})
+	_ = c.Set(ctx, "m", "b", []float32{2})
+	if _, ok, _ := c.Get(ctx, "m", "a"); !ok {
+		t.Fatalf("expected hit for a")
+	}
+	_ = c.Set(ctx, "m", "c", []float32{3})
+
+	if _, ok, _ := c.Get(ctx, "m", "b"); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	if _, ok, _ := c.Get(ctx, "m", "a"); !ok {
+		t.Fatalf("expected a to survive (recently used)")
+	}
+	if _, ok, _ := c.Get(ctx, "other", "a"); ok {
+		t.Fatalf("expected miss for a different model")
+	}
+}
+
+func TestLRUQueryVectorCache_TTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	c := NewLRUQueryVectorCache(10, time.Minute)
+	c.cache.SetClock(func() time.Time { return now })
+
+	_ = c.Set(ctx, "m", "q", []float32{1})
+	now = now.Add(59 * time.Second)
+	if _, ok, _ := c.Get(ctx, "m", "q"); !ok {
+		t.Fatalf("expected hit before expiry")
+	}
+	now = now.Add(time.Second)
+	if _, ok, _ := c.Get(ctx, "m", "q"); ok {
+		t.Fatalf("expected miss after expiry")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expected expired entry to be dropped, len=%d", c.Len())
+	}
+}
+
+func TestLRUQueryVectorCache_CopiesVectors(t *testing.T) {
+	ctx := context.Background()
+	c := NewLRUQueryVectorCache(10, 0)
+
+	in := []float32{1, 2}
+	_ = c.Set(ctx, "m", "q", in)
+	in[0] = 9
+
+	got, _, _ := c.Get(ctx, "m", "q")
+	got[1] = 9
+
+	again, _, _ := c.Get(ctx, "m", "q")
+	if again[0] != 1 || again[1] != 2 {
+		t.Fatalf("cached vector was mutated: %v", again)
+	}
+}
+
+type recordingTextEmbedder struct {
+	texts []string
+}
+
+func (e *recordingTextEmbedder) Model() string   { return "m" }
+func (e *recordingTextEmbedder) Dimensions() int { return 2 }
+func (e *recordingTextEmbedder) EmbedText(_ context.Context, text string) ([]float32, error) {
+	e.texts = append(e.texts, text)
+	return []float32{3, 4}, nil
+}
+func (e *recordingTextEmbedder) EmbedTexts(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i], _ = e.EmbedText(ctx, text)
+	}
+	return out, nil
+}
+
+func TestEmbedQueryText_SameTextWithAndWithoutCache(t *testing.T) {
+	ctx := context.Background()
+	for _, cache := range []QueryVectorCache{nil, NewLRUQueryVectorCache(10, 0)} {
+		emb := &recordingTextEmbedder{}
+		r := &Runtime{textEmbedders: map[string]embedder.Embedder{"m": emb}, queryCache: cache}
+		for i := 0; i < 2; i++ {
+			vec, err := r.EmbedQueryText(ctx, "m", "  Two   Factor ")
+			if err != nil {
+				t.Fatalf("EmbedQueryText: %v", err)
+			}
+			if vec[0] != 0.6 || vec[1] != 0.8 {
+				t.Fatalf("vec = %v; want full-precision normalized [0.6 0.8]", vec)
+			}
+		}
+		want := normalize.QueryForEmbedding("  Two   Factor ")
+		if emb.texts[0] != want {
+			t.Fatalf("embedded %q; want %q (cache %v)", emb.texts[0], want, cache != nil)
+		}
+	}
+}
@@ -35,6 +35,35 @@ type BuildSemanticDocument func(ctx context.Context, entityType string, language
 // description-like text.
 type BuildLexicalString func(ctx context.Context, entityType string, language string, entityIDs []string) (map[string]string, error)
 
+// LexicalDocument is a field-weighted lexical document: each field carries a
+// tsvector weight A-D (conventionally title, aliases, tags, body).
+type LexicalDocument = pg.LexicalDocument
+
+// LexicalField is one weighted field of a LexicalDocument.
+type LexicalField = pg.LexicalField
+
+// BuildLexicalDocument is the structured alternative to BuildLexicalString:
+// FTS ranks matches by field weight, and trigram/PGroonga boost matches in
+// the A-weight (title) text.
+type BuildLexicalDocument func(ctx context.Context, entityType string, language string, entityIDs []string) (map[string]LexicalDocument, error)
+
+// Attributes is a flat map of filterable document attributes.
+type Attributes = pg.Attributes
+
+// BuildAttributes returns filterable attributes (status, live_at, rating,
+// ...) for a batch of entities in a specific language. They are stored next
+// to lexical documents and embeddings so search.Attr* filters run without
+// joining host tables. Entities missing from the map keep their attributes.
+type BuildAttributes func(ctx context.Context, entityType string, language string, entityIDs []string) (map[string]Attributes, error)
+
+// Signals are per-entity ranking signals (static rank and timestamp).
+type Signals = pg.Signals
+
+// BuildSignals returns ranking signals for a batch of entities. They are
+// language-independent and used by SearchOptions.Boosts. Entities missing
+// from the map keep their signals.
+type BuildSignals func(ctx context.Context, entityType string, entityIDs []string) (map[string]Signals, error)
+
 type Runtime struct {
 	textEmbedders map[string]embedder.Embedder
 	vlEmbedders   map[string]vl.Embedder
@@ -44,7 +73,12 @@ type Runtime struct {
 
 	buildSemantic BuildSemanticDocument
 	buildLexical  BuildLexicalString
+	buildLexDoc   BuildLexicalDocument
+	buildAttrs    BuildAttributes
+	buildSignals  BuildSignals
 	listAssetURLs vl.ListAssetURLs
+
+	queryCache QueryVectorCache
 }
 
 type Options struct {
@@ -62,10 +96,21 @@ type Options struct {
 	// Optional: only needed if you want searchkit-managed lexical (trigram)
 	// document storage/backfill.
 	BuildLexicalString BuildLexicalString
+	// Optional: field-weighted lexical documents. Takes precedence over
+	// BuildLexicalString when both are set.
+	BuildLexicalDocument BuildLexicalDocument
+	// Optional: filterable attributes stored on documents and embeddings.
+	BuildAttributes BuildAttributes
+	// Optional: ranking signals for recency/popularity boosting.
+	BuildSignals BuildSignals
 
 	// Required if VLEmbedders is non-empty.
 	ListAssetURLs vl.ListAssetURLs
 
+	// Optional: caches EmbedQueryText results (e.g. NewLRUQueryVectorCache or
+	// pg.NewQueryVectorCache).
+	QueryCache QueryVectorCache
+
 	// Optional overrides (primarily for tests).
 	TaskRepo *tasks.Repo
 	Storage  *pg.PostgresStorage
@@ -84,8 +129,8 @@ func New(opts Options) (*Runtime, error) {
 	if hasEmbedders && opts.BuildSemanticDocument == nil {
 		return nil, fmt.Errorf("BuildSemanticDocument is required when embedders are configured")
 	}
-	if !hasEmbedders && opts.BuildLexicalString == nil {
-		return nil, fmt.Errorf("at least one embedder or BuildLexicalString is required")
+	if !hasEmbedders && opts.BuildLexicalString == nil && opts.BuildLexicalDocument == nil {
+		return nil, fmt.Errorf("at least one embedder or BuildLexicalString/BuildLexicalDocument is required")
 	}
 
 	textMap := make(map[string]embedder.Embedder, len(opts.TextEmbedders))
@@ -132,7 +177,11 @@ func New(opts Options) (*Runtime, error) {
 		storage:       store,
 		buildSemantic: opts.BuildSemanticDocument,
 		buildLexical:  opts.BuildLexicalString,
+		buildLexDoc:   opts.BuildLexicalDocument,
+		buildAttrs:    opts.BuildAttributes,
+		buildSignals:  opts.BuildSignals,
 		listAssetURLs: opts.ListAssetURLs,
+		queryCache:    opts.QueryCache,
 	}, nil
 }
 
@@ -230,6 +279,54 @@ func (r *Runtime) BuildLexicalString(ctx context.Context, entityType string, lan
 	return r.buildLexical(ctx, entityType, language, entityIDs)
 }
 
+// HasLexicalDocuments reports whether a structured BuildLexicalDocument
+// callback is configured.
+func (r *Runtime) HasLexicalDocuments() bool {
+	return r.buildLexDoc != nil
+}
+
+// BuildLexicalDocuments is the structured counterpart of BuildLexicalString.
+func (r *Runtime) BuildLexicalDocuments(ctx context.Context, entityType string, language string, entityIDs []string) (map[string]LexicalDocument, error) {
+	if r.buildLexDoc == nil {
+		return nil, fmt.Errorf("BuildLexicalDocument not configured")
+	}
+	return r.buildLexDoc(ctx, entityType, language, entityIDs)
+}
+
+// RefreshAttributes builds attributes for entityIDs and stores them on their
+// existing search_documents and embedding_vectors rows, reporting whether a
+// row changed. It does not bump the search generation: callers refreshing
+// several batches bump it once (see BumpSearchGeneration). It is a no-op when
+// no BuildAttributes callback is configured.
+func (r *Runtime) RefreshAttributes(ctx context.Context, entityType string, language string, entityIDs []string) (bool, error) {
+	if r.buildAttrs == nil || len(entityIDs) == 0 {
+		return false, nil
+	}
+	attrs, err := r.buildAttrs(ctx, entityType, language, entityIDs)
+	if err != nil {
+		return false, err
+	}
+	return r.storage.StoreAttributes(ctx, entityType, language, attrs)
+}
+
+// BumpSearchGeneration invalidates cached search results after index writes.
+func (r *Runtime) BumpSearchGeneration(ctx context.Context) error {
+	return r.storage.BumpSearchGeneration(ctx)
+}
+
+// RefreshSignals builds ranking signals for entityIDs and stores them. It is a
+// no-op when no BuildSignals callback is configured.
+func (r *Runtime) RefreshSignals(ctx context.Context, entityType string, entityIDs []string) error {
+	if r.buildSignals == nil || len(entityIDs) == 0 {
+		return nil
+	}
+	signals, err := r.buildSignals(ctx, entityType, entityIDs)
+	if err != nil {
+		return err
+	}
+	return r.storage.UpsertSignals(ctx, entityType, signals)
+}
+
 // ListAssetURLs is exposed for worker implementations that want to batch
 // hydration. The returned map contains assets for entities that exist.
 func (r *Runtime) ListAssetURLs(ctx context.Context, entityType string, entityIDs []string) (map[string][]vl.AssetURL, error) {
@@ -248,16 +345,35 @@ func (r *Runtime) IsVLModel(model string) bool {
 // using a configured text embedder.
 //
 // This is intended for host apps calling SemanticSearch at request time.
+//
+// The text is normalized with normalize.QueryForEmbedding (the same
+// normalization Client.Search applies) before embedding. When a QueryCache is
+// configured, vectors are cached per (model, normalized query).
 func (r *Runtime) EmbedQueryText(ctx context.Context, model string, text string) ([]float32, error) {
-	emb, ok := r.textEmbedders[strings.TrimSpace(model)]
+	model = strings.TrimSpace(model)
+	emb, ok := r.textEmbedders[model]
 	if !ok {
 		return nil, fmt.Errorf("model %q is not configured for text embeddings", model)
 	}
-	vec, err := emb.EmbedText(ctx, text)
+
+	q := normalize.QueryForEmbedding(text)
+	if q == "" {
+		q = text
+	}
+	if r.queryCache != nil {
+		if vec, hit, err := r.queryCache.Get(ctx, model, q); err == nil && hit && len(vec) > 0 {
+			return vec, nil
+		}
+	}
+	vec, err := emb.EmbedText(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 	normalize.L2NormalizeInPlace(vec)
+	if r.queryCache != nil {
+		// Cache write failures only cost a future provider call.
+		_ = r.queryCache.Set(ctx, model, q, vec)
+	}
 	return vec, nil
 }
 
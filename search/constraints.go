@@ -0,0 +1,159 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+)
+
+// Query constraints are the parts of a parsed query that a backend's own
+// match operator cannot express: field scopes (`title:x`) for trigram and
+// PGroonga, and exclusions (`not x`) for trigram and the semantic KNN. They
+// are enforced as conditions on the `search_documents` row aliased sd, inside
+// the retrieval query, so they compose with LIMIT, filters and pagination.
+//
+// Scoped terms match in a field of their weight; documents without fields
+// (flat documents) fall back to the unscoped match, as in FTSSearch. Exclusions are
+// compiled like FTSSearch compiles them (the language's text search config),
+// except for ja/zh/ko: their tsv is not segmented, so words match as
+// case-insensitive substrings of the raw text, which needs no PGroonga.
+
+// constraintParams names the arguments of constraint conditions, skipping
+// the names already bound by the query (several backends' constraints can
+// share one query, as in LexicalFacets).
+type constraintParams struct {
+	args  pgx.NamedArgs
+	bound pgx.NamedArgs
+}
+
+func (p *constraintParams) add(v any) string {
+	for i := len(p.args); ; i++ {
+		name := fmt.Sprintf("qc_%d", i)
+		if _, taken := p.bound[name]; taken {
+			continue
+		}
+		if _, taken := p.args[name]; taken {
+			continue
+		}
+		p.args[name] = v
+		return "@" + name
+	}
+}
+
+// queryConstraintsSQL returns the conditions enforcing q's field scopes
+// and/or exclusions, ANDed, or "" when there are none. Their arguments are
+// merged into args (a conflict with a FilterArgs name is an error). The
+// query must bind @language.
+func queryConstraintsSQL(quotedSchema string, language string, q querynorm.Query, scopes bool, exclusions bool, args pgx.NamedArgs) (string, error) {
+	p := constraintParams{args: pgx.NamedArgs{}, bound: args}
+	var conds []string
+	if scopes {
+		if c := fieldScopeSQL(q, &p); c != "" {
+			conds = append(conds, c)
+		}
+	}
+	if exclusions {
+		if c := exclusionSQL(quotedSchema, language, q, &p); c != "" {
+			conds = append(conds, "NOT coalesce("+c+", false)")
+		}
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	if err := mergeNamedArgs(args, p.args); err != nil {
+		return "", err
+	}
+	return strings.Join(conds, " AND "), nil
+}
+
+// fieldScopeSQL requires every positive clause with a field-scoped term to
+// match in documents with fields; flat documents are not constrained (the
+// backend matched them on the unscoped text). Returns "" when q has no field
+// scopes.
+func fieldScopeSQL(q querynorm.Query, p *constraintParams) string {
+	var conds []string
+	for _, c := range q.Clauses {
+		if c.Exclude || !(querynorm.Query{Clauses: []querynorm.Clause{c}}).HasFieldScopes() {
+			continue
+		}
+		conds = append(conds, termsMatchSQL(c.Terms, p))
+	}
+	if len(conds) == 0 {
+		return ""
+	}
+	return "(sd.fields IS NULL OR (" + strings.Join(conds, " AND ") + "))"
+}
+
+// termsMatchSQL matches any of terms by text containment: a scoped term in a
+// field of its weight (anywhere for flat documents), other terms anywhere in
+// the raw document. Every word must match; a phrase matches as a whole.
+func termsMatchSQL(terms []querynorm.Term, p *constraintParams) string {
+	alts := make([]string, 0, len(terms))
+	for _, t := range terms {
+		patterns := strings.Fields(t.Text)
+		if t.Phrase {
+			patterns = []string{t.Text}
+		}
+		conds := make([]string, 0, len(patterns))
+		for _, pattern := range patterns {
+			param := p.add("%" + escapeLikePattern(pattern) + "%")
+			anywhere := fmt.Sprintf("sd.raw_document ILIKE %s", param)
+			weight := querynorm.FieldWeights[t.Field]
+			if weight == "" {
+				conds = append(conds, anywhere)
+				continue
+			}
+			conds = append(conds, fmt.Sprintf(
+				"((sd.fields IS NULL AND %s) OR EXISTS (SELECT 1 FROM jsonb_array_elements(sd.fields) f WHERE f->>'weight' = '%s' AND f->>'text' ILIKE %s))",
+				anywhere, weight, param))
+		}
+		alts = append(alts, "("+strings.Join(conds, " AND ")+")")
+	}
+	return "(" + strings.Join(alts, " OR ") + ")"
+}
+
+// exclusionSQL is true for documents matching any excluded clause of q (NULL
+// when the document has no tsv). Returns "" when q has no exclusions.
+func exclusionSQL(quotedSchema string, language string, q querynorm.Query, p *constraintParams) string {
+	excluded := q.Exclusions().AnyOf()
+	if len(excluded.Clauses) == 0 {
+		return ""
+	}
+	if cjkLanguage(language) {
+		return termsMatchSQL(excluded.Clauses[0].Terms, p)
+	}
+	// The tsquery (and the language's config) is computed once per query.
+	tsquery := func(q querynorm.Query) string {
+		return fmt.Sprintf("(SELECT %s FROM %s)", bindTSQuery(q, p), tsqueryConfigFrom(quotedSchema))
+	}
+	if !excluded.HasFieldScopes() {
+		return "sd.tsv @@ " + tsquery(excluded)
+	}
+	return fmt.Sprintf("((sd.fields IS NOT NULL AND sd.tsv @@ %s) OR (sd.fields IS NULL AND sd.tsv @@ %s))", tsquery(excluded), tsquery(excluded.Unscoped()))
+}
+
+// bindTSQuery compiles q to a tsquery expression over `cfg.config` (see
+// tsqueryConfigFrom), binding the term texts through p.
+func bindTSQuery(q querynorm.Query, p *constraintParams) string {
+	return q.TSQuerySQL("cfg.config", func(text string) string { return p.add(text) })
+}
+
+// tsqueryConfigFrom is a FROM item binding `cfg.config` to the text search
+// config of @language.
+func tsqueryConfigFrom(quotedSchema string) string {
+	return fmt.Sprintf("(SELECT %s.searchkit_regconfig_for_language(@language) AS config) cfg", quotedSchema)
+}
+
+// cjkLanguage reports whether language is stored without word segmentation
+// in tsv (ja/zh/ko use the `simple` config).
+func cjkLanguage(language string) bool {
+	switch strings.ToLower(strings.TrimSpace(language)) {
+	case "ja", "zh", "ko":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,164 @@
+package search
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	pgvector "github.com/pgvector/pgvector-go"
+)
+
+// Field scopes and exclusions are enforced inside each backend's query, on
+// flat and field-weighted documents alike.
+func TestQueryConstraints_Integration(t *testing.T) {
+	dsn := os.Getenv("SEARCHKIT_TEST_URL")
+	if dsn == "" {
+		t.Skip("SEARCHKIT_TEST_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("pgxpool: %v", err)
+	}
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE EXTENSION IF NOT EXISTS pg_trgm;
+		CREATE EXTENSION IF NOT EXISTS vector;
+		DROP SCHEMA IF EXISTS s_constraints CASCADE;
+		CREATE SCHEMA s_constraints;
+
+		CREATE FUNCTION s_constraints.searchkit_regconfig_for_language(lang text)
+		RETURNS regconfig
+		LANGUAGE sql
+		IMMUTABLE
+		AS $$
+			SELECT 'simple'::regconfig
+		$$;
+
+		CREATE TABLE s_constraints.search_documents (
+			entity_type text NOT NULL,
+			entity_id text NOT NULL,
+			language text NOT NULL,
+			document text,
+			raw_document text,
+			title text,
+			fields jsonb,
+			tsv tsvector,
+			PRIMARY KEY (entity_type, entity_id, language)
+		);
+
+		CREATE TABLE s_constraints.embedding_vectors (
+			entity_type text NOT NULL,
+			entity_id text NOT NULL,
+			model text NOT NULL,
+			language text NOT NULL,
+			embedding halfvec,
+			PRIMARY KEY (entity_type, entity_id, model, language)
+		);
+
+		-- 1 is flat; 2 has "cat" in its title; 3 has "cat" in its body only.
+		INSERT INTO s_constraints.search_documents (entity_type, entity_id, language, document, raw_document, title, fields, tsv) VALUES
+			('gallery', '1', 'en', 'black cat on a mat', 'Black cat on a mat', NULL, NULL,
+				to_tsvector('simple', 'Black cat on a mat')),
+			('gallery', '2', 'en', 'black cat dog', 'Black cat dog', 'black cat',
+				'[{"weight": "A", "text": "Black cat"}, {"weight": "D", "text": "dog"}]',
+				setweight(to_tsvector('simple', 'Black cat'), 'A') || setweight(to_tsvector('simple', 'dog'), 'D')),
+			('gallery', '3', 'en', 'dog black cat', 'Dog black cat', 'dog',
+				'[{"weight": "A", "text": "Dog"}, {"weight": "D", "text": "black cat"}]',
+				setweight(to_tsvector('simple', 'Dog'), 'A') || setweight(to_tsvector('simple', 'black cat'), 'D'));
+	`)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	// 4 has a vector but no lexical document.
+	for _, id := range []string{"1", "2", "3", "4"} {
+		if _, err := pool.Exec(ctx, `
+			INSERT INTO s_constraints.embedding_vectors (entity_type, entity_id, model, language, embedding)
+			VALUES ('gallery', $1, 'm', 'en', $2::halfvec(3))
+		`, id, pgvector.NewHalfVector([]float32{1, 0, 0})); err != nil {
+			t.Fatalf("insert embedding_vectors: %v", err)
+		}
+	}
+
+	fts := func(q string) []string {
+		t.Helper()
+		hits, err := FTSSearch(ctx, pool, q, FTSOptions{Schema: "s_constraints", Language: "en", Limit: 10})
+		if err != nil {
+			t.Fatalf("FTSSearch(%q): %v", q, err)
+		}
+		var ids []string
+		for _, h := range hits {
+			ids = append(ids, h.EntityID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	trigram := func(probe, q string) []string {
+		t.Helper()
+		hits, err := LexicalSearch(ctx, pool, probe, LexicalOptions{
+			Schema: "s_constraints", Language: "en", Limit: 10, MinSimilarity: 0.1, Constraints: q,
+		})
+		if err != nil {
+			t.Fatalf("LexicalSearch(%q): %v", q, err)
+		}
+		var ids []string
+		for _, h := range hits {
+			ids = append(ids, h.EntityID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	semantic := func(q string) []string {
+		t.Helper()
+		hits, err := SemanticSearch(ctx, pool, Query{
+			Schema: "s_constraints", Model: "m", Language: "en", QueryVec: []float32{1, 0, 0}, Limit: 10,
+			Options: Options{Constraints: q},
+		})
+		if err != nil {
+			t.Fatalf("SemanticSearch(%q): %v", q, err)
+		}
+		var ids []string
+		for _, h := range hits {
+			ids = append(ids, h.EntityID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	// The flat document matches a scoped term anywhere.
+	if got, want := fts("title:cat"), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("FTS title:cat = %v; want %v", got, want)
+	}
+	if got, want := trigram("cat", "title:cat"), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("trigram title:cat = %v; want %v", got, want)
+	}
+
+	if got, want := fts("cat not dog"), []string{"1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("FTS cat not dog = %v; want %v", got, want)
+	}
+	if got, want := trigram("cat", "cat not dog"), []string{"1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("trigram cat not dog = %v; want %v", got, want)
+	}
+	if got, want := trigram("cat", "cat not title:dog"), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("trigram cat not title:dog = %v; want %v", got, want)
+	}
+	// Exclusions apply before the LIMIT; the entity without a document is kept.
+	if got, want := semantic("cat not dog"), []string{"1", "4"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("semantic cat not dog = %v; want %v", got, want)
+	}
+
+	counts, err := LexicalFacets(ctx, pool, "title:cat not mat", LexicalFacetOptions{
+		Schema: "s_constraints", Language: "en", FTS: true, Trigram: true,
+	})
+	if err != nil {
+		t.Fatalf("LexicalFacets: %v", err)
+	}
+	if len(counts) != 1 || counts[0].Count != 1 {
+		t.Fatalf("expected only entity 2 to be counted, got %+v", counts)
+	}
+}
@@ -0,0 +1,108 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+)
+
+func TestQueryConstraintsSQL_None(t *testing.T) {
+	args := pgx.NamedArgs{}
+	got, err := queryConstraintsSQL(`"s"`, "en", querynorm.ParseQuery("cat dog"), true, true, args)
+	if err != nil || got != "" || len(args) != 0 {
+		t.Fatalf("expected no constraints, got %q %v (%v)", got, args, err)
+	}
+}
+
+func TestQueryConstraintsSQL_FieldScopes(t *testing.T) {
+	args := pgx.NamedArgs{"language": "en"}
+	got, err := queryConstraintsSQL(`"s"`, "en", querynorm.ParseQuery("title:cat dog"), true, false, args)
+	if err != nil {
+		t.Fatalf("queryConstraintsSQL: %v", err)
+	}
+	if !strings.HasPrefix(got, "(sd.fields IS NULL OR ") {
+		t.Fatalf("expected flat documents to be unconstrained, got %q", got)
+	}
+	if !strings.Contains(got, "f->>'weight' = 'A'") {
+		t.Fatalf("expected the title weight, got %q", got)
+	}
+	if strings.Count(got, "@qc_") != 2 || args["qc_0"] != "%cat%" {
+		t.Fatalf("expected only the scoped term to be constrained, got %q %v", got, args)
+	}
+
+	// Scopes are not requested: nothing to enforce.
+	got, err = queryConstraintsSQL(`"s"`, "en", querynorm.ParseQuery("title:cat"), false, true, pgx.NamedArgs{})
+	if err != nil || got != "" {
+		t.Fatalf("expected no constraints, got %q (%v)", got, err)
+	}
+}
+
+func TestQueryConstraintsSQL_Exclusions(t *testing.T) {
+	args := pgx.NamedArgs{}
+	got, err := queryConstraintsSQL(`"s"`, "en", querynorm.ParseQuery("cat not dog"), true, true, args)
+	if err != nil {
+		t.Fatalf("queryConstraintsSQL: %v", err)
+	}
+	want := `NOT coalesce(sd.tsv @@ (SELECT plainto_tsquery(cfg.config, @qc_0) FROM (SELECT "s".searchkit_regconfig_for_language(@language) AS config) cfg), false)`
+	if got != want {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+	if args["qc_0"] != "dog" {
+		t.Fatalf("unexpected args %v", args)
+	}
+
+	// Scoped exclusions fall back to the unscoped tsquery for flat documents.
+	args = pgx.NamedArgs{}
+	got, err = queryConstraintsSQL(`"s"`, "en", querynorm.ParseQuery("cat not title:dog"), false, true, args)
+	if err != nil {
+		t.Fatalf("queryConstraintsSQL: %v", err)
+	}
+	if !strings.Contains(got, "sd.fields IS NOT NULL AND sd.tsv @@") || !strings.Contains(got, "sd.fields IS NULL AND sd.tsv @@") {
+		t.Fatalf("expected a scoped/flat split, got %q", got)
+	}
+	if !strings.Contains(got, `'\&:A'`) || args["qc_0"] != "dog" || args["qc_1"] != "dog" {
+		t.Fatalf("unexpected args %v", args)
+	}
+
+	// CJK tsv is not segmented: exclusions match raw text substrings.
+	args = pgx.NamedArgs{}
+	got, err = queryConstraintsSQL(`"s"`, "ja", querynorm.ParseQuery("猫 not 犬"), false, true, args)
+	if err != nil {
+		t.Fatalf("queryConstraintsSQL: %v", err)
+	}
+	if !strings.Contains(got, "sd.raw_document ILIKE @qc_0") || strings.Contains(got, "tsv") {
+		t.Fatalf("expected a raw text match, got %q", got)
+	}
+	if args["qc_0"] != "%犬%" {
+		t.Fatalf("unexpected args %v", args)
+	}
+}
+
+func TestQueryConstraintsSQL_ArgNames(t *testing.T) {
+	// Names already bound (another backend's constraints, FilterArgs) are skipped.
+	args := pgx.NamedArgs{"qc_0": "taken"}
+	got, err := queryConstraintsSQL(`"s"`, "en", querynorm.ParseQuery("cat not dog"), false, true, args)
+	if err != nil {
+		t.Fatalf("queryConstraintsSQL: %v", err)
+	}
+	if !strings.Contains(got, "@qc_1") || args["qc_0"] != "taken" || args["qc_1"] != "dog" {
+		t.Fatalf("expected a fresh arg name, got %q %v", got, args)
+	}
+}
+
+func TestSemanticExclusionSQL(t *testing.T) {
+	args := pgx.NamedArgs{}
+	got, err := semanticExclusionSQL(`"s"`, "en", "cat not dog", args)
+	if err != nil {
+		t.Fatalf("semanticExclusionSQL: %v", err)
+	}
+	if !strings.Contains(got, `AND NOT EXISTS`) || !strings.Contains(got, "sd.entity_id = ev.entity_id") {
+		t.Fatalf("expected a document lookup, got %q", got)
+	}
+	if got, err := semanticExclusionSQL(`"s"`, "en", "title:cat", pgx.NamedArgs{}); err != nil || got != "" {
+		t.Fatalf("expected field scopes to be ignored, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+)
+
+// DocumentOptions scopes MatchingDocuments like a retrieval query.
+type DocumentOptions struct {
+	Schema      string
+	Language    string
+	EntityTypes []string
+
+	// FilterSQL, FilterArgs and Filter behave as in FTSOptions.
+	FilterSQL  string
+	FilterArgs map[string]any
+	Filter     Filter
+
+	// Constraints is the user query whose exclusions (`not x`) apply, as in
+	// Options.Constraints.
+	Constraints string
+}
+
+// MatchingDocuments returns the keys (entity type and id; Language is
+// ignored) that have a `search_documents` row in opts.Language passing the
+// same conditions as retrieval: entity types, filters and query exclusions.
+// It does not match the query text, so callers can place entities (e.g.
+// pinned by a rule) that retrieval did not rank without bypassing the scope
+// of the request. Results carry opts.Language and follow the order of keys.
+func MatchingDocuments(ctx context.Context, pool *pgxpool.Pool, keys []DocKey, opts DocumentOptions) ([]DocKey, error) {
+	if strings.TrimSpace(opts.Schema) == "" {
+		return nil, fmt.Errorf("schema is required")
+	}
+	if strings.TrimSpace(opts.Language) == "" {
+		return nil, fmt.Errorf("language is required")
+	}
+	if len(keys) == 0 {
+		return []DocKey{}, nil
+	}
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	quotedSchema, err := quoteIdent(opts.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	types := make([]string, len(keys))
+	ids := make([]string, len(keys))
+	for i, k := range keys {
+		types[i], ids[i] = k.EntityType, k.EntityID
+	}
+	// The keys are matched with IN (subquery) so their columns cannot clash
+	// with unqualified names in FilterSQL.
+	where := "WHERE sd.language = @language" +
+		" AND (sd.entity_type, sd.entity_id) IN (SELECT * FROM unnest(@key_types::text[], @key_ids::text[]))"
+	args := pgx.NamedArgs{
+		"language":  opts.Language,
+		"key_types": types,
+		"key_ids":   ids,
+	}
+	if len(opts.EntityTypes) > 0 {
+		where += " AND sd.entity_type = ANY(@entity_types::text[])"
+		args["entity_types"] = opts.EntityTypes
+	}
+	filterSQL, filterArgs, err := CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(filterSQL) != "" {
+		where += " AND (" + filterSQL + ")"
+		if err := mergeNamedArgs(args, filterArgs); err != nil {
+			return nil, err
+		}
+	}
+	if strings.TrimSpace(opts.Constraints) != "" {
+		cond, err := queryConstraintsSQL(quotedSchema, opts.Language, querynorm.ParseQuery(opts.Constraints), false, true, args)
+		if err != nil {
+			return nil, err
+		}
+		if cond != "" {
+			where += " AND " + cond
+		}
+	}
+
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT sd.entity_type, sd.entity_id
+		FROM %s.search_documents sd
+		%s
+	`, quotedSchema, where), args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	found := map[DocKey]struct{}{}
+	for rows.Next() {
+		var k DocKey
+		if err := rows.Scan(&k.EntityType, &k.EntityID); err != nil {
+			return nil, err
+		}
+		found[k] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]DocKey, 0, len(found))
+	for _, k := range keys {
+		k.Language = ""
+		if _, ok := found[k]; ok {
+			delete(found, k)
+			k.Language = opts.Language
+			out = append(out, k)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,310 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+	"github.com/open-rails/searchkit/internal/textnormalize"
+)
+
+// FacetCount is the number of matching entities for one (entity_type,
+// language) pair.
+type FacetCount struct {
+	EntityType string
+	Language   string
+	Count      int64
+}
+
+type LexicalFacetOptions struct {
+	Schema      string
+	Language    string
+	EntityTypes []string
+
+	// Backends whose matches are counted. A document matched by several
+	// backends is counted once.
+	FTS      bool
+	Trigram  bool
+	PGroonga bool
+
+	// MinSimilarity is the trigram threshold (defaults to 0.1, as in
+	// LexicalSearch).
+	MinSimilarity float32
+
+	// Synonyms expands the query as in FTSSearch. Trigram has no operators, so
+	// a document counts when it matches any of the query's trigram probes (see
+	// querynorm.Query.TrigramProbes), at most MaxTrigramProbes (0 = no limit).
+	Synonyms         map[string][]string
+	MaxTrigramProbes int
+
+	// FilterSQL / FilterArgs / Filter are applied exactly as in FTSSearch.
+	FilterSQL  string
+	FilterArgs map[string]any
+	Filter     Filter
+}
+
+// LexicalFacets counts the documents matching query per entity type, using the
+// same match conditions as FTSSearch, LexicalSearch and PGroongaSearch (without
+// a LIMIT).
+func LexicalFacets(ctx context.Context, pool *pgxpool.Pool, query string, opts LexicalFacetOptions) ([]FacetCount, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(opts.Schema) == "" {
+		return nil, fmt.Errorf("schema is required")
+	}
+	if strings.TrimSpace(opts.Language) == "" {
+		return nil, fmt.Errorf("language is required")
+	}
+
+	quotedSchema, err := quoteIdent(opts.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	table := quotedSchema + ".search_documents"
+
+	args := pgx.NamedArgs{"language": opts.Language}
+	var ctes, conds []string
+	from := table + " sd"
+
+	parsed := querynorm.ParseQuery(query).ExpandSynonyms(opts.Synonyms)
+	if opts.FTS && parsed.HasPositive() {
+		// As in FTSSearch, the tsquery (and the language's config) is computed
+		// once in a CTE rather than per document, and documents without fields
+		// match the unscoped query.
+		p := &constraintParams{args: args, bound: args}
+		tsq := bindTSQuery(parsed, p) + " AS tsq"
+		cond := "(sd.tsv IS NOT NULL AND sd.tsv @@ q.tsq)"
+		if parsed.HasFieldScopes() {
+			tsq += ", " + bindTSQuery(parsed.Unscoped(), p) + " AS flat_tsq"
+			cond = "((sd.fields IS NOT NULL AND sd.tsv @@ q.tsq) OR (sd.fields IS NULL AND sd.tsv @@ q.flat_tsq))"
+		}
+		ctes = append(ctes, fmt.Sprintf("q AS (SELECT %s FROM %s)", tsq, tsqueryConfigFrom(quotedSchema)))
+		from = "q, " + from
+		conds = append(conds, cond)
+	}
+	if opts.Trigram {
+		var probes []string
+		for _, p := range parsed.TrigramProbes(opts.MaxTrigramProbes) {
+			if q := textnormalize.Heavy(p); q != "" {
+				name := fmt.Sprintf("trgm_q_%d", len(probes))
+				args[name] = q
+				probes = append(probes, "sd.document % @"+name)
+			}
+		}
+		if len(probes) > 0 {
+			minSim := opts.MinSimilarity
+			if minSim <= 0 {
+				minSim = 0.1
+			}
+			args["min_similarity"] = minSim
+			// See LexicalSearch for why set_limit is referenced from a CTE.
+			ctes = append(ctes, "_ AS (SELECT set_limit(@min_similarity))")
+			from = "_, " + from
+			cond := "(" + strings.Join(probes, " OR ") + ")"
+			constraints, err := queryConstraintsSQL(quotedSchema, opts.Language, parsed, true, true, args)
+			if err != nil {
+				return nil, err
+			}
+			if constraints != "" {
+				cond = "(" + cond + " AND " + constraints + ")"
+			}
+			conds = append(conds, cond)
+		}
+	}
+	if opts.PGroonga {
+		if q := parsed.PGroonga(); q != "" {
+			extSchema, err := getPGroongaExtensionSchema(ctx, pool)
+			if err != nil {
+				return nil, err
+			}
+			qext, err := quoteIdent(extSchema)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pgroonga schema: %w", err)
+			}
+			args["pgroonga_q"] = q
+			cond := fmt.Sprintf("(sd.raw_document IS NOT NULL AND sd.raw_document OPERATOR(%s.&@~) @pgroonga_q)", qext)
+			scopes, err := queryConstraintsSQL(quotedSchema, opts.Language, parsed, true, false, args)
+			if err != nil {
+				return nil, err
+			}
+			if scopes != "" {
+				cond = "(" + cond + " AND " + scopes + ")"
+			}
+			conds = append(conds, cond)
+		}
+	}
+	if len(conds) == 0 {
+		return []FacetCount{}, nil
+	}
+
+	where := "WHERE sd.language = @language"
+	if len(opts.EntityTypes) > 0 {
+		where += " AND sd.entity_type = ANY(@entity_types::text[])"
+		args["entity_types"] = opts.EntityTypes
+	}
+	filterSQL, filterArgs, err := CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(filterSQL) != "" {
+		where += " AND (" + filterSQL + ")"
+		if err := mergeNamedArgs(args, filterArgs); err != nil {
+			return nil, err
+		}
+	}
+	with := ""
+	if len(ctes) > 0 {
+		with = "WITH " + strings.Join(ctes, ", ")
+	}
+
+	sql := fmt.Sprintf(`
+		%s
+		SELECT sd.entity_type, sd.language, count(*)::bigint
+		FROM %s
+		%s
+		  AND (%s)
+		GROUP BY sd.entity_type, sd.language
+		ORDER BY 3 DESC, 1 ASC
+	`, with, from, where, strings.Join(conds, " OR "))
+	return scanFacetCounts(ctx, pool, sql, args)
+}
+
+// SemanticFacets estimates per-(entity_type, language) counts of vectors whose
+// cosine similarity to q.QueryVec is at least minSimilarity.
+//
+// Only the q.Limit nearest neighbors are inspected, so the cost is that of a
+// single KNN query. capped reports that every inspected neighbor passed the
+// threshold: the counts are then lower bounds.
+//
+// q.Options.EntityTypes, ExcludeIDs, FilterSQL/FilterArgs and Constraints are
+// honored; TwoStage is ignored.
+func SemanticFacets(ctx context.Context, pool *pgxpool.Pool, q Query, minSimilarity float32) (counts []FacetCount, capped bool, err error) {
+	if pool == nil {
+		return nil, false, fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(q.Schema) == "" {
+		return nil, false, fmt.Errorf("schema is required")
+	}
+	if strings.TrimSpace(q.Model) == "" {
+		return nil, false, fmt.Errorf("model is required")
+	}
+	if strings.TrimSpace(q.Language) == "" {
+		return nil, false, fmt.Errorf("language is required")
+	}
+	if q.Limit <= 0 || len(q.QueryVec) == 0 {
+		return []FacetCount{}, false, nil
+	}
+
+	dim := q.Dimensions
+	if dim <= 0 {
+		dim = len(q.QueryVec)
+	}
+	quotedSchema, err := quoteIdent(q.Schema)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid schema: %w", err)
+	}
+	half := fmt.Sprintf("halfvec(%d)", dim)
+	table := quotedSchema + ".embedding_vectors"
+
+	where := "WHERE ev.model = @model AND ev.language = @language AND ev.embedding IS NOT NULL"
+	args := pgx.NamedArgs{
+		"model":          q.Model,
+		"language":       q.Language,
+		"qvec":           pgvector.NewHalfVector(q.QueryVec),
+		"limit":          q.Limit,
+		"min_similarity": minSimilarity,
+	}
+	if len(q.Options.EntityTypes) > 0 {
+		where += " AND ev.entity_type = ANY(@entity_types::text[])"
+		args["entity_types"] = q.Options.EntityTypes
+	}
+	if len(q.Options.ExcludeIDs) > 0 {
+		where += " AND ev.entity_id <> ALL(@exclude_ids::text[])"
+		args["exclude_ids"] = q.Options.ExcludeIDs
+	}
+	filterSQL, filterArgs, err := CombineFilter(q.Options.FilterSQL, q.Options.FilterArgs, q.Options.Filter)
+	if err != nil {
+		return nil, false, err
+	}
+	if strings.TrimSpace(filterSQL) != "" {
+		where += " AND (" + filterSQL + ")"
+		if err := mergeNamedArgs(args, filterArgs); err != nil {
+			return nil, false, err
+		}
+	}
+	exclusions, err := semanticExclusionSQL(quotedSchema, q.Language, q.Options.Constraints, args)
+	if err != nil {
+		return nil, false, err
+	}
+	where += exclusions
+
+	sql := fmt.Sprintf(`
+		WITH knn AS (
+			SELECT
+				ev.entity_type,
+				ev.language,
+				(1 - (ev.embedding::%[1]s <=> (@qvec::%[1]s)))::float4 AS similarity
+			FROM %[2]s
+			%[3]s
+			ORDER BY ev.embedding::%[1]s <=> (@qvec::%[1]s)
+			LIMIT @limit
+		)
+		SELECT
+			entity_type,
+			language,
+			count(*) FILTER (WHERE similarity >= @min_similarity)::bigint,
+			(SELECT count(*) FROM knn)::bigint,
+			(SELECT min(similarity) FROM knn)::float4
+		FROM knn
+		GROUP BY entity_type, language
+		ORDER BY 3 DESC, 1 ASC
+	`, half, semanticFrom(table, filterSQL), where)
+
+	rows, err := pool.Query(ctx, sql, args)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	counts = []FacetCount{}
+	var inspected int64
+	var floor float32
+	for rows.Next() {
+		var fc FacetCount
+		if err := rows.Scan(&fc.EntityType, &fc.Language, &fc.Count, &inspected, &floor); err != nil {
+			return nil, false, err
+		}
+		if fc.Count > 0 {
+			counts = append(counts, fc)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	capped = inspected >= int64(q.Limit) && floor >= minSimilarity
+	return counts, capped, nil
+}
+
+func scanFacetCounts(ctx context.Context, pool *pgxpool.Pool, sql string, args pgx.NamedArgs) ([]FacetCount, error) {
+	rows, err := pool.Query(ctx, sql, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []FacetCount{}
+	for rows.Next() {
+		var fc FacetCount
+		if err := rows.Scan(&fc.EntityType, &fc.Language, &fc.Count); err != nil {
+			return nil, err
+		}
+		out = append(out, fc)
+	}
+	return out, rows.Err()
+}
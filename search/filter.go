@@ -0,0 +1,424 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Filter is a parameterized WHERE condition built with Eq, In, Range, Exists,
+// And, Or, Not and SQL, plus AttrEq, AttrIn, AttrRange and AttrExists over
+// stored document attributes. It is accepted next to FilterSQL/FilterArgs in every
+// options struct; both are ANDed when set.
+//
+// Values are always bound as named args. Generated arg names start with
+// FilterArgPrefix, which FilterArgs and SQL() args must not use.
+type Filter interface {
+	render(r *filterRenderer) (string, error)
+}
+
+// FilterArgPrefix prefixes the arg names generated when rendering a Filter.
+const FilterArgPrefix = "searchkit_filter_"
+
+// filterColumnRe accepts a (possibly qualified) column with an optional cast,
+// e.g. `sd.entity_id` or `v.id::text`.
+var filterColumnRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*(::[A-Za-z_][A-Za-z0-9_]*(\[\])?)?$`)
+
+type filterRenderer struct {
+	args map[string]any
+	n    int
+	// inExists is set while rendering the WHERE of an Exists subquery, where
+	// attribute filters would not refer to the searchkit row.
+	inExists bool
+}
+
+func (r *filterRenderer) bind(v any) string {
+	name := fmt.Sprintf("%s%d", FilterArgPrefix, r.n)
+	r.n++
+	r.args[name] = v
+	return "@" + name
+}
+
+func checkFilterColumn(column string) error {
+	if !filterColumnRe.MatchString(column) {
+		return fmt.Errorf("invalid filter column %q", column)
+	}
+	return nil
+}
+
+// RenderFilter renders f into a SQL fragment and its named args. A nil
+// Filter renders to "".
+func RenderFilter(f Filter) (string, map[string]any, error) {
+	if f == nil {
+		return "", nil, nil
+	}
+	r := &filterRenderer{args: map[string]any{}}
+	sql, err := f.render(r)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, r.args, nil
+}
+
+// CombineFilter ANDs filterSQL with the rendered f and merges their args. It
+// fails if an arg name is used by both.
+func CombineFilter(filterSQL string, filterArgs map[string]any, f Filter) (string, map[string]any, error) {
+	if f == nil {
+		return filterSQL, filterArgs, nil
+	}
+	sql, args, err := RenderFilter(f)
+	if err != nil {
+		return "", nil, err
+	}
+	for k, v := range filterArgs {
+		if strings.HasPrefix(strings.TrimSpace(k), FilterArgPrefix) {
+			return "", nil, fmt.Errorf("FilterArgs key %q uses the reserved prefix %q", k, FilterArgPrefix)
+		}
+		if _, exists := args[k]; exists {
+			return "", nil, fmt.Errorf("FilterArgs key %q conflicts with a Filter arg", k)
+		}
+		args[k] = v
+	}
+	if strings.TrimSpace(filterSQL) == "" {
+		return sql, args, nil
+	}
+	return "(" + filterSQL + ") AND (" + sql + ")", args, nil
+}
+
+type eqFilter struct {
+	column string
+	value  any
+}
+
+// Eq matches rows where column equals value (IS NULL when value is nil).
+func Eq(column string, value any) Filter {
+	return eqFilter{column: column, value: value}
+}
+
+func (f eqFilter) render(r *filterRenderer) (string, error) {
+	if err := checkFilterColumn(f.column); err != nil {
+		return "", err
+	}
+	if isNilValue(f.value) {
+		return f.column + " IS NULL", nil
+	}
+	return f.column + " = " + r.bind(f.value), nil
+}
+
+type inFilter struct {
+	column string
+	values any
+	n      int
+}
+
+// In matches rows where column is one of values. An empty list matches
+// nothing.
+func In[T any](column string, values []T) Filter {
+	return inFilter{column: column, values: values, n: len(values)}
+}
+
+func (f inFilter) render(r *filterRenderer) (string, error) {
+	if err := checkFilterColumn(f.column); err != nil {
+		return "", err
+	}
+	if f.n == 0 {
+		return "FALSE", nil
+	}
+	return f.column + " = ANY(" + r.bind(f.values) + ")", nil
+}
+
+type rangeFilter struct {
+	column   string
+	min, max any
+}
+
+// Range matches rows where min <= column <= max. A nil bound is open; with
+// both nil every non-NULL value matches.
+func Range(column string, min, max any) Filter {
+	return rangeFilter{column: column, min: min, max: max}
+}
+
+func (f rangeFilter) render(r *filterRenderer) (string, error) {
+	if err := checkFilterColumn(f.column); err != nil {
+		return "", err
+	}
+	var conds []string
+	if !isNilValue(f.min) {
+		conds = append(conds, f.column+" >= "+r.bind(f.min))
+	}
+	if !isNilValue(f.max) {
+		conds = append(conds, f.column+" <= "+r.bind(f.max))
+	}
+	if len(conds) == 0 {
+		return f.column + " IS NOT NULL", nil
+	}
+	return strings.Join(conds, " AND "), nil
+}
+
+func isNilValue(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+type existsFilter struct {
+	from  string
+	where []Filter
+}
+
+// Exists matches rows for which `SELECT 1 FROM <from> WHERE <where...>`
+// returns a row. from is trusted SQL (a table with alias, optionally with
+// joins); the where filters are ANDed and usually correlate with the outer
+// row through SQL, e.g. SQL("v.id::text = sd.entity_id", nil).
+func Exists(from string, where ...Filter) Filter {
+	return existsFilter{from: from, where: where}
+}
+
+func (f existsFilter) render(r *filterRenderer) (string, error) {
+	if strings.TrimSpace(f.from) == "" {
+		return "", fmt.Errorf("Exists requires a FROM clause")
+	}
+	sql := "EXISTS (SELECT 1 FROM " + strings.TrimSpace(f.from)
+	if len(f.where) > 0 {
+		outer := r.inExists
+		r.inExists = true
+		cond, err := And(f.where...).render(r)
+		r.inExists = outer
+		if err != nil {
+			return "", err
+		}
+		sql += " WHERE " + cond
+	}
+	return sql + ")", nil
+}
+
+type boolFilter struct {
+	op    string
+	parts []Filter
+}
+
+// And matches rows matching every filter (TRUE when empty).
+func And(filters ...Filter) Filter {
+	return boolFilter{op: "AND", parts: filters}
+}
+
+// Or matches rows matching any filter (FALSE when empty).
+func Or(filters ...Filter) Filter {
+	return boolFilter{op: "OR", parts: filters}
+}
+
+func (f boolFilter) render(r *filterRenderer) (string, error) {
+	parts := make([]string, 0, len(f.parts))
+	for _, p := range f.parts {
+		if p == nil {
+			continue
+		}
+		sql, err := p.render(r)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, sql)
+	}
+	switch {
+	case len(parts) == 0 && f.op == "AND":
+		return "TRUE", nil
+	case len(parts) == 0:
+		return "FALSE", nil
+	case len(parts) == 1:
+		return parts[0], nil
+	}
+	return "(" + strings.Join(parts, ") "+f.op+" (") + ")", nil
+}
+
+type notFilter struct {
+	f Filter
+}
+
+// Not negates f. Rows where f is NULL (e.g. a comparison with a NULL
+// column) match Not(f), as they do not match f.
+func Not(f Filter) Filter {
+	return notFilter{f: f}
+}
+
+func (f notFilter) render(r *filterRenderer) (string, error) {
+	if f.f == nil {
+		return "", fmt.Errorf("Not requires a filter")
+	}
+	sql, err := f.f.render(r)
+	if err != nil {
+		return "", err
+	}
+	return "NOT COALESCE(" + sql + ", false)", nil
+}
+
+type sqlFilter struct {
+	sql  string
+	args map[string]any
+}
+
+// SQL is a trusted SQL fragment with its own named args, for conditions the
+// builder cannot express (e.g. joins between columns). As with FilterSQL, do
+// not insert user input into it.
+func SQL(fragment string, args map[string]any) Filter {
+	return sqlFilter{sql: fragment, args: args}
+}
+
+func (f sqlFilter) render(r *filterRenderer) (string, error) {
+	if strings.TrimSpace(f.sql) == "" {
+		return "", fmt.Errorf("SQL filter is empty")
+	}
+	for k, v := range f.args {
+		k = strings.TrimSpace(k)
+		switch {
+		case k == "":
+			return "", fmt.Errorf("empty SQL filter arg key")
+		case strings.HasPrefix(k, FilterArgPrefix):
+			return "", fmt.Errorf("SQL filter arg %q uses the reserved prefix %q", k, FilterArgPrefix)
+		}
+		if _, exists := r.args[k]; exists {
+			return "", fmt.Errorf("SQL filter arg %q is bound twice", k)
+		}
+		r.args[k] = v
+	}
+	return f.sql, nil
+}
+
+// attributesColumn is the attributes of the search_documents row; semantic
+// queries expose the embedding_vectors row's attributes under the same alias
+// (see semanticFrom).
+const attributesColumn = "sd.attributes"
+
+type attrFilter struct {
+	key    string
+	kind   string // "eq", "in", "range", "exists"
+	values []any
+	min    any
+	max    any
+}
+
+// AttrEq matches documents whose stored attribute key equals value (see
+// pg.Attributes; time.Time values compare as Unix seconds). Served by the
+// attributes GIN index. A nil value matches an explicit JSON null; use
+// Not(AttrExists(key)) for missing keys.
+func AttrEq(key string, value any) Filter {
+	return attrFilter{key: key, kind: "eq", values: []any{value}}
+}
+
+// AttrIn matches documents whose attribute key equals one of values. An
+// empty list matches nothing.
+func AttrIn[T any](key string, values []T) Filter {
+	vs := make([]any, len(values))
+	for i, v := range values {
+		vs[i] = v
+	}
+	return attrFilter{key: key, kind: "in", values: vs}
+}
+
+// AttrRange matches documents where min <= attribute key <= max, comparing
+// JSON numbers (numeric and time.Time bounds) or strings (string bounds). A
+// nil bound is open. Values of another JSON type never match.
+// pg.EnsureAttributeIndex creates a matching expression index.
+func AttrRange(key string, min, max any) Filter {
+	return attrFilter{key: key, kind: "range", min: min, max: max}
+}
+
+// AttrExists matches documents that have a non-null attribute key.
+func AttrExists(key string) Filter {
+	return attrFilter{key: key, kind: "exists"}
+}
+
+func (f attrFilter) render(r *filterRenderer) (string, error) {
+	if strings.TrimSpace(f.key) == "" {
+		return "", fmt.Errorf("attribute key is required")
+	}
+	if r.inExists {
+		return "", fmt.Errorf("attribute filter %q cannot be used inside Exists", f.key)
+	}
+	// The key is inlined as a literal (not bound) so expression indexes on
+	// `attributes -> 'key'` match.
+	path := attributesColumn + " -> '" + strings.ReplaceAll(f.key, "'", "''") + "'"
+
+	switch f.kind {
+	case "exists":
+		// Never NULL, so Not(AttrExists(key)) matches missing keys.
+		return "coalesce(jsonb_typeof(" + path + ") <> 'null', false)", nil
+	case "eq", "in":
+		if len(f.values) == 0 {
+			return "FALSE", nil
+		}
+		conds := make([]string, 0, len(f.values))
+		for _, v := range f.values {
+			doc, err := json.Marshal(map[string]any{f.key: attrValue(v)})
+			if err != nil {
+				return "", fmt.Errorf("attribute %q: %w", f.key, err)
+			}
+			conds = append(conds, attributesColumn+" @> "+r.bind(string(doc))+"::jsonb")
+		}
+		if len(conds) == 1 {
+			return conds[0], nil
+		}
+		return "(" + strings.Join(conds, " OR ") + ")", nil
+	case "range":
+		var conds []string
+		typ := ""
+		for _, b := range []struct {
+			v  any
+			op string
+		}{{f.min, ">="}, {f.max, "<="}} {
+			if isNilValue(b.v) {
+				continue
+			}
+			v := attrValue(b.v)
+			t := jsonType(v)
+			if t == "" || (typ != "" && t != typ) {
+				return "", fmt.Errorf("attribute %q: range bounds must both be numbers or strings", f.key)
+			}
+			typ = t
+			doc, err := json.Marshal(v)
+			if err != nil {
+				return "", fmt.Errorf("attribute %q: %w", f.key, err)
+			}
+			conds = append(conds, "("+path+") "+b.op+" "+r.bind(string(doc))+"::jsonb")
+		}
+		if len(conds) == 0 {
+			return "jsonb_typeof(" + path + ") <> 'null'", nil
+		}
+		// jsonb orders values of different types by type, so pin the type.
+		conds = append(conds, "jsonb_typeof("+path+") = '"+typ+"'")
+		return strings.Join(conds, " AND "), nil
+	}
+	return "", fmt.Errorf("unsupported attribute filter %q", f.kind)
+}
+
+// attrValue mirrors pg.MarshalAttributes: times are stored as Unix seconds.
+func attrValue(v any) any {
+	switch t := v.(type) {
+	case time.Time:
+		return t.Unix()
+	case *time.Time:
+		if t == nil {
+			return nil
+		}
+		return t.Unix()
+	}
+	return v
+}
+
+// jsonType returns the JSON type name ("number" or "string") v encodes to,
+// or "" for other types.
+func jsonType(v any) string {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	}
+	return ""
+}
@@ -0,0 +1,179 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func TestRenderFilter(t *testing.T) {
+	since := "2024-01-01"
+	f := And(
+		Eq("sd.entity_type", "video"),
+		In("sd.language", []string{"en", "ja"}),
+		Range("v.published_at", &since, nil),
+		Not(Eq("v.deleted_at", nil)),
+		Or(Eq("v.status", "live"), Range("v.rating", 3, 5)),
+		Exists("hentai0.videos v",
+			SQL("v.id::text = sd.entity_id", nil),
+			Eq("v.owner_id::text", "u1"),
+		),
+	)
+	sql, args, err := RenderFilter(f)
+	if err != nil {
+		t.Fatalf("RenderFilter: %v", err)
+	}
+	want := "(sd.entity_type = @searchkit_filter_0)" +
+		" AND (sd.language = ANY(@searchkit_filter_1))" +
+		" AND (v.published_at >= @searchkit_filter_2)" +
+		" AND (NOT COALESCE(v.deleted_at IS NULL, false))" +
+		" AND ((v.status = @searchkit_filter_3) OR (v.rating >= @searchkit_filter_4 AND v.rating <= @searchkit_filter_5))" +
+		" AND (EXISTS (SELECT 1 FROM hentai0.videos v WHERE (v.id::text = sd.entity_id) AND (v.owner_id::text = @searchkit_filter_6)))"
+	if sql != want {
+		t.Fatalf("sql =\n%s\nwant\n%s", sql, want)
+	}
+	wantArgs := map[string]any{
+		"searchkit_filter_0": "video",
+		"searchkit_filter_1": []string{"en", "ja"},
+		"searchkit_filter_2": &since,
+		"searchkit_filter_3": "live",
+		"searchkit_filter_4": 3,
+		"searchkit_filter_5": 5,
+		"searchkit_filter_6": "u1",
+	}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("args = %v; want %v", args, wantArgs)
+	}
+}
+
+func TestRenderFilter_NotMatchesNull(t *testing.T) {
+	// v.status = 'live' is NULL for a NULL status: NOT (NULL) would drop the
+	// row, although it is not live.
+	sql, _, err := RenderFilter(Not(Eq("v.status", "live")))
+	if err != nil {
+		t.Fatalf("RenderFilter: %v", err)
+	}
+	if want := "NOT COALESCE(v.status = @searchkit_filter_0, false)"; sql != want {
+		t.Fatalf("sql = %q; want %q", sql, want)
+	}
+}
+
+func TestRenderFilter_EdgeCases(t *testing.T) {
+	cases := []struct {
+		f    Filter
+		want string
+	}{
+		{f: nil, want: ""},
+		{f: And(), want: "TRUE"},
+		{f: Or(), want: "FALSE"},
+		{f: In("sd.entity_id", []int{}), want: "FALSE"},
+		{f: Range("sd.rank", nil, nil), want: "sd.rank IS NOT NULL"},
+		{f: And(Eq("sd.entity_type", "video")), want: "sd.entity_type = @searchkit_filter_0"},
+	}
+	for _, tc := range cases {
+		sql, _, err := RenderFilter(tc.f)
+		if err != nil {
+			t.Fatalf("RenderFilter: %v", err)
+		}
+		if sql != tc.want {
+			t.Fatalf("RenderFilter = %q; want %q", sql, tc.want)
+		}
+	}
+
+	for _, f := range []Filter{
+		Eq("sd.entity_id; DROP TABLE x", 1),
+		In("lower(sd.entity_id)", []string{"a"}),
+		Exists(" "),
+		Not(nil),
+		SQL("", nil),
+		SQL("a = @searchkit_filter_0", map[string]any{"searchkit_filter_0": 1}),
+		And(SQL("a = @x", map[string]any{"x": 1}), SQL("b = @x", map[string]any{"x": 2})),
+	} {
+		if _, _, err := RenderFilter(f); err == nil {
+			t.Fatalf("expected error for %#v", f)
+		}
+	}
+}
+
+func TestCombineFilter(t *testing.T) {
+	sql, args, err := CombineFilter("sd.entity_id <> @skip", map[string]any{"skip": "1"}, Eq("sd.entity_type", "video"))
+	if err != nil {
+		t.Fatalf("CombineFilter: %v", err)
+	}
+	if want := "(sd.entity_id <> @skip) AND (sd.entity_type = @searchkit_filter_0)"; sql != want {
+		t.Fatalf("sql = %q; want %q", sql, want)
+	}
+	if len(args) != 2 || args["skip"] != "1" || args["searchkit_filter_0"] != "video" {
+		t.Fatalf("unexpected args %v", args)
+	}
+
+	if _, _, err := CombineFilter("x", map[string]any{"searchkit_filter_0": 1}, Eq("a", 1)); err == nil {
+		t.Fatalf("expected reserved prefix error")
+	}
+	if sql, args, err := CombineFilter("x = @y", map[string]any{"y": 1}, nil); err != nil || sql != "x = @y" || args["y"] != 1 {
+		t.Fatalf("expected FilterSQL passthrough, got %q %v %v", sql, args, err)
+	}
+
+	// Generated names never collide with the reserved args of the retrieval
+	// queries.
+	reserved := pgx.NamedArgs{"language": "en", "q": "x", "limit": 10, "entity_types": []string{"a"}}
+	_, args, _ = CombineFilter("", nil, And(Eq("a", 1), Eq("b", 2)))
+	if err := mergeNamedArgs(reserved, args); err != nil {
+		t.Fatalf("mergeNamedArgs: %v", err)
+	}
+	for k := range args {
+		if !strings.HasPrefix(k, FilterArgPrefix) {
+			t.Fatalf("unexpected arg name %q", k)
+		}
+	}
+}
+
+func TestRenderFilter_Attributes(t *testing.T) {
+	liveAt := time.Unix(1700000000, 0)
+	f := And(
+		AttrEq("status", "live"),
+		AttrIn("artist_id", []string{"a1", "a2"}),
+		AttrRange("rating", 3, nil),
+		AttrRange("live_at", nil, liveAt),
+		Not(AttrExists("hidden")),
+		AttrEq("it's", true),
+	)
+	sql, args, err := RenderFilter(f)
+	if err != nil {
+		t.Fatalf("RenderFilter: %v", err)
+	}
+	want := "(sd.attributes @> @searchkit_filter_0::jsonb)" +
+		" AND ((sd.attributes @> @searchkit_filter_1::jsonb OR sd.attributes @> @searchkit_filter_2::jsonb))" +
+		" AND ((sd.attributes -> 'rating') >= @searchkit_filter_3::jsonb AND jsonb_typeof(sd.attributes -> 'rating') = 'number')" +
+		" AND ((sd.attributes -> 'live_at') <= @searchkit_filter_4::jsonb AND jsonb_typeof(sd.attributes -> 'live_at') = 'number')" +
+		" AND (NOT COALESCE(coalesce(jsonb_typeof(sd.attributes -> 'hidden') <> 'null', false), false))" +
+		" AND (sd.attributes @> @searchkit_filter_5::jsonb)"
+	if sql != want {
+		t.Fatalf("sql =\n%s\nwant\n%s", sql, want)
+	}
+	wantArgs := map[string]any{
+		"searchkit_filter_0": `{"status":"live"}`,
+		"searchkit_filter_1": `{"artist_id":"a1"}`,
+		"searchkit_filter_2": `{"artist_id":"a2"}`,
+		"searchkit_filter_3": `3`,
+		"searchkit_filter_4": `1700000000`,
+		"searchkit_filter_5": `{"it's":true}`,
+	}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Fatalf("args = %v; want %v", args, wantArgs)
+	}
+
+	for _, f := range []Filter{
+		AttrEq("", 1),
+		AttrRange("rating", 1, "5"),
+		AttrRange("rating", true, nil),
+		Exists("app.galleries g", AttrEq("status", "live")),
+	} {
+		if _, _, err := RenderFilter(f); err == nil {
+			t.Fatalf("expected error for %#v", f)
+		}
+	}
+}
@@ -35,6 +35,13 @@ type FTSOptions struct {
 	// FilterArgs are named args referenced by FilterSQL using pgx '@name'
 	// placeholders (e.g. "... language = @lang").
 	FilterArgs map[string]any
+	// Filter is a builder alternative to FilterSQL/FilterArgs (see Filter);
+	// both are ANDed when set.
+	Filter Filter
+
+	// Synonyms expands matching query terms into OR groups (see
+	// querynorm.Query.ExpandSynonyms). Keys are querynorm.SynonymKey forms.
+	Synonyms map[string][]string
 }
 
 // NormalizeFTSScore maps Postgres `ts_rank_cd` scores into a bounded [0..1] range.
@@ -83,8 +90,8 @@ func FTSSearch(ctx context.Context, pool *pgxpool.Pool, query string, opts FTSOp
 		return nil, fmt.Errorf("pool is required")
 	}
 
-	q := querynorm.QueryForFTS(query)
-	if q == "" {
+	parsed := querynorm.ParseQuery(query).ExpandSynonyms(opts.Synonyms)
+	if !parsed.HasPositive() {
 		return []FTSHit{}, nil
 	}
 
@@ -97,60 +104,65 @@ func FTSSearch(ctx context.Context, pool *pgxpool.Pool, query string, opts FTSOp
 	where := "WHERE sd.language = @language AND sd.tsv IS NOT NULL"
 	args := pgx.NamedArgs{
 		"language": opts.Language,
-		"q":        q,
 		"limit":    opts.Limit,
 	}
 	if len(opts.EntityTypes) > 0 {
 		where += " AND sd.entity_type = ANY(@entity_types::text[])"
 		args["entity_types"] = opts.EntityTypes
 	}
-	if strings.TrimSpace(opts.FilterSQL) != "" {
-		where += " AND (" + opts.FilterSQL + ")"
-		if err := mergeNamedArgs(args, opts.FilterArgs); err != nil {
+	filterSQL, filterArgs, err := CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(filterSQL) != "" {
+		where += " AND (" + filterSQL + ")"
+		if err := mergeNamedArgs(args, filterArgs); err != nil {
 			return nil, err
 		}
 	}
 
-	// Prefer websearch_to_tsquery (supports multi-word and quotes).
-	// If the query is not parseable, fall back to plainto_tsquery.
-	run := func(fn string) ([]FTSHit, error) {
-		sql := fmt.Sprintf(`
-			WITH q AS (
-				SELECT %s(%s.searchkit_regconfig_for_language(@language), @q) AS tsq
-			)
-			SELECT
-				sd.entity_type,
-				sd.entity_id,
-				sd.language,
-				ts_rank_cd(sd.tsv, q.tsq)::float4 AS score
-			FROM q, %s sd
-			%s
-			  AND q.tsq IS NOT NULL
-			  AND sd.tsv @@ q.tsq
-			ORDER BY score DESC, sd.entity_type ASC, sd.entity_id ASC
-			LIMIT @limit
-		`, fn, quotedSchema, table, where)
-
-		rows, err := pool.Query(ctx, sql, args)
-		if err != nil {
-			return nil, err
-		}
-		defer rows.Close()
+	// The parsed query compiles to a tsquery built from the config's own
+	// lexemes (phrases, OR, exclusions, field weights), computed once in a CTE
+	// rather than per document.
+	//
+	// Field weights only exist in documents with fields: flat documents have
+	// an unweighted tsv, so they are matched with the unscoped query.
+	p := &constraintParams{args: args, bound: args}
+	tsq, rank, match := bindTSQuery(parsed, p)+" AS tsq", "q.tsq", "sd.tsv @@ q.tsq"
+	if parsed.HasFieldScopes() {
+		tsq += ", " + bindTSQuery(parsed.Unscoped(), p) + " AS flat_tsq"
+		rank = "CASE WHEN sd.fields IS NULL THEN q.flat_tsq ELSE q.tsq END"
+		match = "((sd.fields IS NOT NULL AND sd.tsv @@ q.tsq) OR (sd.fields IS NULL AND sd.tsv @@ q.flat_tsq))"
+	}
+	sql := fmt.Sprintf(`
+		WITH q AS (
+			SELECT %[1]s FROM %[2]s
+		)
+		SELECT
+			sd.entity_type,
+			sd.entity_id,
+			sd.language,
+			ts_rank_cd(sd.tsv, %[5]s)::float4 AS score
+		FROM q, %[3]s sd
+		%[4]s
+		  AND %[6]s
+		ORDER BY score DESC, sd.entity_type ASC, sd.entity_id ASC
+		LIMIT @limit
+	`, tsq, tsqueryConfigFrom(quotedSchema), table, where, rank, match)
 
-		var out []FTSHit
-		for rows.Next() {
-			var h FTSHit
-			if err := rows.Scan(&h.EntityType, &h.EntityID, &h.Language, &h.Score); err != nil {
-				return nil, err
-			}
-			out = append(out, h)
-		}
-		return out, rows.Err()
+	rows, err := pool.Query(ctx, sql, args)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	out, err := run("websearch_to_tsquery")
-	if err == nil {
-		return out, nil
+	var out []FTSHit
+	for rows.Next() {
+		var h FTSHit
+		if err := rows.Scan(&h.EntityType, &h.EntityID, &h.Language, &h.Score); err != nil {
+			return nil, err
+		}
+		out = append(out, h)
 	}
-	return run("plainto_tsquery")
+	return out, rows.Err()
 }
@@ -0,0 +1,251 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// FusionMethod selects how ranked lists are fused.
+//
+// RRF only uses ranks. The other methods use the backends' raw scores, so a
+// strong exact lexical hit outranks a barely relevant semantic neighbor at
+// the same rank. Raw scores live on different scales (ts_rank_cd, pg_trgm
+// similarity, PGroonga score, cosine similarity), so each list is normalized
+// first.
+type FusionMethod string
+
+const (
+	// FusionRRF is Reciprocal Rank Fusion (see FuseRRF). The default.
+	FusionRRF FusionMethod = "rrf"
+	// FusionMinMax is a convex combination of min-max normalized scores:
+	//
+	//	score(doc) = Σ w_i·(s_i - min_i)/(max_i - min_i) / Σ w_i
+	//
+	// Documents missing from a list get 0 for it.
+	FusionMinMax FusionMethod = "min_max"
+	// FusionZScore is a convex combination of z-score normalized scores:
+	//
+	//	score(doc) = Σ w_i·(z_i - min_i(z)) / Σ w_i,  z_i = (s_i - mean_i)/stddev_i
+	//
+	// Shifting by the list's lowest z-score makes documents missing from a
+	// list (which get 0 for it) rank as if they had its lowest score.
+	FusionZScore FusionMethod = "z_score"
+	// FusionDBSF is Distribution-Based Score Fusion: each list is scaled to
+	// [0..1] between mean-3σ and mean+3σ (clamped), and the weighted scaled
+	// scores are summed. Documents missing from a list get 0 for it.
+	FusionDBSF FusionMethod = "dbsf"
+)
+
+// ValidFusionMethod reports whether m is a known method ("" means FusionRRF).
+func ValidFusionMethod(m FusionMethod) bool {
+	switch m {
+	case "", FusionRRF, FusionMinMax, FusionZScore, FusionDBSF:
+		return true
+	default:
+		return false
+	}
+}
+
+// ScoredKey is a ranked list item with its backend's raw score (higher is
+// better).
+type ScoredKey struct {
+	RRFKey
+	Score float32
+}
+
+type FusionOptions struct {
+	// Method defaults to FusionRRF.
+	Method FusionMethod
+	// K is the RRF stabilizer constant (FusionRRF only; see RRFOptions.K).
+	K int
+	// Weights applied to each list. Empty => all 1.0.
+	Weights []float32
+	// Window bounds how many items at the top of each list the normalization
+	// statistics (min/max, mean/stddev) are computed from; 0 means all of
+	// them. Callers that paginate by re-running retrieval deeper set it and
+	// retrieve at least Window items per list, so a hit's fused score does
+	// not depend on the page. Items below the window are scaled with the
+	// window's statistics, clamped at 0 (as if missing from the list).
+	Window int
+}
+
+// FusionContributions returns the score each item of each list adds to its
+// fused score under opts (index-aligned with lists).
+func FusionContributions(lists [][]ScoredKey, opts FusionOptions) ([][]float32, error) {
+	if !ValidFusionMethod(opts.Method) {
+		return nil, fmt.Errorf("invalid fusion method %q", opts.Method)
+	}
+	weights := listWeights(len(lists), opts.Weights)
+	total := float32(0)
+	for _, w := range weights {
+		total += w
+	}
+
+	out := make([][]float32, len(lists))
+	for li, list := range lists {
+		w := weights[li]
+		out[li] = make([]float32, len(list))
+		if opts.Method == "" || opts.Method == FusionRRF {
+			for i := range list {
+				out[li][i] = RRFContribution(opts.K, w, i+1)
+			}
+			continue
+		}
+		norm := NormalizeScoresWindow(scoresOf(list), opts.Method, opts.Window)
+		for i, n := range norm {
+			switch opts.Method {
+			case FusionDBSF:
+				out[li][i] = w * n
+			default:
+				out[li][i] = w * n / total
+			}
+		}
+	}
+	return out, nil
+}
+
+// Fuse fuses ranked lists of scored items with opts.Method. Input lists are
+// expected to be ordered best-first; each item should appear at most once per
+// list.
+func Fuse(lists [][]ScoredKey, opts FusionOptions) ([]RRFHit, error) {
+	contribs, err := FusionContributions(lists, opts)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]float32)
+	example := make(map[string]RRFKey)
+	for li, list := range lists {
+		for i, item := range list {
+			ks := item.keyString()
+			example[ks] = item.RRFKey
+			scores[ks] += contribs[li][i]
+		}
+	}
+
+	out := make([]RRFHit, 0, len(scores))
+	for ks, sc := range scores {
+		out = append(out, RRFHit{RRFKey: example[ks], Score: sc})
+	}
+	sortFused(out)
+	return out, nil
+}
+
+// NormalizeScores maps one list's raw scores onto a common scale for method:
+//   - FusionMinMax: [0..1].
+//   - FusionZScore: standard scores, shifted so the lowest is 0.
+//   - FusionDBSF: [0..1] between mean-3σ and mean+3σ.
+//
+// All-equal scores (e.g. a single-item list) map to 1. FusionRRF (or "")
+// returns the scores unchanged.
+func NormalizeScores(scores []float32, method FusionMethod) []float32 {
+	return NormalizeScoresWindow(scores, method, 0)
+}
+
+// NormalizeScoresWindow is NormalizeScores with the statistics computed from
+// the first window scores (0 = all), as Fuse normalizes with
+// FusionOptions.Window.
+func NormalizeScoresWindow(scores []float32, method FusionMethod, window int) []float32 {
+	out := make([]float32, len(scores))
+	if len(scores) == 0 {
+		return out
+	}
+	stats := scores
+	if window > 0 && window < len(scores) {
+		stats = scores[:window]
+	}
+	switch method {
+	case FusionMinMax:
+		lo, hi := stats[0], stats[0]
+		for _, s := range stats[1:] {
+			lo = min(lo, s)
+			hi = max(hi, s)
+		}
+		for i, s := range scores {
+			switch {
+			case s < lo:
+				out[i] = 0
+			case hi == lo:
+				out[i] = 1
+			default:
+				out[i] = (s - lo) / (hi - lo)
+			}
+		}
+	case FusionZScore, FusionDBSF:
+		mean, std := meanStd(stats)
+		lowest := float64(stats[0])
+		for _, s := range stats[1:] {
+			lowest = math.Min(lowest, float64(s))
+		}
+		for i, s := range scores {
+			switch {
+			case method == FusionZScore && float64(s) < lowest:
+				out[i] = 0
+			case std == 0:
+				out[i] = 1
+			case method == FusionZScore:
+				out[i] = float32((float64(s) - lowest) / std)
+			default:
+				lo := mean - 3*std
+				v := (float64(s) - lo) / (6 * std)
+				out[i] = float32(math.Max(0, math.Min(1, v)))
+			}
+		}
+	default:
+		copy(out, scores)
+	}
+	return out
+}
+
+func meanStd(scores []float32) (mean, std float64) {
+	for _, s := range scores {
+		mean += float64(s)
+	}
+	mean /= float64(len(scores))
+	for _, s := range scores {
+		d := float64(s) - mean
+		std += d * d
+	}
+	return mean, math.Sqrt(std / float64(len(scores)))
+}
+
+func scoresOf(list []ScoredKey) []float32 {
+	out := make([]float32, len(list))
+	for i, item := range list {
+		out[i] = item.Score
+	}
+	return out
+}
+
+// listWeights returns n weights: weights[i] when > 0, else 1.0 (as FuseRRF).
+func listWeights(n int, weights []float32) []float32 {
+	out := make([]float32, n)
+	for i := range out {
+		out[i] = 1.0
+		if i < len(weights) && weights[i] > 0 {
+			out[i] = weights[i]
+		}
+	}
+	return out
+}
+
+// sortFused orders hits by score, breaking ties on the full key so the order
+// is deterministic (callers paginate over it).
+func sortFused(out []RRFHit) {
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.EntityType != b.EntityType {
+			return a.EntityType < b.EntityType
+		}
+		if a.EntityID != b.EntityID {
+			return a.EntityID < b.EntityID
+		}
+		if a.Language != b.Language {
+			return a.Language < b.Language
+		}
+		return a.Model < b.Model
+	})
+}
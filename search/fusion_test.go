@@ -0,0 +1,116 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func scored(id string, score float32) ScoredKey {
+	return ScoredKey{RRFKey: RRFKey{EntityType: "gallery", EntityID: id, Language: "en"}, Score: score}
+}
+
+func TestNormalizeScores(t *testing.T) {
+	scores := []float32{4, 2, 0}
+	cases := []struct {
+		method FusionMethod
+		want   []float32
+	}{
+		{method: FusionMinMax, want: []float32{1, 0.5, 0}},
+		// mean 2, stddev sqrt(8/3).
+		{method: FusionZScore, want: []float32{2.4494898, 1.2247449, 0}},
+		{method: FusionDBSF, want: []float32{0.7041241, 0.5, 0.29587585}},
+		{method: FusionRRF, want: []float32{4, 2, 0}},
+	}
+	for _, tc := range cases {
+		got := NormalizeScores(scores, tc.method)
+		for i := range tc.want {
+			if math.Abs(float64(got[i]-tc.want[i])) > 1e-5 {
+				t.Fatalf("NormalizeScores(%s) = %v; want %v", tc.method, got, tc.want)
+			}
+		}
+	}
+	for _, m := range []FusionMethod{FusionMinMax, FusionZScore, FusionDBSF} {
+		if got := NormalizeScores([]float32{0.3}, m); got[0] != 1 {
+			t.Fatalf("single score under %s = %v; want 1", m, got[0])
+		}
+	}
+}
+
+func TestFuse_WindowKeepsScoresAcrossDepths(t *testing.T) {
+	// The same list retrieved two and four deep (pages 1 and 2): with a
+	// window, the shared top items fuse to the same scores.
+	deep := []ScoredKey{scored("a", 4), scored("b", 2), scored("c", 1), scored("d", 0)}
+	for _, m := range []FusionMethod{FusionMinMax, FusionZScore, FusionDBSF} {
+		opts := FusionOptions{Method: m, Window: 2}
+		shallow, err := FusionContributions([][]ScoredKey{deep[:2]}, opts)
+		if err != nil {
+			t.Fatalf("FusionContributions: %v", err)
+		}
+		full, err := FusionContributions([][]ScoredKey{deep}, opts)
+		if err != nil {
+			t.Fatalf("FusionContributions: %v", err)
+		}
+		for i := range shallow[0] {
+			if shallow[0][i] != full[0][i] {
+				t.Fatalf("%s: contribution %d = %v at depth 2, %v at depth 4", m, i, shallow[0][i], full[0][i])
+			}
+		}
+		for i := 2; i < len(full[0]); i++ {
+			if full[0][i] < 0 || full[0][i] > full[0][1] {
+				t.Fatalf("%s: below-window contribution %d = %v; want within [0, %v]", m, i, full[0][i], full[0][1])
+			}
+		}
+	}
+}
+
+func TestFuse_ScoreMagnitudes(t *testing.T) {
+	// RRF ties X and Y (ranks 1+2 each). X is far ahead of Y in the first
+	// list and barely behind it in the second, so score fusion prefers X.
+	lexical := []ScoredKey{scored("X", 0.9), scored("Y", 0.1)}
+	semantic := []ScoredKey{scored("Y", 0.51), scored("X", 0.50), scored("Z", 0.1)}
+
+	rrf, err := Fuse([][]ScoredKey{lexical, semantic}, FusionOptions{K: 60})
+	if err != nil {
+		t.Fatalf("Fuse: %v", err)
+	}
+	want := FuseRRF([][]RRFKey{keysOf(lexical), keysOf(semantic)}, RRFOptions{K: 60})
+	for i := range want {
+		if rrf[i] != want[i] {
+			t.Fatalf("Fuse(rrf) = %v; want FuseRRF %v", rrf, want)
+		}
+	}
+
+	for _, m := range []FusionMethod{FusionMinMax, FusionZScore, FusionDBSF} {
+		out, err := Fuse([][]ScoredKey{lexical, semantic}, FusionOptions{Method: m})
+		if err != nil {
+			t.Fatalf("Fuse(%s): %v", m, err)
+		}
+		if out[0].EntityID != "X" || out[0].Score == out[1].Score {
+			t.Fatalf("Fuse(%s) = %v; want X first", m, out)
+		}
+	}
+
+	if _, err := Fuse(nil, FusionOptions{Method: "borda"}); err == nil {
+		t.Fatalf("expected invalid method error")
+	}
+}
+
+func TestFuse_ConvexWeights(t *testing.T) {
+	a := []ScoredKey{scored("A", 1), scored("B", 0)}
+	b := []ScoredKey{scored("B", 1), scored("A", 0)}
+	out, err := Fuse([][]ScoredKey{a, b}, FusionOptions{Method: FusionMinMax, Weights: []float32{3, 1}})
+	if err != nil {
+		t.Fatalf("Fuse: %v", err)
+	}
+	if out[0].EntityID != "A" || math.Abs(float64(out[0].Score-0.75)) > 1e-6 || math.Abs(float64(out[1].Score-0.25)) > 1e-6 {
+		t.Fatalf("unexpected convex combination %v", out)
+	}
+}
+
+func keysOf(list []ScoredKey) []RRFKey {
+	out := make([]RRFKey, len(list))
+	for i, item := range list {
+		out[i] = item.RRFKey
+	}
+	return out
+}
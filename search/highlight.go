@@ -0,0 +1,256 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+)
+
+// DocKey identifies one `search_documents` row.
+type DocKey struct {
+	EntityType string
+	EntityID   string
+	Language   string
+}
+
+type HeadlineOptions struct {
+	Schema string
+
+	// StartSel and StopSel wrap each matched span. They must not contain '"'
+	// or ','.
+	StartSel string
+	StopSel  string
+
+	// Snippet returns fragments around the matches instead of the whole
+	// document.
+	Snippet bool
+	// MaxWords / MinWords bound fragment length (Snippet only; FTS defaults
+	// 35/15). For PGroonga, MaxWords*6 is used as the snippet width in bytes.
+	MaxWords int
+	MinWords int
+	// MaxFragments is the maximum number of fragments (Snippet only; default 1).
+	MaxFragments int
+	// FragmentDelimiter joins fragments (default " ... ").
+	FragmentDelimiter string
+}
+
+func (o HeadlineOptions) validate() error {
+	if strings.TrimSpace(o.Schema) == "" {
+		return fmt.Errorf("schema is required")
+	}
+	if o.StartSel == "" || o.StopSel == "" {
+		return fmt.Errorf("StartSel and StopSel are required")
+	}
+	for _, s := range []string{o.StartSel, o.StopSel, o.FragmentDelimiter} {
+		if strings.ContainsAny(s, `",`) {
+			return fmt.Errorf("highlight markers must not contain '\"' or ','")
+		}
+	}
+	return nil
+}
+
+func (o HeadlineOptions) fragmentDelimiter() string {
+	if o.FragmentDelimiter == "" {
+		return " ... "
+	}
+	return o.FragmentDelimiter
+}
+
+// tsHeadlineOptions renders opts into a ts_headline option string.
+func (o HeadlineOptions) tsHeadlineOptions() string {
+	parts := []string{
+		fmt.Sprintf(`StartSel="%s"`, o.StartSel),
+		fmt.Sprintf(`StopSel="%s"`, o.StopSel),
+	}
+	if !o.Snippet {
+		return strings.Join(append(parts, "HighlightAll=true"), ", ")
+	}
+	maxWords, minWords, maxFragments := o.MaxWords, o.MinWords, o.MaxFragments
+	if maxWords <= 0 {
+		maxWords = 35
+	}
+	if minWords <= 0 || minWords >= maxWords {
+		minWords = maxWords / 2
+	}
+	if maxFragments <= 0 {
+		maxFragments = 1
+	}
+	parts = append(parts,
+		fmt.Sprintf("MaxWords=%d", maxWords),
+		fmt.Sprintf("MinWords=%d", minWords),
+		fmt.Sprintf("MaxFragments=%d", maxFragments),
+		fmt.Sprintf(`FragmentDelimiter="%s"`, o.fragmentDelimiter()),
+	)
+	return strings.Join(parts, ", ")
+}
+
+func docKeyArgs(keys []DocKey) pgx.NamedArgs {
+	types := make([]string, len(keys))
+	ids := make([]string, len(keys))
+	langs := make([]string, len(keys))
+	for i, k := range keys {
+		types[i], ids[i], langs[i] = k.EntityType, k.EntityID, k.Language
+	}
+	return pgx.NamedArgs{
+		"entity_types": types,
+		"entity_ids":   ids,
+		"languages":    langs,
+	}
+}
+
+const docKeysJoin = `
+	FROM unnest(@entity_types::text[], @entity_ids::text[], @languages::text[]) AS k(entity_type, entity_id, language)
+	JOIN %s sd ON sd.entity_type = k.entity_type AND sd.entity_id = k.entity_id AND sd.language = k.language
+	WHERE sd.raw_document IS NOT NULL`
+
+// docKeysConfigJoin is docKeysJoin with `cfg.config`, the text search config
+// of the key's language, resolved once per language rather than per document.
+// It is formatted with the quoted schema and the select list, and starts the
+// statement.
+const docKeysConfigJoin = `
+	WITH cfg AS MATERIALIZED (
+		SELECT l.language, %[1]s.searchkit_regconfig_for_language(l.language) AS config
+		FROM (SELECT DISTINCT unnest(@languages::text[]) AS language) l
+	)
+	SELECT %[2]s
+	FROM unnest(@entity_types::text[], @entity_ids::text[], @languages::text[]) AS k(entity_type, entity_id, language)
+	JOIN %[1]s.search_documents sd ON sd.entity_type = k.entity_type AND sd.entity_id = k.entity_id AND sd.language = k.language
+	JOIN cfg ON cfg.language = k.language
+	WHERE sd.raw_document IS NOT NULL`
+
+func scanDocTexts(ctx context.Context, pool *pgxpool.Pool, sql string, args pgx.NamedArgs) (map[DocKey]string, error) {
+	rows, err := pool.Query(ctx, sql, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[DocKey]string{}
+	for rows.Next() {
+		var k DocKey
+		var text string
+		if err := rows.Scan(&k.EntityType, &k.EntityID, &k.Language, &text); err != nil {
+			return nil, err
+		}
+		out[k] = text
+	}
+	return out, rows.Err()
+}
+
+// RawDocuments returns `search_documents.raw_document` for keys (missing rows
+// are omitted).
+func RawDocuments(ctx context.Context, pool *pgxpool.Pool, schema string, keys []DocKey) (map[DocKey]string, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	quotedSchema, err := quoteIdent(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if len(keys) == 0 {
+		return map[DocKey]string{}, nil
+	}
+	sql := `SELECT k.entity_type, k.entity_id, k.language, sd.raw_document` +
+		fmt.Sprintf(docKeysJoin, quotedSchema+".search_documents")
+	return scanDocTexts(ctx, pool, sql, docKeyArgs(keys))
+}
+
+// FTSHeadlines highlights query matches in each document's raw_document using
+// ts_headline with the document language's text search config, so stemmed
+// matches are marked the same way FTSSearch matched them.
+func FTSHeadlines(ctx context.Context, pool *pgxpool.Pool, query string, keys []DocKey, opts HeadlineOptions) (map[DocKey]string, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	quotedSchema, err := quoteIdent(opts.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	// Highlights mark words anywhere in the raw text, so field scopes do not
+	// apply.
+	parsed := querynorm.ParseQuery(query).Unscoped()
+	if len(keys) == 0 || !parsed.HasPositive() {
+		return map[DocKey]string{}, nil
+	}
+
+	args := docKeyArgs(keys)
+	args["hl_opts"] = opts.tsHeadlineOptions()
+	tsq := bindTSQuery(parsed, &constraintParams{args: args, bound: args})
+	sql := fmt.Sprintf(docKeysConfigJoin, quotedSchema,
+		"k.entity_type, k.entity_id, k.language, ts_headline(cfg.config, sd.raw_document, "+tsq+", @hl_opts)")
+	return scanDocTexts(ctx, pool, sql, args)
+}
+
+// PGroongaHighlights highlights query keywords in each document's raw_document
+// using pgroonga_highlight_html (or pgroonga_snippet_html when opts.Snippet).
+//
+// PGroonga's HTML output is converted back to plain text: keyword tags become
+// StartSel/StopSel and HTML entities are unescaped.
+func PGroongaHighlights(ctx context.Context, pool *pgxpool.Pool, query string, keys []DocKey, opts HeadlineOptions) (map[DocKey]string, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	quotedSchema, err := quoteIdent(opts.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	q := querynorm.ParseQuery(query).PGroonga()
+	if len(keys) == 0 || q == "" {
+		return map[DocKey]string{}, nil
+	}
+	extSchema, err := getPGroongaExtensionSchema(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	qext, err := quoteIdent(extSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pgroonga schema: %w", err)
+	}
+
+	args := docKeyArgs(keys)
+	args["q"] = q
+	expr := fmt.Sprintf("%[1]s.pgroonga_highlight_html(sd.raw_document, %[1]s.pgroonga_query_extract_keywords(@q))", qext)
+	if opts.Snippet {
+		width := opts.MaxWords * 6
+		if width <= 0 {
+			width = 200
+		}
+		args["width"] = width
+		args["delimiter"] = opts.fragmentDelimiter()
+		expr = fmt.Sprintf("array_to_string(%[1]s.pgroonga_snippet_html(sd.raw_document, %[1]s.pgroonga_query_extract_keywords(@q), @width), @delimiter)", qext)
+	}
+	sql := fmt.Sprintf(`SELECT k.entity_type, k.entity_id, k.language, %s`, expr) +
+		fmt.Sprintf(docKeysJoin, quotedSchema+".search_documents")
+
+	out, err := scanDocTexts(ctx, pool, sql, args)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range out {
+		out[k] = pgroongaHTMLToMarked(v, opts.StartSel, opts.StopSel)
+	}
+	return out, nil
+}
+
+// pgroongaHTMLToMarked converts pgroonga_highlight_html/pgroonga_snippet_html
+// output to plain text with the given markers. The document text in that output
+// is HTML-escaped, so only PGroonga's own tags can contain '<'.
+func pgroongaHTMLToMarked(s string, startSel string, stopSel string) string {
+	s = strings.ReplaceAll(s, `<span class="keyword">`, "\x00start\x00")
+	s = strings.ReplaceAll(s, `</span>`, "\x00stop\x00")
+	s = html.UnescapeString(s)
+	s = strings.ReplaceAll(s, "\x00start\x00", startSel)
+	return strings.ReplaceAll(s, "\x00stop\x00", stopSel)
+}
@@ -0,0 +1,33 @@
+package search
+
+import "testing"
+
+func TestPGroongaHTMLToMarked(t *testing.T) {
+	in := `&lt;b&gt; <span class="keyword">東京</span> &amp; 大阪`
+	got := pgroongaHTMLToMarked(in, "[", "]")
+	if want := "<b> [東京] & 大阪"; got != want {
+		t.Fatalf("pgroongaHTMLToMarked = %q; want %q", got, want)
+	}
+}
+
+func TestHeadlineOptions(t *testing.T) {
+	opts := HeadlineOptions{Schema: "s", StartSel: "\x02", StopSel: "\x03"}
+	if err := opts.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if got, want := opts.tsHeadlineOptions(), "StartSel=\"\x02\", StopSel=\"\x03\", HighlightAll=true"; got != want {
+		t.Fatalf("tsHeadlineOptions = %q; want %q", got, want)
+	}
+
+	opts.Snippet = true
+	opts.MaxWords = 20
+	want := "StartSel=\"\x02\", StopSel=\"\x03\", MaxWords=20, MinWords=10, MaxFragments=1, FragmentDelimiter=\" ... \""
+	if got := opts.tsHeadlineOptions(); got != want {
+		t.Fatalf("tsHeadlineOptions = %q; want %q", got, want)
+	}
+
+	opts.StartSel = `<mark class="x">`
+	if err := opts.validate(); err == nil {
+		t.Fatalf("expected error for marker containing a quote")
+	}
+}
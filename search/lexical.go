@@ -8,6 +8,7 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
 	"github.com/open-rails/searchkit/internal/textnormalize"
 )
 
@@ -24,6 +25,16 @@ type LexicalOptions struct {
 	EntityTypes   []string
 	Limit         int
 	MinSimilarity float32
+	// TitleBoost adds TitleBoost * similarity(title, q) to the score of
+	// documents with a title (A-weight text of field-weighted documents), and
+	// lets title matches qualify on their own. The boosted score is capped at
+	// 1, like an unboosted similarity. 0 disables.
+	TitleBoost float32
+	// Constraints is the user query (searchkit syntax) the trigram text was
+	// derived from, e.g. one of querynorm's trigram probes. Its field scopes
+	// and exclusions, which trigram similarity cannot express, are enforced
+	// in the WHERE clause.
+	Constraints string
 
 	// FilterSQL is an optional additional WHERE fragment appended to the query as:
 	//   ... AND (<FilterSQL>)
@@ -37,6 +48,9 @@ type LexicalOptions struct {
 	// FilterArgs are named args referenced by FilterSQL using pgx '@name'
 	// placeholders (e.g. "... language = @lang").
 	FilterArgs map[string]any
+	// Filter is a builder alternative to FilterSQL/FilterArgs (see Filter);
+	// both are ANDed when set.
+	Filter Filter
 }
 
 // LexicalSearch runs a trigram similarity search against `<schema>.search_documents`.
@@ -78,12 +92,26 @@ func LexicalSearch(ctx context.Context, pool *pgxpool.Pool, query string, opts L
 		where += " AND sd.entity_type = ANY(@entity_types::text[])"
 		args["entity_types"] = opts.EntityTypes
 	}
-	if strings.TrimSpace(opts.FilterSQL) != "" {
-		where += " AND (" + opts.FilterSQL + ")"
-		if err := mergeNamedArgs(args, opts.FilterArgs); err != nil {
+	if opts.TitleBoost > 0 {
+		args["title_boost"] = opts.TitleBoost
+	}
+	filterSQL, filterArgs, err := CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(filterSQL) != "" {
+		where += " AND (" + filterSQL + ")"
+		if err := mergeNamedArgs(args, filterArgs); err != nil {
 			return nil, err
 		}
 	}
+	constraints, err := queryConstraintsSQL(quotedSchema, opts.Language, querynorm.ParseQuery(opts.Constraints), true, true, args)
+	if err != nil {
+		return nil, err
+	}
+	if constraints != "" {
+		where += " AND " + constraints
+	}
 
 	// Use both `%` (fast candidate filter via gin_trgm_ops) and similarity threshold.
 	// Note: `%` is sensitive to pg_trgm similarity threshold setting; we still apply
@@ -98,19 +126,20 @@ func LexicalSearch(ctx context.Context, pool *pgxpool.Pool, query string, opts L
 	// (set via `set_limit`). To ensure `MinSimilarity` is respected (and to keep
 	// the GIN trigram index usable for candidate filtering), we set the limit via
 	// a CTE and *reference it* so Postgres can't optimize it away.
+	scoreExpr, matchExpr := lexicalScoreSQL(opts.TitleBoost)
 	sql := fmt.Sprintf(`
 		WITH _ AS (SELECT set_limit(@min_similarity))
 		SELECT
 			sd.entity_type,
 			sd.entity_id,
 			sd.language,
-			SIMILARITY(sd.document, @q)::float4 AS score
+			%s::float4 AS score
 		FROM _, %s sd
 		%s
-		  AND sd.document %% @q
+		  AND %s
 		ORDER BY score DESC, sd.entity_type ASC, sd.entity_id ASC
 		LIMIT @limit
-	`, table, where)
+	`, scoreExpr, table, where, matchExpr)
 
 	rows, err := pool.Query(ctx, sql, args)
 	if err != nil {
@@ -128,3 +157,14 @@ func LexicalSearch(ctx context.Context, pool *pgxpool.Pool, query string, opts L
 	}
 	return out, rows.Err()
 }
+
+// lexicalScoreSQL returns LexicalSearch's score and match expressions. With a
+// title boost, title matches qualify on their own and the score stays in
+// [0, 1] so MinScore thresholds mean the same with and without it.
+func lexicalScoreSQL(titleBoost float32) (scoreExpr string, matchExpr string) {
+	if titleBoost <= 0 {
+		return "SIMILARITY(sd.document, @q)", "sd.document % @q"
+	}
+	return "least(1, SIMILARITY(sd.document, @q) + @title_boost * coalesce(SIMILARITY(sd.title, @q), 0))",
+		"(sd.document % @q OR sd.title % @q)"
+}
@@ -0,0 +1,24 @@
+package search
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLexicalScoreSQL(t *testing.T) {
+	score, match := lexicalScoreSQL(0)
+	if score != "SIMILARITY(sd.document, @q)" || match != "sd.document % @q" {
+		t.Fatalf("unboosted: got score %q, match %q", score, match)
+	}
+
+	score, match = lexicalScoreSQL(0.5)
+	if !strings.Contains(score, "@title_boost * coalesce(SIMILARITY(sd.title, @q), 0)") {
+		t.Fatalf("expected title similarity in score, got %q", score)
+	}
+	if !strings.HasPrefix(score, "least(1, ") {
+		t.Fatalf("expected boosted score capped at 1, got %q", score)
+	}
+	if match != "(sd.document % @q OR sd.title % @q)" {
+		t.Fatalf("expected title matches to qualify, got %q", match)
+	}
+}
@@ -1,13 +1,20 @@
 package search
 
-import "math"
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
+)
 
 // MMRReRank applies Maximal Marginal Relevance to an initial candidate list.
 //
-// This is intentionally generic: searchkit does not assume it can access
-// stored vectors for candidates. Callers must provide a similarity function
-// between two candidates (e.g. computed from vectors, or approximated by
-// metadata).
+// This is intentionally generic: callers provide a similarity function between
+// two candidates (e.g. CosineSimilarity of StoredVectors, or approximated by
+// metadata). Hit.Similarity is the candidate's relevance.
 //
 // lambda must be in [0..1]. Higher means "more relevance, less diversity".
 func MMRReRank(hits []Hit, k int, lambda float32, candidateSim func(a, b Hit) float32) []Hit {
@@ -23,30 +30,30 @@ func MMRReRank(hits []Hit, k int, lambda float32, candidateSim func(a, b Hit) fl
 		lambda = 1
 	}
 
+	// redundancy[i] is candidate i's highest similarity to the selected hits,
+	// updated with the latest selection only: O(len(hits)·k) candidateSim
+	// calls.
 	selected := make([]Hit, 0, k)
-	remaining := make([]Hit, len(hits))
-	copy(remaining, hits)
+	picked := make([]bool, len(hits))
+	redundancy := make([]float32, len(hits))
 
 	// Always take the top-1 by similarity first (assumes caller pre-sorted).
-	selected = append(selected, remaining[0])
-	remaining = append(remaining[:0], remaining[1:]...)
+	selected = append(selected, hits[0])
+	picked[0] = true
+	last := 0
 
-	for len(selected) < k && len(remaining) > 0 {
+	for len(selected) < k {
 		bestIdx := -1
 		bestScore := float32(-math.MaxFloat32)
 
-		for i, cand := range remaining {
-			rel := cand.Similarity
-
-			maxRedundancy := float32(0)
-			for _, sel := range selected {
-				sim := candidateSim(cand, sel)
-				if sim > maxRedundancy {
-					maxRedundancy = sim
-				}
+		for i, cand := range hits {
+			if picked[i] {
+				continue
 			}
-
-			score := lambda*rel - (1-lambda)*maxRedundancy
+			if sim := candidateSim(cand, hits[last]); sim > redundancy[i] {
+				redundancy[i] = sim
+			}
+			score := lambda*cand.Similarity - (1-lambda)*redundancy[i]
 			if score > bestScore {
 				bestScore = score
 				bestIdx = i
@@ -57,9 +64,71 @@ func MMRReRank(hits []Hit, k int, lambda float32, candidateSim func(a, b Hit) fl
 			break
 		}
 
-		selected = append(selected, remaining[bestIdx])
-		remaining = append(remaining[:bestIdx], remaining[bestIdx+1:]...)
+		selected = append(selected, hits[bestIdx])
+		picked[bestIdx] = true
+		last = bestIdx
 	}
 
 	return selected
 }
+
+// StoredVectors returns the `embedding_vectors` embeddings of keys for model
+// (keys without a vector are omitted).
+func StoredVectors(ctx context.Context, pool *pgxpool.Pool, schema string, model string, keys []DocKey) (map[DocKey][]float32, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(model) == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	quotedSchema, err := quoteIdent(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if len(keys) == 0 {
+		return map[DocKey][]float32{}, nil
+	}
+	sql := fmt.Sprintf(`
+		SELECT k.entity_type, k.entity_id, k.language, ev.embedding
+		FROM unnest(@entity_types::text[], @entity_ids::text[], @languages::text[]) AS k(entity_type, entity_id, language)
+		JOIN %s.embedding_vectors ev
+		  ON ev.entity_type = k.entity_type AND ev.entity_id = k.entity_id AND ev.language = k.language
+		WHERE ev.model = @model AND ev.embedding IS NOT NULL
+	`, quotedSchema)
+	args := docKeyArgs(keys)
+	args["model"] = model
+	rows, err := pool.Query(ctx, sql, args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[DocKey][]float32{}
+	for rows.Next() {
+		var k DocKey
+		var vec pgvector.HalfVector
+		if err := rows.Scan(&k.EntityType, &k.EntityID, &k.Language, &vec); err != nil {
+			return nil, err
+		}
+		out[k] = vec.Slice()
+	}
+	return out, rows.Err()
+}
+
+// CosineSimilarity returns the cosine similarity of a and b (0 when either is
+// empty, zero or their dimensions differ).
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
@@ -0,0 +1,25 @@
+package search
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosineSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b []float32
+		want float32
+	}{
+		{a: []float32{1, 0}, b: []float32{2, 0}, want: 1},
+		{a: []float32{1, 0}, b: []float32{0, 3}, want: 0},
+		{a: []float32{1, 1}, b: []float32{-1, -1}, want: -1},
+		{a: []float32{1, 0}, b: []float32{1, 0, 0}, want: 0},
+		{a: nil, b: []float32{1}, want: 0},
+		{a: []float32{0, 0}, b: []float32{1, 0}, want: 0},
+	}
+	for _, tc := range cases {
+		if got := CosineSimilarity(tc.a, tc.b); math.Abs(float64(got-tc.want)) > 1e-6 {
+			t.Fatalf("CosineSimilarity(%v, %v) = %v; want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
 )
 
 var pgroongaExtensionSchema struct {
@@ -61,10 +62,23 @@ type PGroongaOptions struct {
 	// token is treated as a prefix.
 	Prefix bool
 
+	// Structured parses the query with querynorm.ParseQuery (phrases, OR,
+	// exclusions) and compiles it to escaped PGroonga query syntax, instead
+	// of dropping every operator. Field scopes are enforced as in
+	// LexicalOptions.Constraints. Ignored when Prefix is set.
+	Structured bool
+	// Synonyms expands structured queries (see FTSOptions.Synonyms).
+	Synonyms map[string][]string
+
 	// ScoreK controls normalization: normalized = raw / (raw + ScoreK).
 	// Defaults to 1.
 	ScoreK float32
 
+	// TitleBoost multiplies the raw score by (1 + TitleBoost) for documents
+	// whose title (A-weight text of field-weighted documents) also matches.
+	// 0 disables.
+	TitleBoost float32
+
 	// FilterSQL is an optional additional WHERE fragment appended to the query as:
 	//   ... AND (<FilterSQL>)
 	//
@@ -77,6 +91,9 @@ type PGroongaOptions struct {
 	// FilterArgs are named args referenced by FilterSQL using pgx '@name'
 	// placeholders (e.g. "... language = @lang").
 	FilterArgs map[string]any
+	// Filter is a builder alternative to FilterSQL/FilterArgs (see Filter);
+	// both are ANDed when set.
+	Filter Filter
 }
 
 // NormalizePGroongaScore converts a raw PGroonga score into a [0..1] range
@@ -147,7 +164,7 @@ func buildPGroongaTypeaheadQuery(q string) string {
 	return strings.Join(toks, " ")
 }
 
-func buildPGroongaSQL(docSchema string, extSchema string, entityTypes []string, filterSQL string, filterArgs map[string]any) (string, pgx.NamedArgs, string, error) {
+func buildPGroongaSQL(docSchema string, extSchema string, entityTypes []string, titleBoost float32, filterSQL string, filterArgs map[string]any) (string, pgx.NamedArgs, string, error) {
 	qs, err := quoteIdent(docSchema)
 	if err != nil {
 		return "", nil, "", fmt.Errorf("invalid schema: %w", err)
@@ -169,6 +186,11 @@ func buildPGroongaSQL(docSchema string, extSchema string, entityTypes []string,
 		where += " AND sd.entity_type = ANY(@entity_types::text[])"
 		args["entity_types"] = entityTypes
 	}
+	scoreExpr := fmt.Sprintf("%s.pgroonga_score(tableoid, ctid)", qext)
+	if titleBoost > 0 {
+		args["title_boost"] = titleBoost
+		scoreExpr = fmt.Sprintf("%[1]s.pgroonga_score(tableoid, ctid) * (1 + @title_boost * (coalesce(sd.raw_title OPERATOR(%[1]s.&@~) @q, false))::int)", qext)
+	}
 	if strings.TrimSpace(filterSQL) != "" {
 		where += " AND (" + filterSQL + ")"
 		if err := mergeNamedArgs(args, filterArgs); err != nil {
@@ -183,13 +205,13 @@ func buildPGroongaSQL(docSchema string, extSchema string, entityTypes []string,
 			sd.entity_type,
 			sd.entity_id,
 			sd.language,
-			%[1]s.pgroonga_score(tableoid, ctid)::float4 AS raw_score
-		FROM %s sd
-		%s
+			(%[2]s)::float4 AS raw_score
+		FROM %[3]s sd
+		%[4]s
 		  AND sd.raw_document OPERATOR(%[1]s.&@~) @q
 		ORDER BY raw_score DESC, sd.entity_type ASC, sd.entity_id ASC
 		LIMIT @limit
-	`, qext, table, where)
+	`, qext, scoreExpr, table, where)
 
 	return sql, args, table, nil
 }
@@ -219,7 +241,14 @@ func PGroongaSearch(ctx context.Context, pool *pgxpool.Pool, query string, opts
 		return []PGroongaHit{}, nil
 	}
 
-	q = sanitizePGroongaQuery(q)
+	var parsed *querynorm.Query
+	if opts.Structured && !opts.Prefix {
+		pq := querynorm.ParseQuery(q).ExpandSynonyms(opts.Synonyms)
+		parsed = &pq
+		q = pq.PGroonga()
+	} else {
+		q = sanitizePGroongaQuery(q)
+	}
 	if q == "" {
 		return []PGroongaHit{}, nil
 	}
@@ -235,7 +264,27 @@ func PGroongaSearch(ctx context.Context, pool *pgxpool.Pool, query string, opts
 		return nil, err
 	}
 
-	sql, args, _, err := buildPGroongaSQL(opts.Schema, extSchema, opts.EntityTypes, opts.FilterSQL, opts.FilterArgs)
+	filterSQL, filterArgs, err := CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if parsed != nil && parsed.HasFieldScopes() {
+		// PGroonga's query syntax has no field scopes; enforce them like
+		// LexicalSearch does (exclusions are part of the PGroonga query).
+		scopeArgs := pgx.NamedArgs{}
+		for k, v := range filterArgs {
+			scopeArgs[k] = v
+		}
+		scopes, err := queryConstraintsSQL("", opts.Language, *parsed, true, false, scopeArgs)
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(filterSQL) != "" {
+			scopes = "(" + filterSQL + ") AND " + scopes
+		}
+		filterSQL, filterArgs = scopes, scopeArgs
+	}
+	sql, args, _, err := buildPGroongaSQL(opts.Schema, extSchema, opts.EntityTypes, opts.TitleBoost, filterSQL, filterArgs)
 	if err != nil {
 		return nil, err
 	}
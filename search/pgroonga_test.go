@@ -1,6 +1,9 @@
 package search
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestBuildPGroongaTypeaheadQuery(t *testing.T) {
 	if got := buildPGroongaTypeaheadQuery(""); got != "" {
@@ -35,7 +38,7 @@ func TestNormalizePGroongaScore(t *testing.T) {
 }
 
 func TestBuildPGroongaSQL(t *testing.T) {
-	sql, args, _, err := buildPGroongaSQL("doujins", "doujins", []string{"gallery"}, "", nil)
+	sql, args, _, err := buildPGroongaSQL("doujins", "doujins", []string{"gallery"}, 0, "", nil)
 	if err != nil {
 		t.Fatalf("unexpected err: %v", err)
 	}
@@ -55,3 +58,20 @@ func TestBuildPGroongaSQL(t *testing.T) {
 		t.Fatalf("expected limit arg placeholder")
 	}
 }
+
+func TestBuildPGroongaSQL_TitleBoost(t *testing.T) {
+	sql, args, _, err := buildPGroongaSQL("doujins", "pgroonga", nil, 0.5, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %v", err)
+	}
+	if args["title_boost"] != float32(0.5) {
+		t.Fatalf("expected title_boost arg, got %v", args["title_boost"])
+	}
+	if !strings.Contains(sql, `sd.raw_title OPERATOR("pgroonga".&@~) @q`) {
+		t.Fatalf("expected title match in score, got:\n%s", sql)
+	}
+
+	if _, _, _, err := buildPGroongaSQL("doujins", "pgroonga", nil, 0.5, "sd.entity_id = @title_boost", map[string]any{"title_boost": 1}); err == nil {
+		t.Fatalf("expected FilterArgs conflict with title_boost")
+	}
+}
@@ -1,9 +1,6 @@
 package search
 
-import (
-	"sort"
-	"strings"
-)
+import "strings"
 
 // RRF (Reciprocal Rank Fusion) combines ranked lists without relying on raw
 // score calibration.
@@ -43,6 +40,15 @@ func (k RRFKey) keyString() string {
 	}, "\x1f")
 }
 
+// RRFContribution returns the score a single list adds for an item at the given
+// 1-based rank: weight / (k + rank). k defaults to 60 when <= 0.
+func RRFContribution(k int, weight float32, rank int) float32 {
+	if k <= 0 {
+		k = 60
+	}
+	return weight / float32(k+rank)
+}
+
 // FuseRRF fuses multiple ranked lists into a single ranked list via RRF.
 //
 // Input lists are expected to be ordered best-first.
@@ -71,7 +77,7 @@ func FuseRRF(lists [][]RRFKey, opts RRFOptions) []RRFHit {
 			rank := i + 1
 			ks := item.keyString()
 			example[ks] = item
-			scores[ks] += w / float32(k+rank)
+			scores[ks] += RRFContribution(k, w, rank)
 		}
 	}
 
@@ -79,14 +85,6 @@ func FuseRRF(lists [][]RRFKey, opts RRFOptions) []RRFHit {
 	for ks, sc := range scores {
 		out = append(out, RRFHit{RRFKey: example[ks], Score: sc})
 	}
-	sort.Slice(out, func(i, j int) bool {
-		if out[i].Score == out[j].Score {
-			if out[i].EntityType == out[j].EntityType {
-				return out[i].EntityID < out[j].EntityID
-			}
-			return out[i].EntityType < out[j].EntityType
-		}
-		return out[i].Score > out[j].Score
-	})
+	sortFused(out)
 	return out
 }
@@ -8,6 +8,8 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	pgvector "github.com/pgvector/pgvector-go"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
 )
 
 type Hit struct {
@@ -47,6 +49,17 @@ type Options struct {
 	// FilterArgs are named args referenced by FilterSQL using pgx '@name'
 	// placeholders (e.g. "... language = @lang").
 	FilterArgs map[string]any
+	// Filter is a builder alternative to FilterSQL/FilterArgs (see Filter);
+	// both are ANDed when set.
+	Filter Filter
+
+	// Constraints is the user query (searchkit syntax) the vector was embedded
+	// from. Entities whose lexical document (same entity and language)
+	// matches one of its exclusions (`not x`) are excluded inside the KNN
+	// query, as lexical backends exclude them; entities without a lexical
+	// document have no text to match and are kept. Field scopes describe term
+	// matches and are ignored.
+	Constraints string
 }
 
 type Query struct {
@@ -150,12 +163,21 @@ func SemanticSearch(ctx context.Context, pool *pgxpool.Pool, q Query) ([]Hit, er
 		where += " AND ev.entity_id <> ALL(@exclude_ids::text[])"
 		args["exclude_ids"] = opts.ExcludeIDs
 	}
-	if strings.TrimSpace(opts.FilterSQL) != "" {
-		where += " AND (" + opts.FilterSQL + ")"
-		if err := mergeNamedArgs(args, opts.FilterArgs); err != nil {
+	filterSQL, filterArgs, err := CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(filterSQL) != "" {
+		where += " AND (" + filterSQL + ")"
+		if err := mergeNamedArgs(args, filterArgs); err != nil {
 			return nil, err
 		}
 	}
+	exclusions, err := semanticExclusionSQL(quotedSchema, q.Language, opts.Constraints, args)
+	if err != nil {
+		return nil, err
+	}
+	where += exclusions
 
 	if !opts.TwoStage {
 		// 1-stage cosine KNN:
@@ -168,11 +190,11 @@ func SemanticSearch(ctx context.Context, pool *pgxpool.Pool, q Query) ([]Hit, er
 				ev.model,
 				ev.language,
 				(1 - (ev.embedding::%s <=> (@qvec::%s)))::float4 AS similarity
-			FROM %s ev
+			FROM %s
 			%s
 			ORDER BY ev.embedding::%s <=> (@qvec::%s)
 			LIMIT @limit
-		`, half, half, table, where, half, half)
+		`, half, half, semanticFrom(table, filterSQL), where, half, half)
 
 		args["qvec"] = vec
 		args["limit"] = q.Limit
@@ -190,7 +212,7 @@ func SemanticSearch(ctx context.Context, pool *pgxpool.Pool, q Query) ([]Hit, er
 						ev.model,
 						ev.language,
 						ev.embedding
-					FROM %s ev
+					FROM %s
 					%s
 					ORDER BY (binary_quantize(embedding::%s)::bit(%d)) <~> (binary_quantize(@qvec::%s)::bit(%d))
 					LIMIT @oversample
@@ -205,7 +227,7 @@ func SemanticSearch(ctx context.Context, pool *pgxpool.Pool, q Query) ([]Hit, er
 				WHERE (1 - (embedding::%s <=> (@qvec::%s))) >= @min_similarity
 				ORDER BY embedding::%s <=> (@qvec::%s)
 				LIMIT @limit
-			`, table, where, half, dim, half, dim, half, half, half, half, half, half)
+			`, semanticFrom(table, filterSQL), where, half, dim, half, dim, half, half, half, half, half, half)
 
 		args["qvec"] = vec
 		args["oversample"] = oversample
@@ -233,6 +255,44 @@ func SemanticSearch(ctx context.Context, pool *pgxpool.Pool, q Query) ([]Hit, er
 	return out, rows.Err()
 }
 
+// semanticFrom returns the FROM item of a KNN query over the
+// embedding_vectors table (aliased ev). When a filter is set, the columns ev
+// shares with search_documents are also exposed as sd, so filters written for
+// the lexical backends (`sd.entity_id = ...`, attribute filters) apply to
+// vectors unchanged.
+func semanticFrom(table string, filterSQL string) string {
+	from := table + " ev"
+	if strings.TrimSpace(filterSQL) != "" {
+		from += " CROSS JOIN LATERAL (SELECT ev.entity_type, ev.entity_id, ev.language, ev.attributes) sd"
+	}
+	return from
+}
+
+// semanticExclusionSQL returns the " AND NOT EXISTS (...)" condition applying
+// the exclusions of constraints to the embedding_vectors row aliased ev, or
+// "" when it has none.
+func semanticExclusionSQL(quotedSchema string, language string, constraints string, args pgx.NamedArgs) (string, error) {
+	if strings.TrimSpace(constraints) == "" {
+		return "", nil
+	}
+	p := constraintParams{args: pgx.NamedArgs{}, bound: args}
+	cond := exclusionSQL(quotedSchema, language, querynorm.ParseQuery(constraints), &p)
+	if cond == "" {
+		return "", nil
+	}
+	if err := mergeNamedArgs(args, p.args); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`
+		AND NOT EXISTS (
+			SELECT 1 FROM %s.search_documents sd
+			WHERE sd.entity_type = ev.entity_type
+			  AND sd.entity_id = ev.entity_id
+			  AND sd.language = ev.language
+			  AND %s
+		)`, quotedSchema, cond), nil
+}
+
 // SearchVectors is a legacy alias for SemanticSearch.
 func SearchVectors(ctx context.Context, pool *pgxpool.Pool, q Query) ([]Hit, error) {
 	return SemanticSearch(ctx, pool, q)
@@ -289,9 +349,13 @@ func SimilarTo(ctx context.Context, pool *pgxpool.Pool, schema string, entityTyp
 		where += " AND ev.entity_id <> ALL(@exclude_ids::text[])\n"
 		args["exclude_ids"] = opts.ExcludeIDs
 	}
-	if strings.TrimSpace(opts.FilterSQL) != "" {
-		where += " AND (" + opts.FilterSQL + ")\n"
-		if err := mergeNamedArgs(args, opts.FilterArgs); err != nil {
+	filterSQL, filterArgs, err := CombineFilter(opts.FilterSQL, opts.FilterArgs, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(filterSQL) != "" {
+		where += " AND (" + filterSQL + ")\n"
+		if err := mergeNamedArgs(args, filterArgs); err != nil {
 			return nil, err
 		}
 	}
@@ -311,11 +375,11 @@ func SimilarTo(ctx context.Context, pool *pgxpool.Pool, schema string, entityTyp
 			ev.model,
 			ev.language,
 			(1 - (ev.embedding <=> s.embedding))::float4 AS similarity
-		FROM %s ev, source s
+		FROM %s, source s
 		%s
 		ORDER BY ev.embedding <=> s.embedding
 		LIMIT @limit
-	`, table, table, where)
+	`, table, semanticFrom(table, filterSQL), where)
 
 	rows, err := pool.Query(ctx, sql, args)
 	if err != nil {
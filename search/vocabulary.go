@@ -0,0 +1,209 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Term is a vocabulary entry from `<schema>.search_vocabulary`.
+type Term struct {
+	Term     string
+	DocCount int64
+	// Similarity is the pg_trgm similarity to the looked-up term (SimilarTerms
+	// only).
+	Similarity float32
+}
+
+type SimilarTermsOptions struct {
+	Schema   string
+	Language string
+	Limit    int
+	// MinSimilarity is the pg_trgm threshold (default 0.3).
+	MinSimilarity float32
+}
+
+// SimilarTerms returns vocabulary terms similar to term (pg_trgm), excluding
+// term itself, best first.
+func SimilarTerms(ctx context.Context, pool *pgxpool.Pool, term string, opts SimilarTermsOptions) ([]Term, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(opts.Schema) == "" {
+		return nil, fmt.Errorf("schema is required")
+	}
+	if strings.TrimSpace(opts.Language) == "" {
+		return nil, fmt.Errorf("language is required")
+	}
+	term = strings.TrimSpace(term)
+	if opts.Limit <= 0 || term == "" {
+		return []Term{}, nil
+	}
+	quotedSchema, err := quoteIdent(opts.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	minSim := opts.MinSimilarity
+	if minSim <= 0 {
+		minSim = 0.3
+	}
+
+	// See LexicalSearch for why set_limit is referenced from a CTE.
+	sql := fmt.Sprintf(`
+		WITH _ AS (SELECT set_limit(@min_similarity))
+		SELECT
+			v.term,
+			v.doc_count,
+			similarity(v.term, @term)::float4 AS score
+		FROM _, %s.search_vocabulary v
+		WHERE v.language = @language
+		  AND v.term %% @term
+		  AND v.term <> @term
+		ORDER BY score DESC, v.doc_count DESC, v.term ASC
+		LIMIT @limit
+	`, quotedSchema)
+	rows, err := pool.Query(ctx, sql, pgx.NamedArgs{
+		"language":       opts.Language,
+		"term":           term,
+		"min_similarity": minSim,
+		"limit":          opts.Limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Term{}
+	for rows.Next() {
+		var t Term
+		if err := rows.Scan(&t.Term, &t.DocCount, &t.Similarity); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// KnownTerms returns the document counts of the given terms that exist in the
+// vocabulary for language.
+func KnownTerms(ctx context.Context, pool *pgxpool.Pool, schema string, language string, terms []string) (map[string]int64, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	quotedSchema, err := quoteIdent(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	out := map[string]int64{}
+	if len(terms) == 0 {
+		return out, nil
+	}
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT term, doc_count
+		FROM %s.search_vocabulary
+		WHERE language = @language AND term = ANY(@terms::text[])
+	`, quotedSchema), pgx.NamedArgs{"language": language, "terms": terms})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var term string
+		var n int64
+		if err := rows.Scan(&term, &n); err != nil {
+			return nil, err
+		}
+		out[term] = n
+	}
+	return out, rows.Err()
+}
+
+type CompleteTermsOptions struct {
+	Schema   string
+	Language string
+	Limit    int
+	// PGroonga uses PGroonga's prefix operator (`&^`) instead of LIKE. Use it
+	// for native-script CJK prefixes. When the pgroonga extension is not
+	// installed no terms are returned: CJK text is not segmented by the
+	// `simple` config, so a LIKE prefix would only complete to whole runs of
+	// text.
+	PGroonga bool
+}
+
+// CompleteTerms returns vocabulary terms starting with prefix, most frequent
+// first (ties by term).
+func CompleteTerms(ctx context.Context, pool *pgxpool.Pool, prefix string, opts CompleteTermsOptions) ([]Term, error) {
+	if pool == nil {
+		return nil, fmt.Errorf("pool is required")
+	}
+	if strings.TrimSpace(opts.Schema) == "" {
+		return nil, fmt.Errorf("schema is required")
+	}
+	if strings.TrimSpace(opts.Language) == "" {
+		return nil, fmt.Errorf("language is required")
+	}
+	prefix = strings.TrimSpace(prefix)
+	if opts.Limit <= 0 || prefix == "" {
+		return []Term{}, nil
+	}
+	quotedSchema, err := quoteIdent(opts.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	args := pgx.NamedArgs{
+		"language": opts.Language,
+		"limit":    opts.Limit,
+	}
+	var cond string
+	if opts.PGroonga {
+		extSchema, err := getPGroongaExtensionSchema(ctx, pool)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return []Term{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		qext, err := quoteIdent(extSchema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pgroonga schema: %w", err)
+		}
+		cond = fmt.Sprintf("v.term OPERATOR(%s.&^) @prefix", qext)
+		args["prefix"] = prefix
+	} else {
+		cond = "v.term LIKE @pattern"
+		args["pattern"] = escapeLikePattern(prefix) + "%"
+	}
+
+	rows, err := pool.Query(ctx, fmt.Sprintf(`
+		SELECT v.term, v.doc_count
+		FROM %s.search_vocabulary v
+		WHERE v.language = @language
+		  AND %s
+		ORDER BY v.doc_count DESC, v.term ASC
+		LIMIT @limit
+	`, quotedSchema, cond), args)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Term{}
+	for rows.Next() {
+		var t Term
+		if err := rows.Scan(&t.Term, &t.DocCount); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// escapeLikePattern escapes LIKE metacharacters (default escape '\').
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
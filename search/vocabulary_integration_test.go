@@ -0,0 +1,75 @@
+package search
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestCompleteTerms_Integration_Prefix(t *testing.T) {
+	dsn := os.Getenv("SEARCHKIT_TEST_URL")
+	if dsn == "" {
+		t.Skip("SEARCHKIT_TEST_URL not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("pgxpool: %v", err)
+	}
+	defer pool.Close()
+
+	_, err = pool.Exec(ctx, `
+		CREATE SCHEMA IF NOT EXISTS s;
+		CREATE TABLE IF NOT EXISTS s.search_vocabulary (
+			language text NOT NULL,
+			term text NOT NULL,
+			doc_count bigint NOT NULL DEFAULT 0,
+			updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (language, term)
+		);
+		CREATE INDEX IF NOT EXISTS idx_search_vocabulary_term_prefix
+			ON s.search_vocabulary (language, term text_pattern_ops);
+		TRUNCATE TABLE s.search_vocabulary;
+		INSERT INTO s.search_vocabulary (language, term, doc_count) VALUES
+			('en', 'tentacle', 5),
+			('en', 'tentative', 9),
+			('en', 'tent', 9),
+			('en', 'attention', 50),
+			('en', '100%', 3),
+			('en', '1000', 7),
+			('fr', 'tentation', 40);
+	`)
+	if err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	terms := func(prefix string) []string {
+		t.Helper()
+		got, err := CompleteTerms(ctx, pool, prefix, CompleteTermsOptions{Schema: "s", Language: "en", Limit: 10})
+		if err != nil {
+			t.Fatalf("CompleteTerms(%q): %v", prefix, err)
+		}
+		out := []string{}
+		for _, term := range got {
+			out = append(out, term.Term)
+		}
+		return out
+	}
+
+	// Most frequent first, ties by term; infix matches and other languages are
+	// excluded.
+	if got, want := terms("tent"), []string{"tent", "tentative", "tentacle"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CompleteTerms(tent) = %v; want %v", got, want)
+	}
+	// LIKE metacharacters in the prefix match literally.
+	if got, want := terms("100%"), []string{"100%"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("CompleteTerms(100%%) = %v; want %v", got, want)
+	}
+	if got := terms("xyz"); len(got) != 0 {
+		t.Fatalf("CompleteTerms(xyz) = %v; want none", got)
+	}
+}
@@ -0,0 +1,18 @@
+package search
+
+import "testing"
+
+func TestEscapeLikePattern(t *testing.T) {
+	cases := map[string]string{
+		"tentac":   "tentac",
+		"100%":     `100\%`,
+		"foo_bar":  `foo\_bar`,
+		`back\sl`:  `back\\sl`,
+		`%_\mixed`: `\%\_\\mixed`,
+	}
+	for in, want := range cases {
+		if got := escapeLikePattern(in); got != want {
+			t.Fatalf("escapeLikePattern(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package searchkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+	"github.com/open-rails/searchkit/search"
+)
+
+// SuggestOptions configures Client.Suggest.
+type SuggestOptions struct {
+	// Language selects the vocabulary. Defaults to ClientConfig.DefaultLanguage.
+	Language string
+	// Limit is the maximum number of suggested queries (default 3).
+	Limit int
+	// MinSimilarity is the pg_trgm similarity a vocabulary term needs to be
+	// considered as a correction (default 0.3).
+	MinSimilarity float32
+	// MaxCandidates is the number of vocabulary terms fetched per misspelled
+	// token before ranking by edit distance (default 10).
+	MaxCandidates int
+}
+
+// QuerySuggestion is a corrected ("did you mean") query.
+type QuerySuggestion struct {
+	// Query is the corrected query text (lowercased).
+	Query string
+	// Corrections lists the replaced tokens in query order.
+	Corrections []TermCorrection
+}
+
+// TermCorrection is one replaced token of a QuerySuggestion.
+type TermCorrection struct {
+	Original  string
+	Corrected string
+	// Distance is the Levenshtein distance between Original and Corrected.
+	Distance int
+	// DocCount is the number of documents containing Corrected.
+	DocCount int64
+}
+
+const (
+	defaultSuggestLimit         = 3
+	defaultSuggestMinSimilarity = 0.3
+	defaultSuggestCandidates    = 10
+	// Shorter tokens have too many plausible neighbours to correct reliably.
+	minCorrectableTokenRunes = 3
+)
+
+// Suggest returns "did you mean" corrections for userText, best first.
+//
+// Tokens that are not in the language's vocabulary (`search_vocabulary`,
+// maintained from search_documents) are replaced by similar vocabulary terms:
+// candidates come from pg_trgm and are ranked by edit distance, then document
+// count. Returns an empty slice when every token is known or nothing close
+// enough exists.
+//
+// Rewrite rules apply first, as in Search, so suggestions correct the query
+// that is searched; a query redirected by a rule gets no suggestions.
+func (c *Client) Suggest(ctx context.Context, userText string, opts SuggestOptions) ([]QuerySuggestion, error) {
+	language := strings.TrimSpace(opts.Language)
+	if language == "" {
+		language = c.defaultLanguage
+	}
+	if language == "" {
+		return nil, fmt.Errorf("Language is required")
+	}
+	rewrite := c.rewriteQuery(ctx, language, userText)
+	if rewrite.redirect != "" {
+		return []QuerySuggestion{}, nil
+	}
+	if rewrite.query != "" {
+		userText = rewrite.query
+	}
+	q := strings.ToLower(querynorm.QueryForEmbedding(userText))
+	if q == "" || !hasAnyLetterOrNumber(q) {
+		return []QuerySuggestion{}, nil
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultSuggestLimit
+	}
+	minSim := opts.MinSimilarity
+	if minSim <= 0 {
+		minSim = defaultSuggestMinSimilarity
+	}
+	maxCandidates := opts.MaxCandidates
+	if maxCandidates <= 0 {
+		maxCandidates = defaultSuggestCandidates
+	}
+
+	spans := correctableSpans(q)
+	if len(spans) == 0 {
+		return []QuerySuggestion{}, nil
+	}
+	words := make([]string, 0, len(spans))
+	for _, sp := range spans {
+		words = append(words, q[sp.Start:sp.End])
+	}
+	known, err := search.KnownTerms(ctx, c.pool, c.schema, language, words)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([][]TermCorrection, len(spans))
+	for i, w := range words {
+		if _, ok := known[w]; ok {
+			continue
+		}
+		terms, err := search.SimilarTerms(ctx, c.pool, w, search.SimilarTermsOptions{
+			Schema:        c.schema,
+			Language:      language,
+			Limit:         maxCandidates,
+			MinSimilarity: minSim,
+		})
+		if err != nil {
+			return nil, err
+		}
+		candidates[i] = rankCorrections(w, terms)
+	}
+	return buildSuggestions(q, spans, candidates, limit), nil
+}
+
+// correctableSpans returns the word spans of q worth spell-checking: at least
+// minCorrectableTokenRunes long, not purely numeric and not CJK (CJK text has
+// no word boundaries to correct on).
+func correctableSpans(q string) []HighlightSpan {
+	var out []HighlightSpan
+	for _, sp := range wordSpans(q) {
+		w := q[sp.Start:sp.End]
+		if utf8.RuneCountInString(w) < minCorrectableTokenRunes || containsCJKScript(w) {
+			continue
+		}
+		if strings.IndexFunc(w, unicode.IsLetter) < 0 {
+			continue
+		}
+		out = append(out, sp)
+	}
+	return out
+}
+
+// maxEditDistance is the largest Levenshtein distance accepted as a
+// correction of a word with n runes.
+func maxEditDistance(n int) int {
+	switch {
+	case n <= 4:
+		return 1
+	case n <= 8:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// rankCorrections orders vocabulary candidates for word by edit distance,
+// then document count, then trigram similarity, dropping those too far away.
+func rankCorrections(word string, terms []search.Term) []TermCorrection {
+	type scored struct {
+		TermCorrection
+		similarity float32
+	}
+	maxDist := maxEditDistance(utf8.RuneCountInString(word))
+	list := make([]scored, 0, len(terms))
+	for _, t := range terms {
+		d := levenshtein(word, t.Term)
+		if d == 0 || d > maxDist {
+			continue
+		}
+		list = append(list, scored{
+			TermCorrection: TermCorrection{Original: word, Corrected: t.Term, Distance: d, DocCount: t.DocCount},
+			similarity:     t.Similarity,
+		})
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if a.DocCount != b.DocCount {
+			return a.DocCount > b.DocCount
+		}
+		if a.similarity != b.similarity {
+			return a.similarity > b.similarity
+		}
+		return a.Corrected < b.Corrected
+	})
+	out := make([]TermCorrection, 0, len(list))
+	for _, s := range list {
+		out = append(out, s.TermCorrection)
+	}
+	return out
+}
+
+// buildSuggestions combines per-span candidates into corrected queries. The
+// first suggestion uses the best candidate for every span; the others swap a
+// single span to a lower-ranked candidate, ordered by total edit distance.
+func buildSuggestions(q string, spans []HighlightSpan, candidates [][]TermCorrection, limit int) []QuerySuggestion {
+	best := make([]int, len(spans))
+	corrected := false
+	for i, cs := range candidates {
+		if len(cs) == 0 {
+			best[i] = -1
+			continue
+		}
+		corrected = true
+	}
+	if !corrected {
+		return []QuerySuggestion{}
+	}
+
+	type combo struct {
+		choice   []int
+		distance int
+		docs     int64
+	}
+	score := func(choice []int) combo {
+		c := combo{choice: choice}
+		for i, ci := range choice {
+			if ci >= 0 {
+				c.distance += candidates[i][ci].Distance
+				c.docs += candidates[i][ci].DocCount
+			}
+		}
+		return c
+	}
+
+	combos := []combo{score(best)}
+	var alternatives []combo
+	for i, cs := range candidates {
+		for r := 1; r < len(cs); r++ {
+			choice := append([]int(nil), best...)
+			choice[i] = r
+			alternatives = append(alternatives, score(choice))
+		}
+	}
+	sort.SliceStable(alternatives, func(i, j int) bool {
+		if alternatives[i].distance != alternatives[j].distance {
+			return alternatives[i].distance < alternatives[j].distance
+		}
+		return alternatives[i].docs > alternatives[j].docs
+	})
+	combos = append(combos, alternatives...)
+
+	out := []QuerySuggestion{}
+	seen := map[string]struct{}{}
+	for _, cb := range combos {
+		if len(out) >= limit {
+			break
+		}
+		var b strings.Builder
+		var corrections []TermCorrection
+		prev := 0
+		for i, sp := range spans {
+			if cb.choice[i] < 0 {
+				continue
+			}
+			corr := candidates[i][cb.choice[i]]
+			b.WriteString(q[prev:sp.Start])
+			b.WriteString(corr.Corrected)
+			prev = sp.End
+			corrections = append(corrections, corr)
+		}
+		b.WriteString(q[prev:])
+		query := b.String()
+		if _, ok := seen[query]; ok {
+			continue
+		}
+		seen[query] = struct{}{}
+		out = append(out, QuerySuggestion{Query: query, Corrections: corrections})
+	}
+	return out
+}
+
+// levenshtein returns the rune-level edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// searchWithSuggestion runs the search and, when it finds nothing, retries
+// once with the best spelling suggestion. Suggestion failures keep the
+// original (empty) result.
+func (c *Client) searchWithSuggestion(ctx context.Context, userText string, opts SearchOptions) (*SearchResult, error) {
+	opts.RetryWithSuggestion = false
+	res, err := c.SearchWithMeta(ctx, userText, opts)
+	if err != nil || len(res.Hits) > 0 || len(res.Failures) > 0 || res.Redirect != "" {
+		return res, err
+	}
+	suggestions, err := c.Suggest(ctx, userText, SuggestOptions{Language: opts.Language, Limit: 1})
+	if err != nil || len(suggestions) == 0 {
+		return res, nil
+	}
+	corrected := suggestions[0].Query
+	retried, err := c.SearchWithMeta(ctx, corrected, opts)
+	if err != nil || len(retried.Hits) == 0 {
+		return res, nil
+	}
+	retried.CorrectedQuery = corrected
+	return retried, nil
+}
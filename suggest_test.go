@@ -0,0 +1,94 @@
+package searchkit
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/open-rails/searchkit/search"
+)
+
+func TestLevenshtein(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"naruto", "nartuo", 2},
+		{"café", "cafe", 1},
+	}
+	for _, tc := range cases {
+		if got := levenshtein(tc.a, tc.b); got != tc.want {
+			t.Fatalf("levenshtein(%q, %q) = %d; want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestRankCorrections(t *testing.T) {
+	t.Parallel()
+
+	got := rankCorrections("narto", []search.Term{
+		{Term: "narrator", DocCount: 500, Similarity: 0.4},
+		{Term: "naruto", DocCount: 10, Similarity: 0.5},
+		{Term: "narto2", DocCount: 100, Similarity: 0.6},
+		{Term: "completely", DocCount: 1000, Similarity: 0.3},
+	})
+	var terms []string
+	for _, c := range got {
+		terms = append(terms, c.Corrected)
+	}
+	// Equal distance ties break on document count; "narrator" (3) and
+	// "completely" exceed the distance budget for a 5-rune word.
+	if want := []string{"narto2", "naruto"}; !reflect.DeepEqual(terms, want) {
+		t.Fatalf("corrections = %v; want %v", terms, want)
+	}
+}
+
+func TestBuildSuggestions(t *testing.T) {
+	t.Parallel()
+
+	q := "narto the hokage"
+	spans := correctableSpans(q)
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 correctable spans, got %v", spans)
+	}
+	candidates := [][]TermCorrection{
+		{
+			{Original: "narto", Corrected: "naruto", Distance: 1, DocCount: 10},
+			{Original: "narto", Corrected: "nato", Distance: 1, DocCount: 2},
+		},
+		nil,
+		nil,
+	}
+	got := buildSuggestions(q, spans, candidates, 3)
+	var queries []string
+	for _, s := range got {
+		queries = append(queries, s.Query)
+	}
+	if want := []string{"naruto the hokage", "nato the hokage"}; !reflect.DeepEqual(queries, want) {
+		t.Fatalf("suggestions = %v; want %v", queries, want)
+	}
+	if len(got[0].Corrections) != 1 || got[0].Corrections[0].Original != "narto" {
+		t.Fatalf("unexpected corrections: %+v", got[0].Corrections)
+	}
+
+	if got := buildSuggestions(q, spans, make([][]TermCorrection, 3), 3); len(got) != 0 {
+		t.Fatalf("expected no suggestions when nothing is corrected, got %+v", got)
+	}
+}
+
+func TestCorrectableSpansSkipsShortNumericAndCJK(t *testing.T) {
+	t.Parallel()
+
+	q := "ab 2024 東京タワー tokyo"
+	var words []string
+	for _, sp := range correctableSpans(q) {
+		words = append(words, q[sp.Start:sp.End])
+	}
+	if want := []string{"tokyo"}; !reflect.DeepEqual(words, want) {
+		t.Fatalf("correctable words = %v; want %v", words, want)
+	}
+}
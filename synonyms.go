@@ -0,0 +1,70 @@
+package searchkit
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	querynorm "github.com/open-rails/searchkit/internal/normalize"
+	"github.com/open-rails/searchkit/pg"
+	"github.com/open-rails/searchkit/search"
+)
+
+// maxSynonymProbes caps the extra trigram/PGroonga queries run per backend
+// and language for synonym expansion.
+const maxSynonymProbes = 4
+
+// synonymsFor returns the expansion dictionary for language (nil when it has
+// no entries). Dictionaries are reloaded when the index generation changes,
+// which pg.UpsertSynonym/DeleteSynonym bump. Synonyms are optional: when they
+// cannot be loaded, queries run unexpanded.
+func (c *Client) synonymsFor(ctx context.Context, language string) querynorm.Synonyms {
+	byLanguage := c.synonyms.get(ctx, c, func(ctx context.Context) (map[string]querynorm.Synonyms, error) {
+		entries, err := pg.ListSynonyms(ctx, c.pool, c.schema, "")
+		if err != nil {
+			return nil, err
+		}
+		byLang := map[string][]pg.Synonym{}
+		for _, e := range entries {
+			lang := strings.ToLower(e.Language)
+			byLang[lang] = append(byLang[lang], e)
+		}
+		out := make(map[string]querynorm.Synonyms, len(byLang))
+		for lang, es := range byLang {
+			out[lang] = pg.SynonymExpansions(es)
+		}
+		return out, nil
+	})
+	return byLanguage[strings.ToLower(language)]
+}
+
+// bestRankedHits dedupes hits from several probes by key, keeping the best
+// raw score, and returns the top limit by score.
+func bestRankedHits(hits []rankedHit, limit int) []rankedHit {
+	best := make(map[search.RRFKey]int, len(hits))
+	out := make([]rankedHit, 0, len(hits))
+	for _, h := range hits {
+		if i, ok := best[h.key]; ok {
+			if h.rawScore > out[i].rawScore {
+				out[i].rawScore = h.rawScore
+			}
+			continue
+		}
+		best[h.key] = len(out)
+		out = append(out, h)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.rawScore != b.rawScore {
+			return a.rawScore > b.rawScore
+		}
+		if a.key.EntityType != b.key.EntityType {
+			return a.key.EntityType < b.key.EntityType
+		}
+		return a.key.EntityID < b.key.EntityID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
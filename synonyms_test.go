@@ -0,0 +1,44 @@
+package searchkit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/open-rails/searchkit/search"
+)
+
+func TestBestRankedHits(t *testing.T) {
+	t.Parallel()
+
+	k := func(id string) search.RRFKey {
+		return search.RRFKey{EntityType: "gallery", EntityID: id, Language: "en"}
+	}
+	hits := []rankedHit{
+		// Original probe.
+		{key: k("1"), rawScore: 0.4},
+		{key: k("2"), rawScore: 0.3},
+		// Synonym probe.
+		{key: k("3"), rawScore: 0.9},
+		{key: k("2"), rawScore: 0.5},
+	}
+	got := bestRankedHits(hits, 2)
+	want := []rankedHit{{key: k("3"), rawScore: 0.9}, {key: k("2"), rawScore: 0.5}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("bestRankedHits = %+v; want %+v", got, want)
+	}
+}
+
+func TestSynonymsFor_UnavailableMeansNone(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewClient(ClientConfig{Pool: newTestPool(t), Schema: "test"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	// The test pool cannot connect: synonyms are optional, so lexical search
+	// runs unexpanded instead of failing.
+	if got := client.synonymsFor(context.Background(), "en"); got != nil {
+		t.Fatalf("synonymsFor = %v; want nil", got)
+	}
+}
@@ -0,0 +1,75 @@
+package searchkit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func isKnownBackend(b Backend) bool {
+	switch b {
+	case BackendFTS, BackendTrigram, BackendPGroonga, BackendSemantic:
+		return true
+	default:
+		return false
+	}
+}
+
+func validateBackendWeights(field string, weights map[Backend]float32) error {
+	for b, w := range weights {
+		if !isKnownBackend(b) {
+			return fmt.Errorf("invalid %s: unknown backend %q", field, b)
+		}
+		if w <= 0 {
+			return fmt.Errorf("invalid %s[%q]: weight must be > 0", field, b)
+		}
+	}
+	return nil
+}
+
+// mergeBackendWeights overlays per-request weights on the client defaults.
+func mergeBackendWeights(defaults map[Backend]float32, override map[Backend]float32) map[Backend]float32 {
+	if len(defaults) == 0 && len(override) == 0 {
+		return nil
+	}
+	out := make(map[Backend]float32, len(defaults)+len(override))
+	for b, w := range defaults {
+		out[b] = w
+	}
+	for b, w := range override {
+		out[b] = w
+	}
+	return out
+}
+
+// listWeights returns the RRF weight for each list: the backend weight
+// (default 1.0), multiplied by fallbackWeight for lists whose language is not
+// the primary (requested) language.
+func listWeights(lists []rankedList, primary string, backendWeights map[Backend]float32, fallbackWeight float32) []float32 {
+	out := make([]float32, len(lists))
+	for i, l := range lists {
+		w := float32(1.0)
+		if bw, ok := backendWeights[l.backend]; ok && bw > 0 {
+			w = bw
+		}
+		if fallbackWeight > 0 && l.language != primary {
+			w *= fallbackWeight
+		}
+		out[i] = w
+	}
+	return out
+}
+
+func weightsFingerprint(backendWeights map[Backend]float32, fallbackWeight float32) string {
+	keys := make([]string, 0, len(backendWeights))
+	for b := range backendWeights {
+		keys = append(keys, string(b))
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys)+1)
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%g", k, backendWeights[Backend(k)]))
+	}
+	parts = append(parts, fmt.Sprintf("fallback=%g", fallbackWeight))
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,68 @@
+package searchkit
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/open-rails/searchkit/search"
+)
+
+func TestListWeights(t *testing.T) {
+	t.Parallel()
+
+	lists := []rankedList{
+		{backend: BackendFTS, language: "es"},
+		{backend: BackendSemantic, language: "es"},
+		{backend: BackendFTS, language: "en"},
+		{backend: BackendTrigram, language: "es"},
+	}
+	got := listWeights(lists, "es", map[Backend]float32{BackendSemantic: 1.5, BackendFTS: 2}, 0.5)
+	want := []float32{2, 1.5, 1, 1}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("listWeights = %v; want %v", got, want)
+		}
+	}
+}
+
+func TestListWeights_ReorderFusion(t *testing.T) {
+	t.Parallel()
+
+	fts := rankedList{backend: BackendFTS, language: "en"}
+	fts.add("gallery", "lex", "en", 1)
+	sem := rankedList{backend: BackendSemantic, language: "en"}
+	sem.add("gallery", "sem", "en", 1)
+	lists := []rankedList{fts, sem}
+
+	weights := listWeights(lists, "en", map[Backend]float32{BackendSemantic: 1.5}, 0)
+	fused := search.FuseRRF([][]search.RRFKey{fts.keys(), sem.keys()}, search.RRFOptions{K: 60, Weights: weights})
+	if fused[0].EntityID != "sem" {
+		t.Fatalf("expected weighted semantic hit first, got %+v", fused)
+	}
+}
+
+func TestClientSearch_InvalidWeights(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewClient(ClientConfig{
+		Pool:           newTestPool(t),
+		Schema:         "test",
+		DefaultWeights: map[Backend]float32{"bm25": 1},
+	}); err == nil || !strings.Contains(err.Error(), "unknown backend") {
+		t.Fatalf("expected unknown backend error, got %v", err)
+	}
+
+	client, err := NewClient(ClientConfig{Pool: newTestPool(t), Schema: "test"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	_, err = client.Search(context.Background(), "two factor", SearchOptions{
+		Mode:               SearchModeLexical,
+		LexicalEntityTypes: []string{"gallery"},
+		Weights:            map[Backend]float32{BackendTrigram: -1},
+	})
+	if err == nil || !strings.Contains(err.Error(), "SearchOptions.Weights") {
+		t.Fatalf("expected SearchOptions.Weights error, got %v", err)
+	}
+}
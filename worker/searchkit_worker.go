@@ -38,6 +38,10 @@ type SearchkitOptions struct {
 	BackfillPageSize int
 	// Upper bound on how much cursor backfill work to do per SyncOnce.
 	BackfillMaxPages int
+	// tsv recomputation after a language config change
+	// (pg.RegisterLanguageConfig): rows per batch and batches per SyncOnce.
+	ReindexBatchSize  int
+	ReindexMaxBatches int
 
 	// Embedding task draining settings (existing embedding worker).
 	DrainOptions Options
@@ -54,6 +58,12 @@ func (o SearchkitOptions) withDefaults() SearchkitOptions {
 	if out.BackfillMaxPages <= 0 {
 		out.BackfillMaxPages = 5
 	}
+	if out.ReindexBatchSize <= 0 {
+		out.ReindexBatchSize = 1000
+	}
+	if out.ReindexMaxBatches <= 0 {
+		out.ReindexMaxBatches = 5
+	}
 	out.DrainOptions = out.DrainOptions.withDefaults()
 	return out
 }
@@ -66,7 +76,7 @@ type dirtyRow struct {
 	Reason     string
 }
 
-func SyncOnce(ctx context.Context, rt *runtime.Runtime, opts SearchkitOptions) error {
+func SyncOnce(ctx context.Context, rt *runtime.Runtime, opts SearchkitOptions) (err error) {
 	if rt == nil {
 		return fmt.Errorf("runtime is required")
 	}
@@ -105,23 +115,113 @@ func SyncOnce(ctx context.Context, rt *runtime.Runtime, opts SearchkitOptions) e
 		semanticSet[t] = struct{}{}
 	}
 
+	// Invalidate cached search results whenever the index changed, including
+	// when a later step fails after earlier ones already wrote.
+	changed := false
+	defer func() {
+		if !changed {
+			return
+		}
+		if bumpErr := pg.BumpSearchGeneration(context.WithoutCancel(ctx), cfg.Pool, cfg.Schema); bumpErr != nil && err == nil {
+			err = bumpErr
+		}
+	}()
+
 	// 1) Drain dirty queue (fast path).
-	if err := processDirtyOnce(ctx, cfg.Pool, cfg.Schema, repo, rt, lexicalSet, semanticSet, cfg.DirtyBatchSize); err != nil {
+	dirtyChanged, err := processDirtyOnce(ctx, cfg.Pool, cfg.Schema, repo, rt, lexicalSet, semanticSet, cfg.DirtyBatchSize)
+	changed = changed || dirtyChanged
+	if err != nil {
 		return err
 	}
 
 	// 2) Bounded backfill tick (slow path).
-	if err := backfillOnce(ctx, cfg.Pool, cfg.Schema, repo, rt, lexicalSet, semanticSet, cfg.SupportedLanguages, cfg.ListEntityIDsPage, cfg.BackfillPageSize, cfg.BackfillMaxPages); err != nil {
+	backfillChanged, err := backfillOnce(ctx, cfg.Pool, cfg.Schema, repo, rt, lexicalSet, semanticSet, cfg.SupportedLanguages, cfg.ListEntityIDsPage, cfg.BackfillPageSize, cfg.BackfillMaxPages)
+	changed = changed || backfillChanged
+	if err != nil {
 		return err
 	}
 
+	// 2b) Bounded tsv recomputation for languages whose FTS config changed.
+	for i := 0; i < cfg.ReindexMaxBatches; i++ {
+		n, queued, err := pg.ReindexSearchDocumentsTSV(ctx, cfg.Pool, cfg.Schema, cfg.ReindexBatchSize)
+		if err != nil {
+			return err
+		}
+		if !queued {
+			break
+		}
+		changed = changed || n > 0
+	}
+
 	// 3) Drain embedding tasks (provider calls + writes embedding_vectors).
 	// If no embedding models are configured, skip draining so tasks remain pending
 	// and lexical maintenance still succeeds.
-	if len(rt.ActiveModels()) == 0 {
-		return nil
+	if len(rt.ActiveModels()) > 0 {
+		drained, err := drainOnce(ctx, rt, repo, cfg.DrainOptions)
+		changed = changed || drained > 0
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertLexical builds and stores lexical documents (and their attributes)
+// for ids, using the structured callback when the runtime has one. Ranking
+// signals are per entity, not per language: callers collect ids in an
+// entitySet and refresh them once.
+func upsertLexical(ctx context.Context, pool *pgxpool.Pool, schema string, rt *runtime.Runtime, entityType string, language string, ids []string) error {
+	if rt.HasLexicalDocuments() {
+		docs, err := rt.BuildLexicalDocuments(ctx, entityType, language, ids)
+		if err != nil {
+			return err
+		}
+		if err := pg.UpsertLexicalDocuments(ctx, pool, schema, entityType, language, docs); err != nil {
+			return err
+		}
+	} else {
+		docs, err := rt.BuildLexicalString(ctx, entityType, language, ids)
+		if err != nil {
+			return err
+		}
+		if err := pg.UpsertSearchDocuments(ctx, pool, schema, entityType, language, docs); err != nil {
+			return err
+		}
+	}
+	// The enclosing SyncOnce bumps the search generation once for all writes.
+	_, err := rt.RefreshAttributes(ctx, entityType, language, ids)
+	return err
+}
+
+// entitySet collects distinct entity ids per entity type, in insertion order.
+type entitySet struct {
+	ids  map[string][]string
+	seen map[[2]string]struct{}
+}
+
+func (s *entitySet) add(entityType string, ids []string) {
+	if s.ids == nil {
+		s.ids = map[string][]string{}
+		s.seen = map[[2]string]struct{}{}
+	}
+	for _, id := range ids {
+		k := [2]string{entityType, id}
+		if _, ok := s.seen[k]; ok {
+			continue
+		}
+		s.seen[k] = struct{}{}
+		s.ids[entityType] = append(s.ids[entityType], id)
 	}
-	return DrainOnce(ctx, rt, repo, cfg.DrainOptions)
+}
+
+// refreshSignals refreshes the ranking signals of every entity in s once.
+func (s *entitySet) refreshSignals(ctx context.Context, rt *runtime.Runtime) error {
+	for et, ids := range s.ids {
+		if err := rt.RefreshSignals(ctx, et, ids); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func processDirtyOnce(
@@ -133,13 +233,13 @@ func processDirtyOnce(
 	lexicalSet map[string]struct{},
 	semanticSet map[string]struct{},
 	limit int,
-) error {
+) (changed bool, err error) {
 	if limit <= 0 {
-		return nil
+		return false, nil
 	}
 	qs, err := pg.QuoteSchema(schema)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	rows, err := pool.Query(ctx, fmt.Sprintf(`
@@ -149,7 +249,7 @@ func processDirtyOnce(
 		LIMIT $1
 	`, qs), limit)
 	if err != nil {
-		return err
+		return false, err
 	}
 	defer rows.Close()
 
@@ -157,7 +257,7 @@ func processDirtyOnce(
 	for rows.Next() {
 		var r dirtyRow
 		if err := rows.Scan(&r.EntityType, &r.EntityID, &r.Language, &r.IsDeleted, &r.Reason); err != nil {
-			return err
+			return false, err
 		}
 		if strings.TrimSpace(r.EntityType) == "" || strings.TrimSpace(r.EntityID) == "" || strings.TrimSpace(r.Language) == "" {
 			continue
@@ -165,25 +265,27 @@ func processDirtyOnce(
 		batch = append(batch, r)
 	}
 	if err := rows.Err(); err != nil {
-		return err
+		return false, err
 	}
 	if len(batch) == 0 {
-		return nil
+		return false, nil
 	}
 
-	// Process deletions first.
+	// Process deletions first. changed is set before each write: a failed
+	// write may still have modified the index (e.g. a partial upsert batch).
 	for _, r := range batch {
 		if !r.IsDeleted {
 			continue
 		}
+		changed = true
 		if err := pg.DeleteSearchDocuments(ctx, pool, schema, r.EntityType, r.EntityID, r.Language); err != nil {
-			return err
+			return changed, err
 		}
 		if err := pg.DeleteEmbeddingVectorsForEntity(ctx, pool, schema, r.EntityType, r.EntityID, r.Language); err != nil {
-			return err
+			return changed, err
 		}
 		if err := repo.DeleteAllForEntity(ctx, r.EntityType, r.EntityID, r.Language); err != nil {
-			return err
+			return changed, err
 		}
 	}
 
@@ -201,17 +303,19 @@ func processDirtyOnce(
 		}
 		groupedLex[r.EntityType][r.Language] = append(groupedLex[r.EntityType][r.Language], r.EntityID)
 	}
+	var signals entitySet
 	for et, byLang := range groupedLex {
 		for lang, ids := range byLang {
-			docs, err := rt.BuildLexicalString(ctx, et, lang, ids)
-			if err != nil {
-				return err
-			}
-			if err := pg.UpsertSearchDocuments(ctx, pool, schema, et, lang, docs); err != nil {
-				return err
+			changed = true
+			if err := upsertLexical(ctx, pool, schema, rt, et, lang, ids); err != nil {
+				return changed, err
 			}
+			signals.add(et, ids)
 		}
 	}
+	if err := signals.refreshSignals(ctx, rt); err != nil {
+		return changed, err
+	}
 
 	// Semantic: enqueue tasks for all active models (no need to build docs here).
 	activeModels := rt.ActiveModels()
@@ -232,7 +336,7 @@ func processDirtyOnce(
 		for lang, ids := range byLang {
 			for _, model := range activeModels {
 				if err := repo.EnqueueMany(ctx, et, ids, model, lang, "dirty"); err != nil {
-					return err
+					return changed, err
 				}
 			}
 		}
@@ -241,7 +345,7 @@ func processDirtyOnce(
 	// Clear dirty rows (processed).
 	tx, err := pool.Begin(ctx)
 	if err != nil {
-		return err
+		return changed, err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 	for _, r := range batch {
@@ -249,10 +353,11 @@ func processDirtyOnce(
 			DELETE FROM %s.search_dirty
 			WHERE entity_type = $1 AND entity_id = $2 AND language = $3
 		`, qs), r.EntityType, r.EntityID, r.Language); err != nil {
-			return err
+			return changed, err
 		}
 	}
-	return tx.Commit(ctx)
+	// Every processed row either deleted or rewrote search artifacts.
+	return true, tx.Commit(ctx)
 }
 
 func backfillOnce(
@@ -267,22 +372,31 @@ func backfillOnce(
 	list ListEntityIDsPage,
 	pageSize int,
 	maxPages int,
-) error {
+) (changed bool, err error) {
 	if maxPages <= 0 || pageSize <= 0 {
-		return nil
+		return false, nil
 	}
 	qs, err := pg.QuoteSchema(schema)
 	if err != nil {
-		return err
+		return false, err
 	}
 	activeModels := rt.ActiveModels()
 	pagesDone := 0
 
+	// Signals are refreshed once for every entity written by this call, on
+	// every return path.
+	var signals entitySet
+	defer func() {
+		if serr := signals.refreshSignals(ctx, rt); err == nil {
+			err = serr
+		}
+	}()
+
 	// Lexical docs: fill missing documents.
 	for et := range lexicalSet {
 		for _, lang := range languages {
 			if pagesDone >= maxPages {
-				return nil
+				return changed, nil
 			}
 			if strings.TrimSpace(lang) == "" {
 				continue
@@ -290,7 +404,7 @@ func backfillOnce(
 
 			cursor, state, err := ensureAndGetDocBackfillState(ctx, pool, qs, et, lang)
 			if err != nil {
-				return err
+				return changed, err
 			}
 			if state == "done" {
 				continue
@@ -303,16 +417,14 @@ func backfillOnce(
 					SET last_error = $3, state = 'failed', updated_at = now()
 					WHERE entity_type = $1 AND language = $2
 				`, qs), et, lang, err.Error())
-				return err
+				return changed, err
 			}
 			if len(ids) > 0 {
-				docs, err := rt.BuildLexicalString(ctx, et, lang, ids)
-				if err != nil {
-					return err
-				}
-				if err := pg.UpsertSearchDocuments(ctx, pool, schema, et, lang, docs); err != nil {
-					return err
+				if err := upsertLexical(ctx, pool, schema, rt, et, lang, ids); err != nil {
+					return changed, err
 				}
+				signals.add(et, ids)
+				changed = true
 			}
 			if done {
 				_, _ = pool.Exec(ctx, fmt.Sprintf(`
@@ -337,11 +449,11 @@ func backfillOnce(
 		for _, lang := range languages {
 			for _, model := range activeModels {
 				if pagesDone >= maxPages {
-					return nil
+					return changed, nil
 				}
 				cursor, state, err := ensureAndGetVecBackfillState(ctx, pool, qs, model, et, lang)
 				if err != nil {
-					return err
+					return changed, err
 				}
 				if state == "done" {
 					continue
@@ -353,15 +465,15 @@ func backfillOnce(
 						SET last_error = $4, state = 'failed', updated_at = now()
 						WHERE model = $1 AND entity_type = $2 AND language = $3
 					`, qs), model, et, lang, err.Error())
-					return err
+					return changed, err
 				}
 				if len(ids) > 0 {
 					missing, err := pg.FilterMissingEmbeddings(ctx, pool, schema, et, model, lang, ids)
 					if err != nil {
-						return err
+						return changed, err
 					}
 					if err := repo.EnqueueMany(ctx, et, missing, model, lang, "model_backfill"); err != nil {
-						return err
+						return changed, err
 					}
 				}
 				if done {
@@ -382,7 +494,7 @@ func backfillOnce(
 		}
 	}
 
-	return nil
+	return changed, nil
 }
 
 func ensureAndGetDocBackfillState(ctx context.Context, pool *pgxpool.Pool, qs string, entityType string, language string) (cursor string, state string, err error) {
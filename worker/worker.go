@@ -407,25 +407,37 @@ func processBatch(ctx context.Context, rt *runtime.Runtime, repo *tasks.Repo, cf
 // This is useful for integrating searchkit into an external job runner (e.g.
 // River/Cron) where you do not want an internal infinite polling loop.
 func DrainOnce(ctx context.Context, rt *runtime.Runtime, repo *tasks.Repo, opts Options) error {
+	drained, err := drainOnce(ctx, rt, repo, opts)
+	if drained > 0 {
+		if bumpErr := rt.BumpSearchGeneration(context.WithoutCancel(ctx)); bumpErr != nil && err == nil {
+			err = bumpErr
+		}
+	}
+	return err
+}
+
+// drainOnce is DrainOnce returning the number of tasks processed, without
+// bumping the search generation.
+func drainOnce(ctx context.Context, rt *runtime.Runtime, repo *tasks.Repo, opts Options) (int, error) {
 	if rt == nil {
-		return fmt.Errorf("runtime is required")
+		return 0, fmt.Errorf("runtime is required")
 	}
 	if repo == nil {
-		return fmt.Errorf("repo is required")
+		return 0, fmt.Errorf("repo is required")
 	}
 	cfg := opts.withDefaults()
 
 	batch, err := repo.FetchReady(ctx, cfg.BatchSize, cfg.LockAhead)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if len(batch) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	docsByType, assetsByType, err := hydrateBatch(ctx, rt, batch)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	sem := make(chan struct{}, cfg.MaxConcurrentEmbeds)
@@ -436,9 +448,55 @@ func DrainOnce(ctx context.Context, rt *runtime.Runtime, repo *tasks.Repo, opts
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	processBatch(ctx, rt, repo, cfg, batch, docsByType, assetsByType, sem, tokens, rng)
+	return len(batch), refreshBatchMetadata(ctx, rt, batch)
+}
+
+// refreshBatchMetadata stores attributes and ranking signals for the entities
+// of a processed batch, so new embedding rows are filterable (see
+// runtime.BuildAttributes) and boostable (see runtime.BuildSignals). The
+// caller bumps the search generation once per batch.
+func refreshBatchMetadata(ctx context.Context, rt *runtime.Runtime, batch []tasks.Task) error {
+	byLanguage, entities := batchEntities(batch)
+	for et, byLang := range byLanguage {
+		for lang, list := range byLang {
+			if _, err := rt.RefreshAttributes(ctx, et, lang, list); err != nil {
+				return err
+			}
+		}
+	}
+	for et, list := range entities {
+		if err := rt.RefreshSignals(ctx, et, list); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// batchEntities returns the distinct entity ids of batch per entity type and
+// language, and per entity type across languages, in batch order.
+func batchEntities(batch []tasks.Task) (byLanguage map[string]map[string][]string, entities map[string][]string) {
+	byLanguage = map[string]map[string][]string{} // entity_type -> language -> ids
+	entities = map[string][]string{}              // entity_type -> ids (all languages)
+	seen := map[[3]string]struct{}{}
+	seenEntity := map[[2]string]struct{}{}
+	for _, t := range batch {
+		if _, ok := seenEntity[[2]string{t.EntityType, t.EntityID}]; !ok {
+			seenEntity[[2]string{t.EntityType, t.EntityID}] = struct{}{}
+			entities[t.EntityType] = append(entities[t.EntityType], t.EntityID)
+		}
+		k := [3]string{t.EntityType, t.Language, t.EntityID}
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		if byLanguage[t.EntityType] == nil {
+			byLanguage[t.EntityType] = map[string][]string{}
+		}
+		byLanguage[t.EntityType][t.Language] = append(byLanguage[t.EntityType][t.Language], t.EntityID)
+	}
+	return byLanguage, entities
+}
+
 // Run drains embedding tasks using the provided runtime and repository.
 //
 // This helper is optional; host apps can implement their own runner in River/Cron/etc.
@@ -477,6 +535,17 @@ func Run(ctx context.Context, rt *runtime.Runtime, repo *tasks.Repo, opts Option
 			}
 
 			processBatch(ctx, rt, repo, cfg, batch, docsByType, assetsByType, sem, tokens, rng)
+			err = refreshBatchMetadata(ctx, rt, batch)
+			if len(batch) > 0 {
+				// Run never returns, so new embeddings and attributes are
+				// published once per polled batch.
+				if bumpErr := rt.BumpSearchGeneration(context.WithoutCancel(ctx)); bumpErr != nil && err == nil {
+					err = bumpErr
+				}
+			}
+			if err != nil {
+				return err
+			}
 		}
 	}
 }